@@ -0,0 +1,112 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/cache"
+)
+
+// cacheCmd represents the cache command.
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of API responses",
+	Long: `
+hledger-price-tracker
+
+Command to manage the cache of API responses.
+
+Successful responses are stored in $XDG_CACHE_HOME/hledger-price-tracker
+(without the API key) and reused while they are fresh: 7 days for the
+lists of currencies and the stock search, 5 minutes for the current
+exchange rates, and 1 hour for everything else.
+
+Use the global flag '--no-cache' to bypass the cache entirely or
+'--refresh' to ignore the cached responses but store the new ones.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print the help message for this command.
+		internal.CheckErr(cmd.Help())
+	},
+}
+
+// cacheClearCmd represents the cache clear command.
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete all the cached API responses",
+	Long: `
+hledger-price-tracker
+
+Command to delete all the cached API responses.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		deleted, err := cache.Clear()
+		internal.CheckErr(err)
+		fmt.Printf("Deleted %d cached responses.\n", deleted)
+	},
+}
+
+// cacheStatsCmd represents the cache stats command.
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics about the cached API responses",
+	Long: `
+hledger-price-tracker
+
+Command to show how many API responses are cached for each endpoint.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := cache.Dir()
+		internal.CheckErr(err)
+		stats, err := cache.GetStats()
+		internal.CheckErr(err)
+
+		t := table.NewWriter()
+		t.SetStyle(table.StyleLight)
+		t.AppendHeader(table.Row{"Endpoint", "Entries", "Stale", "Size", "Oldest", "Newest"})
+		for _, s := range stats {
+			t.AppendRow(table.Row{
+				s.Endpoint,
+				s.Entries,
+				s.Stale,
+				fmt.Sprintf("%.1f KiB", float64(s.Size)/1024),
+				s.Oldest.Format("2006-01-02 15:04:05"),
+				s.Newest.Format("2006-01-02 15:04:05"),
+			})
+		}
+		t.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 4, Align: text.AlignRight},
+		})
+
+		fmt.Printf("Cache directory: %s\n", dir)
+		fmt.Println(t.Render())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+}
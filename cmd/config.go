@@ -23,6 +23,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
 )
 
 // configCmd represents the config command.
@@ -40,6 +42,14 @@ Command to get details about the application's configuration file.`,
 		} else {
 			fmt.Printf("Using config file: %s\n", viper.ConfigFileUsed())
 		}
+
+		usage, perDay, err := internal.Limiter.Usage()
+		internal.CheckErr(err)
+		if perDay > 0 {
+			fmt.Printf("Alpha Vantage requests made today: %d of %d (plan %q)\n", usage.Count, perDay, internal.Plan)
+		} else {
+			fmt.Printf("Alpha Vantage requests made today: %d (plan %q)\n", usage.Count, internal.Plan)
+		}
 	},
 }
 
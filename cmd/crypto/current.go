@@ -26,6 +26,8 @@ import (
 	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/crypto/current"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 // Define the output flag and set it to the default value.
@@ -55,8 +57,10 @@ API documentation: https://www.alphavantage.co/documentation/#crypto-exchange`,
 		} else {
 			to = args[1]
 		}
-		output, err := current.Execute(args[0], to, formatCurrent)
-		cobra.CheckErr(err)
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		output, err := current.Execute(p, symbols.ToAPI(args[0]), symbols.ToAPI(to), formatCurrent)
+		internal.CheckErr(err)
 		fmt.Print(output)
 	},
 }
@@ -66,5 +70,5 @@ func init() {
 	PaletteCmd.AddCommand(currentCmd)
 
 	// Add flags to the `current` subcommand.
-	currentCmd.Flags().VarP(&formatCurrent, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"table\", \"table-long\")")
+	currentCmd.Flags().VarP(&formatCurrent, "format", "f", "format of the output (possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"table\", \"table-long\")")
 }
@@ -23,6 +23,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/crypto/list"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
@@ -41,7 +42,7 @@ Command to list all available physical currencies.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		output, err := list.Execute(formatList)
-		cobra.CheckErr(err)
+		internal.CheckErr(err)
 		fmt.Print(output)
 	},
 }
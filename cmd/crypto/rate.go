@@ -20,21 +20,27 @@ package crypto
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/period"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 var formatRate = flags.OutputFormatHledger
 var interval = flags.IntervalWeekly
 var begin string
 var end string
+var periodRate string
 var full bool
 
 // rateCmd represents the rate command.
 var rateCmd = &cobra.Command{
-	Use:   "rate",
+	Use:   "rate [flags] <cryptocurrency> [<market-currency>]",
 	Short: "Get the historical exchange rate between a currency and a cryptocurrency",
 	Long: `
 hledger-price-tracker
@@ -43,19 +49,35 @@ Command to get the exchange rates between a currency and a cryptocurrency
 in a certain time period and interval.
 
 It returns the open, high, low, and close exchange rates for each each interval 
-in the time period defined.
+in the time period defined. The second argument is the market in which the
+cryptocurrency is traded and must be a physical currency.
+
+The API always returns the entire time series of the cryptocurrency, so you
+can limit it with the '--begin' and '--end' flags.
 
 API documentation:
 - https://www.alphavantage.co/documentation/#currency-daily
 - https://www.alphavantage.co/documentation/#currency-weekly
 - https://www.alphavantage.co/documentation/#currency-monthly`,
 
-	// Require the user to provide at least one argument, which is the currency we want to convert from.
+	// Require the user to provide at least one argument, which is the cryptocurrency we want to convert from.
 	Args: cobra.MinimumNArgs(1),
 
 	Run: func(cmd *cobra.Command, args []string) {
-		// TODO
-		fmt.Println("rate called")
+		if periodRate != "" {
+			span, err := period.Parse(periodRate, time.Now())
+			internal.CheckErr(err)
+			begin, end = span.Dates()
+		}
+		var to string
+		if len(args) < 2 {
+			to = internal.DefaultCurrency
+		} else {
+			to = args[1]
+		}
+		output, err := rate.Execute(symbols.ToAPI(args[0]), symbols.ToAPI(to), "", formatRate, interval, begin, end)
+		internal.CheckErr(err)
+		fmt.Print(output)
 	},
 }
 
@@ -64,9 +86,14 @@ func init() {
 	PaletteCmd.AddCommand(rateCmd)
 
 	// Add flags to the `rate` subcommand.
-	rateCmd.Flags().VarP(&formatRate, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"csv\", \"table\", \"table-long\")")
+	rateCmd.Flags().VarP(&formatRate, "format", "f", "format of the output (possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"raw-csv\", \"table\", \"table-long\")")
 	rateCmd.Flags().VarP(&interval, "interval", "i", "interval between prices (possible values are \"daily\", \"weekly\", \"monthly\")")
-	rateCmd.Flags().StringVarP(&begin, "begin", "b", "", "beginning of the time period (format YYYY-MM-DD) (does not apply to  \"json\" or \"csv\" output formats)")
-	rateCmd.Flags().StringVarP(&end, "end", "e", "", "end of the time period (format YYYY-MM-DD) (does not apply to  \"json\" or \"csv\" output formats)")
-	rateCmd.Flags().BoolVar(&full, "full", false, "for daily intervals, return all the data, otherwise return only the last 100 data points (does nothing for weekly or monthly intervals)")
+	rateCmd.Flags().StringVarP(&begin, "begin", "b", "", "beginning of the time period, as a date (e.g. YYYY-MM-DD) or the first day of a period expression (e.g. \"lastmonth\", \"2025Q1\", \"-30d\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	rateCmd.Flags().StringVarP(&end, "end", "e", "", "end of the time period, included, as a date (e.g. YYYY-MM-DD) or the last day of a period expression (e.g. \"lastmonth\", \"2025Q1\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	rateCmd.Flags().StringVarP(&periodRate, "period", "p", "", "time period, as an hledger period expression (e.g. \"lastmonth\", \"ytd\", \"from 2024-01 to 2024-06\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	rateCmd.MarkFlagsMutuallyExclusive("period", "begin")
+	rateCmd.MarkFlagsMutuallyExclusive("period", "end")
+	// Keep the flag of the previous versions so the existing scripts do not fail.
+	rateCmd.Flags().BoolVar(&full, "full", false, "return all the data (does nothing, the full series is always returned)")
+	internal.CheckErr(rateCmd.Flags().MarkDeprecated("full", "the digital currency endpoints always return the full series"))
 }
@@ -20,8 +20,13 @@ package currency
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 // PaletteCmd represents the currency command palette.
@@ -44,4 +49,25 @@ their exchange rates.`,
 	},
 }
 
-func init() {}
+func init() {
+	// Add flags common to all the subcommands of this palette.
+	PaletteCmd.PersistentFlags().StringVar(&provider.ECBFile, "ecb-file", "", "path to a file downloaded from the ECB website (XML, CSV or ZIP) to use instead of downloading the reference rates (only used by the \"ecb\" provider)")
+}
+
+// pivot returns the currency through which the exchange rates between `from` and `to` are computed, or an empty
+// string to get them directly. The `--via` flag takes precedence over the `pivots` section of the configuration file,
+// which maps a currency to the pivot used whenever it is on either side of the pair.
+func pivot(via string, from string, to string) string {
+	if via != "" {
+		return symbols.ToAPI(via)
+	}
+
+	// Viper lowercases the keys of the maps.
+	pivots := viper.GetStringMapString("pivots")
+	for _, currency := range []string{from, to} {
+		if p, ok := pivots[strings.ToLower(currency)]; ok && p != from && p != to {
+			return symbols.ToAPI(p)
+		}
+	}
+	return ""
+}
@@ -19,22 +19,29 @@
 package currency
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/currency/current"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 // Define the output flag and set it to the default value.
 var formatCurrent = flags.OutputFormatHledger
+var asCurrent string
+var viaCurrent string
 
 // currentCmd represents the current command.
 var currentCmd = &cobra.Command{
-	Use:   "current [flags] <from-currency> [<to-currency>]",
-	Short: "Get the current exchange rate between two currencies",
+	Use:   "current [flags] <from-currency> [<to-currency>] | <from-currency>/<to-currency>...",
+	Short: "Get the current exchange rate between two currencies, or of several pairs of currencies",
 	Long: `
 hledger-price-tracker
 
@@ -43,21 +50,32 @@ Can be used to convert between either physical or crypto currencies
 (i.e. performs the exact same operation as the analogous command in the 
 \'crypto\' command palette).
 
+With '--via' (or the 'pivots' section of the configuration file), the rate is
+a cross rate computed through a pivot currency, for the pairs that Alpha
+Vantage does not provide (e.g. 'XAF --via EUR USD').
+
+Several pairs can be given as 'FROM/TO' (e.g. 'EUR/USD BTC/EUR GBP/CHF'). A
+single currency without destination is converted into the default currency,
+but every pair must have both currencies when several are given. They are
+fetched concurrently (see '--workers') and written in the order given. A pair
+that fails does not stop the others, and the errors are reported at the end.
+
 API documentation: https://www.alphavantage.co/documentation/#currency-exchange`,
 
 	// Require the user to provide at least one argument, which is the currency we want to convert from.
 	Args: cobra.MinimumNArgs(1),
 
 	Run: func(cmd *cobra.Command, args []string) {
-		var to string
-		if len(args) < 2 {
-			to = internal.DefaultCurrency
-		} else {
-			to = args[1]
+		pairs, err := currentPairs(args)
+		internal.CheckErr(err)
+		if asCurrent != "" && len(pairs) > 1 {
+			internal.CheckErr(errors.New("[cmd.currency.current] the --as flag can only be used with a single pair of currencies"))
 		}
-		output, err := current.Execute(args[0], to, formatCurrent)
-		cobra.CheckErr(err)
-		fmt.Print(output)
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		// The rate of each pair is written as soon as it is fetched, so the output can be piped while the others are
+		// fetched.
+		internal.CheckErr(current.Write(os.Stdout, p, pairs, asCurrent, formatCurrent))
 	},
 }
 
@@ -66,5 +84,41 @@ func init() {
 	PaletteCmd.AddCommand(currentCmd)
 
 	// Add flags to the `current` subcommand.
-	currentCmd.Flags().VarP(&formatCurrent, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"table\", \"table-long\")")
+	currentCmd.Flags().VarP(&formatCurrent, "format", "f", "format of the output (possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"table\", \"table-long\")")
+	currentCmd.Flags().StringVar(&viaCurrent, "via", "", "pivot currency through which the cross rate is computed (overrides the \"pivots\" section of the configuration file)")
+	currentCmd.Flags().StringVar(&asCurrent, "as", "", "name of the currency in the output (overrides the \"symbols\" section of the configuration file)")
+}
+
+// currentPairs returns the pairs of currencies given as arguments. A single argument is a pair written as "FROM/TO",
+// or only "FROM" to convert into the default currency, and two arguments without a slash are the origin and
+// destination currencies of a single pair. Several pairs must all be written as "FROM/TO".
+func currentPairs(args []string) ([]current.Pair, error) {
+	bare := 0
+	for _, arg := range args {
+		if !strings.Contains(arg, "/") {
+			bare++
+		}
+	}
+	switch {
+	case len(args) == 2 && bare == 2:
+		args = []string{args[0] + "/" + args[1]}
+	case bare > 2:
+		return nil, errors.New("[cmd.currency.currentPairs] too many currencies, give several pairs as FROM/TO")
+	case len(args) > 1 && bare > 0:
+		return nil, errors.New("[cmd.currency.currentPairs] several pairs must all be given as FROM/TO")
+	}
+
+	pairs := make([]current.Pair, 0, len(args))
+	for _, arg := range args {
+		from, to, found := strings.Cut(arg, "/")
+		if from == "" || (found && to == "" && len(args) > 1) {
+			return nil, fmt.Errorf("[cmd.currency.currentPairs] invalid pair %q, expected FROM/TO", arg)
+		}
+		if to == "" {
+			to = internal.DefaultCurrency
+		}
+		from, to = symbols.ToAPI(from), symbols.ToAPI(to)
+		pairs = append(pairs, current.Pair{From: from, To: to, Pivot: pivot(viaCurrent, from, to)})
+	}
+	return pairs, nil
 }
@@ -23,8 +23,10 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/currency/list"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
 )
 
 // Define the output flag and set it to the default value.
@@ -40,8 +42,10 @@ hledger-price-tracker
 Command to list all available physical currencies.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
-		output, err := list.Execute(formatList)
-		cobra.CheckErr(err)
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		output, err := list.Execute(p, formatList)
+		internal.CheckErr(err)
 		fmt.Print(output)
 	},
 }
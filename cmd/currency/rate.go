@@ -20,19 +20,26 @@ package currency
 
 import (
 	"fmt"
-	"github.com/lentidas/hledger-price-tracker/internal"
-	"github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/currency/rate"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/period"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 var formatRate = flags.OutputFormatHledger
 var interval = flags.IntervalWeekly
 var begin string
 var end string
+var periodRate string
 var full bool
+var asRate string
+var viaRate string
 
 // rateCmd represents the rate command.
 var rateCmd = &cobra.Command{
@@ -47,7 +54,17 @@ in a certain time period and interval.
 It returns the open, high, low, and close exchange rates for each each interval 
 in the time period defined.
 
+With an intraday interval (e.g. "5min"), the tables and the "ledger" output show
+the time of each price, while the "hledger" and "beancount" outputs keep only the
+last price of each day.
+
+With '--via' (or the 'pivots' section of the configuration file), the rates
+are cross rates computed through a pivot currency, for the pairs that Alpha
+Vantage does not provide (e.g. 'XAF --via EUR USD'). A leg with a digital
+currency on one side is read from the digital currency endpoints.
+
 API documentation:
+- https://www.alphavantage.co/documentation/#fx-intraday
 - https://www.alphavantage.co/documentation/#fx-daily
 - https://www.alphavantage.co/documentation/#fx-weekly
 - https://www.alphavantage.co/documentation/#fx-monthly`,
@@ -56,14 +73,22 @@ API documentation:
 	Args: cobra.MinimumNArgs(1),
 
 	Run: func(cmd *cobra.Command, args []string) {
+		if periodRate != "" {
+			span, err := period.Parse(periodRate, time.Now())
+			internal.CheckErr(err)
+			begin, end = span.Dates()
+		}
 		var to string
 		if len(args) < 2 {
 			to = internal.DefaultCurrency
 		} else {
 			to = args[1]
 		}
-		output, err := rate.Execute(args[0], to, formatRate, interval, begin, end, full)
-		cobra.CheckErr(err)
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		from, to := symbols.ToAPI(args[0]), symbols.ToAPI(to)
+		output, err := rate.Execute(p, from, to, pivot(viaRate, from, to), asRate, formatRate, interval, begin, end, full)
+		internal.CheckErr(err)
 		fmt.Print(output)
 	},
 }
@@ -73,9 +98,14 @@ func init() {
 	PaletteCmd.AddCommand(rateCmd)
 
 	// Add flags to the `rate` subcommand.
-	rateCmd.Flags().VarP(&formatRate, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"csv\", \"table\", \"table-long\")")
-	rateCmd.Flags().VarP(&interval, "interval", "i", "interval between prices (possible values are \"daily\", \"weekly\", \"monthly\")")
-	rateCmd.Flags().StringVarP(&begin, "begin", "b", "", "beginning of the time period (format YYYY-MM-DD) (does not apply to  \"json\" or \"csv\" output formats)")
-	rateCmd.Flags().StringVarP(&end, "end", "e", "", "end of the time period (format YYYY-MM-DD) (does not apply to  \"json\" or \"csv\" output formats)")
-	rateCmd.Flags().BoolVar(&full, "full", false, "for daily intervals, return all the data, otherwise return only the last 100 data points (does nothing for weekly or monthly intervals)")
+	rateCmd.Flags().VarP(&formatRate, "format", "f", "format of the output (possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"raw-csv\", \"table\", \"table-long\")")
+	rateCmd.Flags().VarP(&interval, "interval", "i", "interval between prices (possible values are \"daily\", \"weekly\", \"monthly\", \"1min\", \"5min\", \"15min\", \"30min\", \"60min\")")
+	rateCmd.Flags().StringVarP(&begin, "begin", "b", "", "beginning of the time period, as a date (e.g. YYYY-MM-DD) or the first day of a period expression (e.g. \"lastmonth\", \"2025Q1\", \"-30d\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	rateCmd.Flags().StringVarP(&end, "end", "e", "", "end of the time period, included, as a date (e.g. YYYY-MM-DD) or the last day of a period expression (e.g. \"lastmonth\", \"2025Q1\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	rateCmd.Flags().StringVarP(&periodRate, "period", "p", "", "time period, as an hledger period expression (e.g. \"lastmonth\", \"ytd\", \"from 2024-01 to 2024-06\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	rateCmd.MarkFlagsMutuallyExclusive("period", "begin")
+	rateCmd.MarkFlagsMutuallyExclusive("period", "end")
+	rateCmd.Flags().StringVar(&asRate, "as", "", "name of the currency in the output (overrides the \"symbols\" section of the configuration file)")
+	rateCmd.Flags().StringVar(&viaRate, "via", "", "pivot currency through which the cross rates are computed (overrides the \"pivots\" section of the configuration file)")
+	rateCmd.Flags().BoolVar(&full, "full", false, "for daily and intraday intervals, return all the data, otherwise return only the last 100 data points (does nothing for weekly or monthly intervals)")
 }
@@ -0,0 +1,72 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/watchlist"
+)
+
+// fetchCmd represents the fetch command.
+var fetchCmd = &cobra.Command{
+	Use:   "fetch [flags] [commodity...]",
+	Short: "Fetch the prices of the commodities listed in the configuration file",
+	Long: `
+hledger-price-tracker
+
+Command to fetch the prices of every commodity of the 'commodities' section
+of the configuration file in a single run, which is meant to be scheduled.
+
+Each entry describes the symbol of the commodity in the API, its kind
+("stock", "fx" or "crypto"), and optionally its name in the journal, the
+currency, the interval, whether to use adjusted prices, the first date to
+fetch, and the file where the price directives are appended. Only the prices
+that are newer than the latest one in the output file are fetched.
+
+If commodities are given as arguments, only those entries are processed.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var entries []watchlist.Entry
+		internal.CheckErr(viper.UnmarshalKey("commodities", &entries))
+		if len(entries) == 0 {
+			internal.CheckErr(errors.New("no commodities found in the configuration file"))
+		}
+		entries, err := watchlist.Filter(entries, args)
+		internal.CheckErr(err)
+
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+
+		// Print the prices that were fetched even if some commodities failed.
+		output, err := watchlist.Execute(p, entries)
+		fmt.Print(output)
+		internal.CheckErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fetchCmd)
+}
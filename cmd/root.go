@@ -31,9 +31,16 @@ import (
 	"github.com/lentidas/hledger-price-tracker/cmd/currency"
 	"github.com/lentidas/hledger-price-tracker/cmd/stock"
 	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/ratelimit"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 var cfgFile string
+var baseUrl string
+var templateFile string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -51,8 +58,8 @@ market price records for hledger using the Alpha Vantage API.`,
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		fmt.Println(err) // FIXME Is there a better way to show error messages?
-		os.Exit(1)
+		// Cobra already printed the error, so only the exit code is left.
+		os.Exit(internal.ExitCode(err))
 	}
 }
 
@@ -63,6 +70,22 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "path to config file (default is $HOME/.config/hledger-price-tracker/config)")
 	rootCmd.PersistentFlags().StringVarP(&internal.DefaultCurrency, "currency", "c", "EUR", "default destination currency for exchange rates")
 	rootCmd.PersistentFlags().StringVarP(&internal.ApiKey, "api-key", "k", "", "API key to access the Alpha Vantage API")
+	rootCmd.PersistentFlags().StringVar(&internal.ProviderName, "provider", provider.DefaultName, fmt.Sprintf("source of the market prices, or a comma-separated list of sources to try in order (possible values are \"%s\")", strings.Join(provider.Names(), "\", \"")))
+	rootCmd.PersistentFlags().StringVar(&internal.Plan, "plan", "free", fmt.Sprintf("Alpha Vantage subscription plan, used to respect its rate limits (possible values are \"%s\")", strings.Join(ratelimit.PlanNames(), "\", \"")))
+	rootCmd.PersistentFlags().IntVar(&internal.RequestsPerMinute, "requests-per-minute", 0, "maximum number of requests per minute to the Alpha Vantage API (overrides the value of the plan)")
+	rootCmd.PersistentFlags().IntVar(&internal.RequestsPerDay, "requests-per-day", 0, "maximum number of requests per day to the Alpha Vantage API (overrides the value of the plan)")
+	rootCmd.PersistentFlags().IntVar(&internal.Workers, "workers", 4, "maximum number of symbols fetched at the same time when several are given (the requests still respect the rate limits)")
+	rootCmd.PersistentFlags().BoolVar(&internal.KeepMinorUnits, "keep-minor-units", false, "keep the prices of the stocks quoted in a minor unit of a currency (e.g. GBX) instead of converting them to the major unit (e.g. GBP)")
+	rootCmd.PersistentFlags().IntVar(&internal.Precision, "precision", -1, "number of decimals of the prices (overrides the \"precisions\" section of the configuration file; by default, the digits given by the API are kept)")
+	rootCmd.PersistentFlags().StringVar(&render.Template, "template", "", "Go template written for each price with the \"template\" output format (e.g. '{{.Date.Format \"2006-01-02\"}} {{.Commodity}} {{.Price}} {{.Currency}}')")
+	rootCmd.PersistentFlags().StringVar(&templateFile, "template-file", "", "file containing the Go template of the \"template\" output format")
+	rootCmd.MarkFlagsMutuallyExclusive("template", "template-file")
+	rootCmd.PersistentFlags().BoolVar(&internal.NoCache, "no-cache", false, "neither read nor write the cache of API responses")
+	rootCmd.PersistentFlags().BoolVar(&internal.RefreshCache, "refresh", false, "ignore the cached API responses, but store the new ones in the cache")
+	rootCmd.PersistentFlags().StringVar(&baseUrl, "base-url", internal.DefaultBaseUrl, "address of the Alpha Vantage API, e.g. to use a mirror or a local stand-in")
+	rootCmd.PersistentFlags().StringVar(&internal.Record, "record", "", "directory where the API responses are recorded as fixtures")
+	rootCmd.PersistentFlags().StringVar(&internal.Replay, "replay", "", "directory from which the API responses are replayed instead of making requests")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
 	rootCmd.PersistentFlags().BoolVar(&internal.DebugMode, "debug", false, "enable debug mode (disables a few API requests and prints more information)")
 	rootCmd.PersistentFlags().MarkHidden("debug")
 
@@ -83,7 +106,7 @@ func initConfig() {
 	} else {
 		// Find home directory.
 		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+		internal.CheckErr(err)
 
 		// Search config in home directory with name "hledger-price-tracker" (without extension).
 		viper.AddConfigPath(home + "/.config/hledger-price-tracker")
@@ -124,4 +147,53 @@ func initConfig() {
 			rootCmd.Flags().Set(f.Name, fmt.Sprintf("%v", val))
 		}
 	})
+
+	internal.SetBaseUrl(baseUrl)
+	initLimiter()
+	initSymbols()
+	initPrecisions()
+	initTemplate()
+}
+
+// initSymbols loads the mapping between the commodities of the journal and the symbols of the API.
+func initSymbols() {
+	var mappings []symbols.Mapping
+	internal.CheckErr(viper.UnmarshalKey("symbols", &mappings))
+	internal.CheckErr(symbols.Load(mappings))
+}
+
+// initPrecisions loads the number of decimals of the prices of each commodity.
+func initPrecisions() {
+	internal.CheckErr(viper.UnmarshalKey("precisions", &internal.Precisions))
+}
+
+// initTemplate reads the template of the "template" output format from the file given with `--template-file`.
+func initTemplate() {
+	if templateFile == "" {
+		return
+	}
+	path, err := journal.ExpandHome(templateFile)
+	internal.CheckErr(err)
+	content, err := os.ReadFile(path)
+	internal.CheckErr(err)
+	render.Template = string(content)
+}
+
+// initLimiter creates the rate limiter for the Alpha Vantage API from the plan and the limits given by the user.
+func initLimiter() {
+	plan, ok := ratelimit.Plans[internal.Plan]
+	if !ok {
+		internal.CheckErr(fmt.Errorf("unknown plan %q (possible values are \"%s\")", internal.Plan, strings.Join(ratelimit.PlanNames(), "\", \"")))
+	}
+	if internal.RequestsPerMinute > 0 {
+		plan.PerMinute = internal.RequestsPerMinute
+	}
+	if internal.RequestsPerDay > 0 {
+		plan.PerDay = internal.RequestsPerDay
+	}
+
+	usagePath, err := ratelimit.UsagePath()
+	internal.CheckErr(err)
+
+	internal.Limiter = ratelimit.New(plan.PerMinute, plan.PerDay, usagePath)
 }
@@ -0,0 +1,152 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package stock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/period"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/dividends"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+var formatDividends = flags.OutputFormatHledger
+var sourceDividends string
+var quantityDividends string
+var journalFileDividends string
+var beginDividends string
+var endDividends string
+var periodDividends string
+var asDividends string
+var withholdingTaxDividends string
+var optionsDividends = dividends.DefaultOptions()
+
+// dividendsCmd represents the dividends command.
+var dividendsCmd = &cobra.Command{
+	Use:   "dividends [flags] <stock-symbol>",
+	Short: "Generate the transactions of the dividends paid by a stock",
+	Long: `
+hledger-price-tracker
+
+Command to list the dividends paid by a stock and to generate an hledger
+transaction for each dividend received.
+
+The quantity held is either given with '--quantity' or read from the postings
+of a journal given with '--file', on the day before each ex-dividend date.
+Each transaction books the gross dividend in the income account, the
+withholding tax in the tax account, and the net dividend in the cash account.
+These accounts and the withholding tax percentage can be set in the 'dividends'
+section of the configuration file.
+
+The dividends come either from the DIVIDENDS endpoint (default), which gives
+their payment dates, or from the weekly adjusted prices ('--source adjusted'),
+in which case each dividend is dated with the last day of its week.
+
+API documentation:
+- https://www.alphavantage.co/documentation/#dividends
+- https://www.alphavantage.co/documentation/#weeklyadj`,
+
+	// Require the user to provide exactly one argument, which is the stock symbol, since the dividends are generated
+	// for a single stock.
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if periodDividends != "" {
+			span, err := period.Parse(periodDividends, time.Now())
+			internal.CheckErr(err)
+			beginDividends, endDividends = span.Dates()
+		}
+		// The flags take precedence over the configuration file.
+		configured := dividends.DefaultOptions()
+		internal.CheckErr(viper.UnmarshalKey("dividends", &configured, viper.DecodeHook(decimal.DecodeHook)))
+		if cmd.Flags().Changed("account") {
+			configured.Account = optionsDividends.Account
+		}
+		if cmd.Flags().Changed("income-account") {
+			configured.IncomeAccount = optionsDividends.IncomeAccount
+		}
+		if cmd.Flags().Changed("cash-account") {
+			configured.CashAccount = optionsDividends.CashAccount
+		}
+		if cmd.Flags().Changed("tax-account") {
+			configured.TaxAccount = optionsDividends.TaxAccount
+		}
+		if cmd.Flags().Changed("withholding-tax") {
+			tax, err := decimal.Parse(withholdingTaxDividends)
+			internal.CheckErr(err)
+			configured.WithholdingTax = tax
+		}
+
+		symbol := symbols.ToAPI(args[0])
+		name := asDividends
+		if name == "" {
+			name = symbols.ToJournal(symbol)
+		}
+
+		var holding dividends.Holding
+		if cmd.Flags().Changed("quantity") {
+			held, err := decimal.Parse(quantityDividends)
+			internal.CheckErr(err)
+			holding = dividends.Quantity(held)
+		} else if journalFileDividends != "" {
+			path, err := journal.ExpandHome(journalFileDividends)
+			internal.CheckErr(err)
+			j, err := journal.Parse(path)
+			internal.CheckErr(err)
+			holding = dividends.JournalHolding(j, name, configured.Account)
+		}
+
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		output, err := dividends.Execute(p, symbol, name, sourceDividends, holding, configured, formatDividends, beginDividends, endDividends)
+		internal.CheckErr(err)
+		fmt.Print(output)
+	},
+}
+
+func init() {
+	// Add this subcommand to the `stock` command palette.
+	PaletteCmd.AddCommand(dividendsCmd)
+
+	// Add flags to the `dividends` subcommand.
+	dividendsCmd.Flags().VarP(&formatDividends, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"raw-json\", \"table\", \"table-long\")")
+	dividendsCmd.Flags().StringVar(&sourceDividends, "source", dividends.SourceDividends, "source of the dividends (possible values are \"dividends\", \"adjusted\")")
+	dividendsCmd.Flags().StringVarP(&quantityDividends, "quantity", "q", "", "quantity of the stock held (takes precedence over --file)")
+	dividendsCmd.Flags().StringVar(&journalFileDividends, "file", "", "journal from which the quantity held at each ex-dividend date is read")
+	dividendsCmd.Flags().StringVarP(&beginDividends, "begin", "b", "", "beginning of the time period, by ex-dividend date, as a date (e.g. YYYY-MM-DD) or the first day of a period expression (e.g. \"lastmonth\", \"2025Q1\", \"-30d\") (does not apply to the \"raw-json\" output format)")
+	dividendsCmd.Flags().StringVarP(&endDividends, "end", "e", "", "end of the time period, by ex-dividend date, included, as a date (e.g. YYYY-MM-DD) or the last day of a period expression (e.g. \"lastmonth\", \"2025Q1\") (does not apply to the \"raw-json\" output format)")
+	dividendsCmd.Flags().StringVarP(&periodDividends, "period", "p", "", "time period, by ex-dividend date, as an hledger period expression (e.g. \"lastmonth\", \"ytd\", \"from 2024-01 to 2024-06\") (does not apply to the \"raw-json\" output format)")
+	dividendsCmd.MarkFlagsMutuallyExclusive("period", "begin")
+	dividendsCmd.MarkFlagsMutuallyExclusive("period", "end")
+	dividendsCmd.Flags().StringVar(&asDividends, "as", "", "name of the commodity in the journal (overrides the \"symbols\" section of the configuration file)")
+	dividendsCmd.Flags().StringVar(&optionsDividends.Account, "account", optionsDividends.Account, "account (and its subaccounts) where the stock is held in the journal")
+	dividendsCmd.Flags().StringVar(&optionsDividends.IncomeAccount, "income-account", optionsDividends.IncomeAccount, "account where the gross dividends are booked")
+	dividendsCmd.Flags().StringVar(&optionsDividends.CashAccount, "cash-account", optionsDividends.CashAccount, "account receiving the net dividends")
+	dividendsCmd.Flags().StringVar(&optionsDividends.TaxAccount, "tax-account", optionsDividends.TaxAccount, "account where the withholding tax is booked")
+	dividendsCmd.Flags().StringVar(&withholdingTaxDividends, "withholding-tax", "", "percentage of the dividends withheld at source")
+}
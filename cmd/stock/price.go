@@ -19,34 +19,56 @@
 package stock
 
 import (
-	"fmt"
+	"errors"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/period"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
 	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
 )
 
 var formatPrice = flags.OutputFormatHledger
 var interval = flags.IntervalWeekly
 var begin string
 var end string
+var periodPrice string
 var adjusted bool
 var full bool
+var as string
+var toPrice string
 
 // priceCmd represents the price command
 var priceCmd = &cobra.Command{
-	Use:   "price [flags] <stock-symbol>",
-	Short: "Get prices for a given stock in a certain time period and interval",
+	Use:   "price [flags] <stock-symbol>...",
+	Short: "Get prices for one or more stocks in a certain time period and interval",
 	Long: `
 hledger-price-tracker
 
-Command to obtain prices for a given stock in a certain time period and interval.
+Command to obtain prices for one or more stocks in a certain time period and interval.
 
 It returns the open, high, low, close, and volume of the stock for each interval 
 in the time period defined. Adjusted close prices are also available.
 
+With an intraday interval (e.g. "5min"), the tables and the "ledger" output show
+the time of each price, while the "hledger" and "beancount" outputs keep only the
+last price of each day.
+
+When several symbols are given, they are fetched concurrently (see '--workers')
+and written in the order given. A symbol that fails does not stop the others,
+and the errors are reported at the end.
+
+With '--to', the prices are converted into another currency with the exchange
+rates of the same interval. When there is no exchange rate for the date of a
+price, the last one before it is used.
+
 API documentation:
+- https://www.alphavantage.co/documentation/#intraday
 - https://www.alphavantage.co/documentation/#daily
 - https://www.alphavantage.co/documentation/#dailyadj
 - https://www.alphavantage.co/documentation/#weekly
@@ -58,12 +80,24 @@ API documentation:
 	Args: cobra.MinimumNArgs(1),
 
 	Run: func(cmd *cobra.Command, args []string) {
-		output, err := price.Execute(args[0], formatPrice, interval, begin, end, adjusted, full)
-		cobra.CheckErr(err)
-		fmt.Print(output)
+		if periodPrice != "" {
+			span, err := period.Parse(periodPrice, time.Now())
+			internal.CheckErr(err)
+			begin, end = span.Dates()
+		}
+		if as != "" && len(args) > 1 {
+			internal.CheckErr(errors.New("[cmd.stock.price] the --as flag can only be used with a single stock symbol"))
+		}
+		apiSymbols := make([]string, len(args))
+		for i, arg := range args {
+			apiSymbols[i] = symbols.ToAPI(arg)
+		}
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		// The prices of each symbol are written as soon as they are fetched, so the output can be piped while the
+		// others are fetched.
+		internal.CheckErr(price.Write(os.Stdout, p, apiSymbols, symbols.ToAPI(toPrice), as, formatPrice, interval, begin, end, adjusted, full))
 	},
-
-	// TODO Implement a way to output an error when the API does not find a stock symbol.
 }
 
 func init() {
@@ -71,10 +105,15 @@ func init() {
 	PaletteCmd.AddCommand(priceCmd)
 
 	// Add flags to the `price` subcommand.
-	priceCmd.Flags().VarP(&formatPrice, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"csv\", \"table\", \"table-long\")")
-	priceCmd.Flags().VarP(&interval, "interval", "i", "interval between prices (possible values are \"daily\", \"weekly\", \"monthly\")")
-	priceCmd.Flags().StringVarP(&begin, "begin", "b", "", "beginning of the time period (format YYYY-MM-DD) (does not apply to  \"json\" or \"csv\" output formats)")
-	priceCmd.Flags().StringVarP(&end, "end", "e", "", "end of the time period (format YYYY-MM-DD) (does not apply to  \"json\" or \"csv\" output formats)")
+	priceCmd.Flags().VarP(&formatPrice, "format", "f", "format of the output (possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"raw-csv\", \"table\", \"table-long\")")
+	priceCmd.Flags().VarP(&interval, "interval", "i", "interval between prices (possible values are \"daily\", \"weekly\", \"monthly\", \"1min\", \"5min\", \"15min\", \"30min\", \"60min\")")
+	priceCmd.Flags().StringVarP(&begin, "begin", "b", "", "beginning of the time period, as a date (e.g. YYYY-MM-DD) or the first day of a period expression (e.g. \"lastmonth\", \"2025Q1\", \"-30d\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	priceCmd.Flags().StringVarP(&end, "end", "e", "", "end of the time period, included, as a date (e.g. YYYY-MM-DD) or the last day of a period expression (e.g. \"lastmonth\", \"2025Q1\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	priceCmd.Flags().StringVarP(&periodPrice, "period", "p", "", "time period, as an hledger period expression (e.g. \"lastmonth\", \"ytd\", \"from 2024-01 to 2024-06\") (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	priceCmd.MarkFlagsMutuallyExclusive("period", "begin")
+	priceCmd.MarkFlagsMutuallyExclusive("period", "end")
 	priceCmd.Flags().BoolVarP(&adjusted, "adjusted", "a", false, "return adjusted close prices")
-	priceCmd.Flags().BoolVar(&full, "full", false, "for daily intervals, return all the data, otherwise return only the last 100 data points (does nothing for weekly or monthly intervals)")
+	priceCmd.Flags().StringVar(&toPrice, "to", "", "currency into which the prices are converted (does not apply to the \"raw-json\" or \"raw-csv\" output formats)")
+	priceCmd.Flags().StringVar(&as, "as", "", "name of the commodity in the output (overrides the \"symbols\" section of the configuration file)")
+	priceCmd.Flags().BoolVar(&full, "full", false, "for daily and intraday intervals, return all the data, otherwise return only the last 100 data points (does nothing for weekly or monthly intervals)")
 }
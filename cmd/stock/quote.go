@@ -0,0 +1,80 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package stock
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/quote"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+// Define the output flag and set it to the default value.
+var formatQuote = flags.OutputFormatHledger
+var asQuote string
+
+// quoteCmd represents the quote command.
+var quoteCmd = &cobra.Command{
+	Use:   "quote [flags] <stock-symbol>...",
+	Short: "Get the latest price of one or more stocks",
+	Long: `
+hledger-price-tracker
+
+Command to get the latest price of one or more stocks, as of the last trading day.
+Each symbol costs one request to the API. With the "hledger" output format, a
+single price directive is printed per stock.
+
+The symbols are fetched concurrently (see '--workers') and written in the order
+given. A symbol that fails does not stop the others, and the errors are reported
+at the end.
+
+API documentation: https://www.alphavantage.co/documentation/#latestprice`,
+
+	// Require the user to provide at least one argument, which is the stock symbol.
+	Args: cobra.MinimumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if asQuote != "" && len(args) > 1 {
+			internal.CheckErr(errors.New("[cmd.stock.quote] the --as flag can only be used with a single stock symbol"))
+		}
+		apiSymbols := make([]string, len(args))
+		for i, arg := range args {
+			apiSymbols[i] = symbols.ToAPI(arg)
+		}
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		// The quotes are written as soon as they are fetched, so the output can be piped while the others are fetched.
+		internal.CheckErr(quote.Write(os.Stdout, p, apiSymbols, asQuote, formatQuote))
+	},
+}
+
+func init() {
+	// Add this subcommand to the `stock` command palette.
+	PaletteCmd.AddCommand(quoteCmd)
+
+	// Add flags to the `quote` subcommand.
+	quoteCmd.Flags().VarP(&formatQuote, "format", "f", "format of the output (possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"raw-csv\", \"table\", \"table-long\")")
+	quoteCmd.Flags().StringVar(&asQuote, "as", "", "name of the commodity in the output (overrides the \"symbols\" section of the configuration file)")
+}
@@ -23,7 +23,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
 	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
 )
 
@@ -47,8 +49,10 @@ API documentation: https://www.alphavantage.co/documentation/#symbolsearch`,
 
 	// TODO Show example with the argument.
 	Run: func(cmd *cobra.Command, args []string) {
-		output, err := search.Execute(args[0], formatSearch)
-		cobra.CheckErr(err)
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		output, err := search.Execute(p, args[0], formatSearch)
+		internal.CheckErr(err)
 		fmt.Println(output)
 	},
 }
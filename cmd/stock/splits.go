@@ -0,0 +1,136 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package stock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/period"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/splits"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+var formatSplits = flags.OutputFormatHledger
+var sourceSplits string
+var quantitySplits string
+var journalFileSplits string
+var beginSplits string
+var endSplits string
+var periodSplits string
+var asSplits string
+var optionsSplits = splits.DefaultOptions()
+
+// splitsCmd represents the splits command.
+var splitsCmd = &cobra.Command{
+	Use:   "splits [flags] <stock-symbol>",
+	Short: "Generate the transactions converting the lots of a stock after its splits",
+	Long: `
+hledger-price-tracker
+
+Command to list the splits of a stock and to generate an hledger transaction
+for each split, so that the quantity held stays correct afterwards.
+
+The quantity held is either given with '--quantity' or read from the postings
+of a journal given with '--file', on the day before each effective date. In
+each account holding the stock, the transaction converts the old lot into the
+new one through the conversion account. These accounts can be set in the
+'splits' section of the configuration file.
+
+The splits come either from the SPLITS endpoint (default) or from the split
+coefficients of the daily adjusted prices ('--source adjusted'), which is a
+premium endpoint.
+
+API documentation:
+- https://www.alphavantage.co/documentation/#splits
+- https://www.alphavantage.co/documentation/#dailyadj`,
+
+	// Require the user to provide exactly one argument, which is the stock symbol, since the splits are generated
+	// for a single stock.
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if periodSplits != "" {
+			span, err := period.Parse(periodSplits, time.Now())
+			internal.CheckErr(err)
+			beginSplits, endSplits = span.Dates()
+		}
+		// The flags take precedence over the configuration file.
+		configured := splits.DefaultOptions()
+		internal.CheckErr(viper.UnmarshalKey("splits", &configured))
+		if cmd.Flags().Changed("account") {
+			configured.Account = optionsSplits.Account
+		}
+		if cmd.Flags().Changed("conversion-account") {
+			configured.ConversionAccount = optionsSplits.ConversionAccount
+		}
+
+		symbol := symbols.ToAPI(args[0])
+		name := asSplits
+		if name == "" {
+			name = symbols.ToJournal(symbol)
+		}
+
+		var holdings splits.Holdings
+		if cmd.Flags().Changed("quantity") {
+			held, err := decimal.Parse(quantitySplits)
+			internal.CheckErr(err)
+			holdings = splits.Quantity(configured.Account, held)
+		} else if journalFileSplits != "" {
+			path, err := journal.ExpandHome(journalFileSplits)
+			internal.CheckErr(err)
+			j, err := journal.Parse(path)
+			internal.CheckErr(err)
+			holdings = splits.JournalHoldings(j, name, configured.Account)
+		}
+
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		output, err := splits.Execute(p, symbol, name, sourceSplits, holdings, configured, formatSplits, beginSplits, endSplits)
+		internal.CheckErr(err)
+		fmt.Print(output)
+	},
+}
+
+func init() {
+	// Add this subcommand to the `stock` command palette.
+	PaletteCmd.AddCommand(splitsCmd)
+
+	// Add flags to the `splits` subcommand.
+	splitsCmd.Flags().VarP(&formatSplits, "format", "f", "format of the output (possible values are \"hledger\", \"json\", \"raw-json\", \"table\", \"table-long\")")
+	splitsCmd.Flags().StringVar(&sourceSplits, "source", splits.SourceSplits, "source of the splits (possible values are \"splits\", \"adjusted\")")
+	splitsCmd.Flags().StringVarP(&quantitySplits, "quantity", "q", "", "quantity of the stock held (takes precedence over --file)")
+	splitsCmd.Flags().StringVar(&journalFileSplits, "file", "", "journal from which the quantity held at each effective date is read")
+	splitsCmd.Flags().StringVarP(&beginSplits, "begin", "b", "", "beginning of the time period, by effective date, as a date (e.g. YYYY-MM-DD) or the first day of a period expression (e.g. \"lastmonth\", \"2025Q1\", \"-30d\") (does not apply to the \"raw-json\" output format)")
+	splitsCmd.Flags().StringVarP(&endSplits, "end", "e", "", "end of the time period, by effective date, included, as a date (e.g. YYYY-MM-DD) or the last day of a period expression (e.g. \"lastmonth\", \"2025Q1\") (does not apply to the \"raw-json\" output format)")
+	splitsCmd.Flags().StringVarP(&periodSplits, "period", "p", "", "time period, by effective date, as an hledger period expression (e.g. \"lastmonth\", \"ytd\", \"from 2024-01 to 2024-06\") (does not apply to the \"raw-json\" output format)")
+	splitsCmd.MarkFlagsMutuallyExclusive("period", "begin")
+	splitsCmd.MarkFlagsMutuallyExclusive("period", "end")
+	splitsCmd.Flags().StringVar(&asSplits, "as", "", "name of the commodity in the journal (overrides the \"symbols\" section of the configuration file)")
+	splitsCmd.Flags().StringVar(&optionsSplits.Account, "account", optionsSplits.Account, "account (and its subaccounts) where the stock is held in the journal")
+	splitsCmd.Flags().StringVar(&optionsSplits.ConversionAccount, "conversion-account", optionsSplits.ConversionAccount, "account balancing the old and the new lots of each split")
+}
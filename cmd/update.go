@@ -0,0 +1,96 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/update"
+)
+
+var journalFile string
+var pricesFile string
+var dryRun bool
+
+// updateCmd represents the update command.
+var updateCmd = &cobra.Command{
+	Use:   "update [flags]",
+	Short: "Append the missing price directives to an hledger journal",
+	Long: `
+hledger-price-tracker
+
+Command to bring the prices of an hledger journal up-to-date.
+
+It reads the journal (and the files it includes) and finds every commodity
+that has postings. For each commodity, it fetches the daily prices after its
+latest 'P' directive (or after its first posting, if there is none) and
+appends only the new 'P' directives to the prices file.
+
+Physical currencies and cryptocurrencies are priced in the default currency
+and every other commodity is considered to be a stock. Commodities whose
+symbol cannot be looked up (e.g. '$') are skipped.
+
+The journal defaults to $LEDGER_FILE or ~/.hledger.journal, like hledger,
+and the prices file defaults to the journal itself.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if journalFile == "" {
+			journalFile = defaultJournalFile()
+		}
+		if pricesFile == "" {
+			pricesFile = journalFile
+		}
+
+		p, err := provider.Selected()
+		internal.CheckErr(err)
+		output, err := update.Execute(p, journalFile, pricesFile)
+
+		// Keep the prices that were fetched even if some commodities failed.
+		if dryRun {
+			fmt.Print(output)
+		} else {
+			internal.CheckErr(update.Append(pricesFile, output))
+		}
+		internal.CheckErr(err)
+	},
+}
+
+// defaultJournalFile returns the journal used by hledger when no file is given.
+func defaultJournalFile() string {
+	if file := os.Getenv("LEDGER_FILE"); file != "" {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	internal.CheckErr(err)
+	return filepath.Join(home, ".hledger.journal")
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().StringVarP(&journalFile, "file", "f", "", "journal to read (default is $LEDGER_FILE or ~/.hledger.journal)")
+	updateCmd.Flags().StringVarP(&pricesFile, "output", "o", "", "file where the new price directives are appended (default is the journal)")
+	updateCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the new price directives instead of appending them")
+}
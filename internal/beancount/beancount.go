@@ -0,0 +1,72 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package beancount formats the prices as Beancount `price` directives.
+package beancount
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxCommodityLength is the maximum length of the name of a commodity in Beancount.
+const maxCommodityLength = 24
+
+// isAlphanumeric reports whether a character can start or end the name of a commodity.
+func isAlphanumeric(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// Commodity returns a name that Beancount accepts for a commodity, derived from its symbol. Beancount commodities are
+// made of up to 24 capital letters, digits, and the characters "'", ".", "_" and "-", start with a letter and end
+// with a letter or a digit. The letters of the symbol are capitalized and the other characters are replaced with
+// "-" (e.g. "BRK/B" gives "BRK-B", while "TSCO.LON" is kept as is). The symbols starting with a digit are prefixed
+// with "X" (e.g. "7203.T" gives "X7203.T"), like the ISO 4217 codes that do not belong to a country.
+func Commodity(symbol string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case isAlphanumeric(r), r == '\'', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, strings.ToUpper(symbol))
+	name = strings.TrimFunc(name, func(r rune) bool {
+		return !isAlphanumeric(r)
+	})
+
+	if name == "" {
+		return "X"
+	}
+	if name[0] < 'A' || name[0] > 'Z' {
+		name = "X" + name
+	}
+	if len(name) > maxCommodityLength {
+		name = strings.TrimRightFunc(name[:maxCommodityLength], func(r rune) bool {
+			return !isAlphanumeric(r)
+		})
+	}
+	return name
+}
+
+// Price returns the `price` directive of a commodity on a given date. The amount is written as is, so it must already
+// be formatted (see internal.FormatPrice).
+func Price(date time.Time, commodity string, amount string, currency string) string {
+	return fmt.Sprintf("%s price %s %s %s\n", date.Format("2006-01-02"), Commodity(commodity), amount, Commodity(currency))
+}
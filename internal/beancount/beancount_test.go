@@ -0,0 +1,50 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package beancount
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommodity(t *testing.T) {
+	tests := map[string]string{
+		"IBM":                           "IBM",
+		"TSCO.LON":                      "TSCO.LON",
+		"BRK/B":                         "BRK-B",
+		"^GSPC":                         "GSPC",
+		"7203.T":                        "X7203.T",
+		"vwce.dex":                      "VWCE.DEX",
+		"€":                             "X",
+		"A.VERY.LONG.SYMBOL.FOR.A.FUND": "A.VERY.LONG.SYMBOL.FOR.A",
+	}
+	for symbol, expected := range tests {
+		if result := Commodity(symbol); result != expected {
+			t.Errorf("expected %s for %s, got %s", expected, symbol, result)
+		}
+	}
+}
+
+func TestPrice(t *testing.T) {
+	expected := "2025-04-04 price TSCO.LON 3.504 GBP\n"
+	result := Price(time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), "TSCO.LON", "3.504", "GBP")
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
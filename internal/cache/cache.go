@@ -0,0 +1,281 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const directoryName = "hledger-price-tracker"
+
+// Path overrides the directory where the responses are stored. When empty, the directory is
+// $XDG_CACHE_HOME/hledger-price-tracker (or the equivalent of the operating system).
+var Path string
+
+// ttlRule associates a time-to-live to the requests whose URL contains a certain pattern.
+type ttlRule struct {
+	pattern string
+	ttl     time.Duration
+}
+
+// ttlRules are checked in order and the first matching rule wins. The lists of currencies and the symbol search
+// rarely change, whereas quotes and intraday prices are only useful for a few minutes.
+var ttlRules = []ttlRule{
+	{"physical_currency_list", 7 * 24 * time.Hour},
+	{"digital_currency_list", 7 * 24 * time.Hour},
+	{"function=SYMBOL_SEARCH", 7 * 24 * time.Hour},
+	{"function=CURRENCY_EXCHANGE_RATE", 5 * time.Minute},
+	{"function=GLOBAL_QUOTE", 5 * time.Minute},
+	{"function=TIME_SERIES_INTRADAY", 5 * time.Minute},
+	{"function=FX_INTRADAY", 5 * time.Minute},
+}
+
+// defaultTTL is used for all the other requests, mostly time series that are updated once a day.
+const defaultTTL = time.Hour
+
+// entry is the structure of each file stored in the cache.
+type entry struct {
+	URL     string    `json:"url"`
+	Fetched time.Time `json:"fetched"`
+	Body    []byte    `json:"body"`
+}
+
+// Stats summarises the content of the cache for a certain endpoint.
+type Stats struct {
+	Endpoint string
+	Entries  int
+	Stale    int
+	Size     int64
+	Oldest   time.Time
+	Newest   time.Time
+}
+
+// Dir returns the directory where the responses are stored.
+func Dir() (string, error) {
+	if Path != "" {
+		return Path, nil
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("[cache.Dir] failure to find the cache directory: %w", err)
+	}
+
+	return filepath.Join(base, directoryName), nil
+}
+
+// StripURL removes the API key from a URL, so it is not written to disk and the same request made with different keys
+// shares the same cache entry.
+func StripURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := parsed.Query()
+	query.Del("apikey")
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// TTL returns how long the response of a certain URL is considered fresh.
+func TTL(rawURL string) time.Duration {
+	for _, rule := range ttlRules {
+		if strings.Contains(rawURL, rule.pattern) {
+			return rule.ttl
+		}
+	}
+	return defaultTTL
+}
+
+// endpoint returns a short name describing the endpoint of a stripped URL, used to group the statistics.
+func endpoint(strippedURL string) string {
+	parsed, err := url.Parse(strippedURL)
+	if err != nil {
+		return strippedURL
+	}
+	if function := parsed.Query().Get("function"); function != "" {
+		return function
+	}
+	return parsed.Host + parsed.Path
+}
+
+// file returns the path of the file that stores the response of a URL.
+func file(rawURL string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(StripURL(rawURL)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// Get returns the stored response of a URL, if there is one and it is still fresh.
+func Get(rawURL string) ([]byte, bool) {
+	path, err := file(rawURL)
+	if err != nil {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var stored entry
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, false
+	}
+	if time.Since(stored.Fetched) > TTL(rawURL) {
+		return nil, false
+	}
+
+	return stored.Body, true
+}
+
+// Put stores the response of a URL.
+func Put(rawURL string, body []byte) error {
+	path, err := file(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("[cache.Put] failure to create the cache directory: %w", err)
+	}
+
+	content, err := json.Marshal(entry{URL: StripURL(rawURL), Fetched: time.Now(), Body: body})
+	if err != nil {
+		return fmt.Errorf("[cache.Put] failure to marshal cache entry: %w", err)
+	}
+
+	// Write to a temporary file first, so a concurrent run never reads a half-written entry. Each write has its own
+	// temporary file, since the same response may be stored by several workers at once (e.g. a leg shared by two
+	// cross rates).
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("[cache.Put] failure to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("[cache.Put] failure to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("[cache.Put] failure to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("[cache.Put] failure to write cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// Clear deletes every stored response and returns how many were deleted.
+func Clear() (int, error) {
+	dir, err := Dir()
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("[cache.Clear] failure to list cache entries: %w", err)
+	}
+
+	for _, path := range files {
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("[cache.Clear] failure to delete cache entry: %w", err)
+		}
+	}
+
+	return len(files), nil
+}
+
+// GetStats returns the statistics of the cache for each endpoint, sorted by endpoint name.
+func GetStats() ([]Stats, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("[cache.GetStats] failure to list cache entries: %w", err)
+	}
+
+	statsByEndpoint := make(map[string]*Stats)
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Deleted in the meantime.
+		} else if err != nil {
+			return nil, fmt.Errorf("[cache.GetStats] failure to read cache entry: %w", err)
+		}
+
+		var stored entry
+		if err := json.Unmarshal(content, &stored); err != nil {
+			continue // Ignore corrupted entries, they will be overwritten.
+		}
+
+		name := endpoint(stored.URL)
+		stats, ok := statsByEndpoint[name]
+		if !ok {
+			stats = &Stats{Endpoint: name, Oldest: stored.Fetched, Newest: stored.Fetched}
+			statsByEndpoint[name] = stats
+		}
+
+		stats.Entries++
+		stats.Size += int64(len(content))
+		if time.Since(stored.Fetched) > TTL(stored.URL) {
+			stats.Stale++
+		}
+		if stored.Fetched.Before(stats.Oldest) {
+			stats.Oldest = stored.Fetched
+		}
+		if stored.Fetched.After(stats.Newest) {
+			stats.Newest = stored.Fetched
+		}
+	}
+
+	result := make([]Stats, 0, len(statsByEndpoint))
+	for _, stats := range statsByEndpoint {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Endpoint < result[j].Endpoint
+	})
+
+	return result, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStripURL(t *testing.T) {
+	t.Run("API key removed", func(t *testing.T) {
+		expected := "https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco"
+		result := StripURL("https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco&apikey=demo")
+		if result != expected {
+			t.Errorf("expected %s, got %s", expected, result)
+		}
+	})
+
+	t.Run("different API keys share the same entry", func(t *testing.T) {
+		first, _ := file("https://www.alphavantage.co/query?function=FX_DAILY&apikey=first")
+		second, _ := file("https://www.alphavantage.co/query?function=FX_DAILY&apikey=second")
+		if first != second {
+			t.Errorf("expected %s and %s to be equal", first, second)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	tests := map[string]time.Duration{
+		"https://www.alphavantage.co/physical_currency_list/":                                        7 * 24 * time.Hour,
+		"https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco":                    7 * 24 * time.Hour,
+		"https://www.alphavantage.co/query?function=CURRENCY_EXCHANGE_RATE&from_currency=USD":        5 * time.Minute,
+		"https://www.alphavantage.co/query?function=TIME_SERIES_WEEKLY&symbol=IBM":                   time.Hour,
+		"https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml":                              time.Hour,
+		"https://www.alphavantage.co/query?function=FX_DAILY&from_symbol=EUR&to_symbol=USD&apikey=x": time.Hour,
+	}
+
+	for url, expected := range tests {
+		if result := TTL(url); result != expected {
+			t.Errorf("expected %s for %s, got %s", expected, url, result)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	Path = t.TempDir()
+	defer func() { Path = "" }()
+
+	searchURL := "https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco&apikey=demo"
+	quoteURL := "https://www.alphavantage.co/query?function=CURRENCY_EXCHANGE_RATE&from_currency=USD&to_currency=JPY&apikey=demo"
+
+	t.Run("miss", func(t *testing.T) {
+		if _, ok := Get(searchURL); ok {
+			t.Error("expected miss, got hit")
+		}
+	})
+
+	t.Run("hit", func(t *testing.T) {
+		if err := Put(searchURL, []byte("body")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		body, ok := Get(searchURL)
+		if !ok {
+			t.Fatal("expected hit, got miss")
+		}
+		if string(body) != "body" {
+			t.Errorf("expected body, got %s", body)
+		}
+	})
+
+	t.Run("API key not stored", func(t *testing.T) {
+		path, _ := file(searchURL)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		var stored entry
+		if err := json.Unmarshal(content, &stored); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if stored.URL != StripURL(searchURL) {
+			t.Errorf("expected %s, got %s", StripURL(searchURL), stored.URL)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		path, _ := file(quoteURL)
+		content, _ := json.Marshal(entry{URL: StripURL(quoteURL), Fetched: time.Now().Add(-time.Hour), Body: []byte("old")})
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, ok := Get(quoteURL); ok {
+			t.Error("expected miss, got hit")
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats, err := GetStats()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(stats) != 2 {
+			t.Fatalf("expected 2 endpoints, got %d", len(stats))
+		}
+		if stats[0].Endpoint != "CURRENCY_EXCHANGE_RATE" || stats[0].Stale != 1 {
+			t.Errorf("expected 1 stale CURRENCY_EXCHANGE_RATE entry, got %+v", stats[0])
+		}
+		if stats[1].Endpoint != "SYMBOL_SEARCH" || stats[1].Entries != 1 {
+			t.Errorf("expected 1 SYMBOL_SEARCH entry, got %+v", stats[1])
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		deleted, err := Clear()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("expected 2, got %d", deleted)
+		}
+		if _, ok := Get(searchURL); ok {
+			t.Error("expected miss, got hit")
+		}
+	})
+}
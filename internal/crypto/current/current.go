@@ -23,8 +23,8 @@ import (
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
-func Execute(from string, to string, format flags.OutputFormat) (string, error) {
+func Execute(provider currencyCurrent.Provider, from string, to string, format flags.OutputFormat) (string, error) {
 	// The exchange rate is given by the same API function for cryptocurrencies and currencies,
 	// so we can use the same function from the analogous module.
-	return currencyCurrent.Execute(from, to, format)
+	return currencyCurrent.Execute(provider, from, to, "", "", format)
 }
@@ -28,10 +28,16 @@ import (
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
-const url = "https://www.alphavantage.co/digital_currency_list/"
-
 type Cryptos map[string]string
 
+// Provider is implemented by every price source able to tell whether it supports a digital currency.
+type Provider interface {
+	CryptoExists(cryptoCode string) (bool, error)
+}
+
+// AlphaVantage implements Provider using the CSV file with the digital currencies supported by Alpha Vantage.
+type AlphaVantage struct{}
+
 func (obj *Cryptos) GenerateOutput(body []byte, format flags.OutputFormat) (string, error) {
 	switch format {
 	case flags.OutputFormatHledger, flags.OutputFormatJSON, flags.OutputFormatTableLong:
@@ -60,8 +66,9 @@ func (obj *Cryptos) GenerateOutput(body []byte, format flags.OutputFormat) (stri
 	}
 }
 
-func CryptoExists(cryptoCode string) (bool, error) {
-	body, err := internal.HTTPRequest(url)
+// CryptoExists reports whether a digital currency is in the list of Alpha Vantage.
+func (AlphaVantage) CryptoExists(cryptoCode string) (bool, error) {
+	body, err := internal.HTTPRequest(internal.DigitalCurrencyListUrl)
 	if err != nil {
 		return false, err
 	}
@@ -77,7 +84,7 @@ func CryptoExists(cryptoCode string) (bool, error) {
 }
 
 func Execute(format flags.OutputFormat) (string, error) {
-	body, err := internal.HTTPRequest(url)
+	body, err := internal.HTTPRequest(internal.DigitalCurrencyListUrl)
 	if err != nil {
 		return "", err
 	}
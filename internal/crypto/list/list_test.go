@@ -18,7 +18,16 @@
 
 package list
 
-import "testing"
+import (
+	"os"
+	"testing"
+
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
 
 func TestListCryptoExists(t *testing.T) {
 	// Define a slice of cryptocurrencies to test.
@@ -26,7 +35,7 @@ func TestListCryptoExists(t *testing.T) {
 
 	for _, crypto := range cryptos {
 		t.Run("crypto exists "+crypto, func(t *testing.T) {
-			result, err := CryptoExists(crypto)
+			result, err := AlphaVantage{}.CryptoExists(crypto)
 			if err != nil {
 				t.Errorf("expected nil, got %v", err)
 			} else if !result {
@@ -40,7 +49,7 @@ func TestListCryptoExists(t *testing.T) {
 
 	for _, currency := range currencies {
 		t.Run("crypto does not exist "+currency, func(t *testing.T) {
-			result, err := CryptoExists(currency)
+			result, err := AlphaVantage{}.CryptoExists(currency)
 			if err != nil {
 				t.Errorf("expected nil, got %v", err)
 			} else if result {
@@ -51,7 +60,7 @@ func TestListCryptoExists(t *testing.T) {
 
 	// Test for empty crypto.
 	t.Run("failure empty", func(t *testing.T) {
-		result, err := CryptoExists("")
+		result, err := AlphaVantage{}.CryptoExists("")
 		if err != nil {
 			t.Errorf("expected nil, got %v", err)
 		} else if result {
@@ -61,7 +70,7 @@ func TestListCryptoExists(t *testing.T) {
 
 	// Test for non-existent crypto.
 	t.Run("failure INVALID", func(t *testing.T) {
-		result, err := CryptoExists("INVALID")
+		result, err := AlphaVantage{}.CryptoExists("INVALID")
 		if err != nil {
 			t.Errorf("expected nil, got %v", err)
 		} else if result {
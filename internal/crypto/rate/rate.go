@@ -0,0 +1,402 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	cryptoList "github.com/lentidas/hledger-price-tracker/internal/crypto/list"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
+)
+
+type Response interface {
+	TypeBody() error
+	ParseBody(body []byte) (Series, error)
+	GenerateOutput(body []byte, begin time.Time, end time.Time, as string, format flags.OutputFormat) (string, error)
+}
+
+// Series is the typed time series of a digital currency in a market, independent of the interval between each point.
+type Series struct {
+	MetaData   TypedMetadata
+	TimeSeries map[time.Time]TypedPrices
+}
+
+// Provider is implemented by every price source able to return the time series of a digital currency in a market.
+type Provider interface {
+	CryptoSeries(from string, to string, interval flags.Interval) (Series, error)
+}
+
+// AlphaVantage implements Provider using the DIGITAL_CURRENCY_DAILY, DIGITAL_CURRENCY_WEEKLY and
+// DIGITAL_CURRENCY_MONTHLY endpoints of the Alpha Vantage API.
+type AlphaVantage struct{}
+
+// RawMetadata is the metadata returned by all the digital currency time series endpoints.
+// Unlike the FX endpoints, the daily series does not have an output size, so a single struct is enough.
+type RawMetadata struct {
+	Information         string `json:"1. Information"`
+	DigitalCurrencyCode string `json:"2. Digital Currency Code"`
+	DigitalCurrencyName string `json:"3. Digital Currency Name"`
+	MarketCode          string `json:"4. Market Code"`
+	MarketName          string `json:"5. Market Name"`
+	LastRefreshed       string `json:"6. Last Refreshed"`
+	TimeZone            string `json:"7. Time Zone"`
+}
+
+type TypedMetadata struct {
+	Information         string
+	DigitalCurrencyCode string
+	DigitalCurrencyName string
+	MarketCode          string
+	MarketName          string
+	LastRefreshed       time.Time
+	TimeZone            string
+}
+
+func (typed *TypedMetadata) TypeBody(raw RawMetadata) error {
+	var lastRefreshed time.Time
+	var err error
+
+	// Try parsing with date and time format first.
+	lastRefreshed, err = time.Parse("2006-01-02 15:04:05", raw.LastRefreshed)
+	if err != nil {
+		// If that fails, try just the date format.
+		lastRefreshed, err = time.Parse("2006-01-02", raw.LastRefreshed)
+		if err != nil {
+			return fmt.Errorf("[crypto.rate.(*TypedMetadata).TypeBody] error parsing last refreshed time: %w", err)
+		}
+	}
+
+	typed.Information = raw.Information
+	typed.DigitalCurrencyCode = raw.DigitalCurrencyCode
+	typed.DigitalCurrencyName = raw.DigitalCurrencyName
+	typed.MarketCode = raw.MarketCode
+	typed.MarketName = raw.MarketName
+	typed.LastRefreshed = lastRefreshed
+	typed.TimeZone = raw.TimeZone
+
+	return nil
+}
+
+type RawPrices struct {
+	Open   string `json:"1. open"`
+	High   string `json:"2. high"`
+	Low    string `json:"3. low"`
+	Close  string `json:"4. close"`
+	Volume string `json:"5. volume"`
+}
+
+// TypedPrices holds the prices of a digital currency for a given date.
+// The volume is a decimal as well, because cryptocurrencies are traded in fractions of a unit.
+type TypedPrices struct {
+	Open   decimal.Decimal
+	High   decimal.Decimal
+	Low    decimal.Decimal
+	Close  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+func (typed *TypedPrices) TypeBody(raw RawPrices) error {
+	openPrice, err := decimal.Parse(raw.Open)
+	if err != nil {
+		return fmt.Errorf("[crypto.rate.(*TypedPrices).TypeBody] error parsing open price: %w", err)
+	}
+	highPrice, err := decimal.Parse(raw.High)
+	if err != nil {
+		return fmt.Errorf("[crypto.rate.(*TypedPrices).TypeBody] error parsing high price: %w", err)
+	}
+	lowPrice, err := decimal.Parse(raw.Low)
+	if err != nil {
+		return fmt.Errorf("[crypto.rate.(*TypedPrices).TypeBody] error parsing low price: %w", err)
+	}
+	closePrice, err := decimal.Parse(raw.Close)
+	if err != nil {
+		return fmt.Errorf("[crypto.rate.(*TypedPrices).TypeBody] error parsing close price: %w", err)
+	}
+	volume, err := decimal.Parse(raw.Volume)
+	if err != nil {
+		return fmt.Errorf("[crypto.rate.(*TypedPrices).TypeBody] error parsing volume: %w", err)
+	}
+
+	typed.Open = openPrice
+	typed.High = highPrice
+	typed.Low = lowPrice
+	typed.Close = closePrice
+	typed.Volume = volume
+
+	return nil
+}
+
+// buildURL creates the URL to make the HTTP request to the Alpha Vantage API.
+// The `from` argument must be a digital currency and `to` a physical currency (the `market` in the API).
+func buildURL(from string, to string, format flags.OutputFormat, interval flags.Interval) (string, error) {
+	if internal.ApiKey == "" {
+		return "", errors.New("[crypto.rate.buildURL] API key is required")
+	}
+
+	fromBoolCrypto, err := cryptoList.AlphaVantage{}.CryptoExists(from)
+	if err != nil {
+		return "", err
+	} else if !fromBoolCrypto {
+		return "", fmt.Errorf("[crypto.rate.buildURL] %w: from cryptocurrency %s", internal.ErrInvalidCurrency, from)
+	}
+
+	toBoolCurrency, err := currencyList.AlphaVantage{}.CurrencyExists(to)
+	if err != nil {
+		return "", err
+	} else if !toBoolCurrency {
+		return "", fmt.Errorf("[crypto.rate.buildURL] %w: to currency (market) %s", internal.ErrInvalidCurrency, to)
+	}
+
+	if _, ok := render.Lookup(format); !ok {
+		switch format {
+		case flags.OutputFormatTable, flags.OutputFormatTableLong, flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+			// Do nothing.
+		default:
+			return "", errors.New("[crypto.rate.buildURL] invalid output format")
+		}
+	}
+
+	url := strings.Builder{}
+	url.WriteString(internal.ApiBaseUrl)
+	url.WriteString("function=")
+
+	switch interval {
+	case flags.IntervalDaily:
+		url.WriteString(apiFunctionCryptoRateDaily)
+	case flags.IntervalWeekly:
+		url.WriteString(apiFunctionCryptoRateWeekly)
+	case flags.IntervalMonthly:
+		url.WriteString(apiFunctionCryptoRateMonthly)
+	default:
+		return "", errors.New("[crypto.rate.buildURL] invalid interval")
+	}
+
+	url.WriteString("&symbol=")
+	url.WriteString(from)
+	url.WriteString("&market=")
+	url.WriteString(to)
+	url.WriteString("&apikey=")
+	url.WriteString(internal.ApiKey)
+
+	if format == flags.OutputFormatRawCSV {
+		url.WriteString("&datatype=csv")
+	}
+
+	return url.String(), nil
+}
+
+// createResponseObject creates a Response object with the proper struct that will be used to parse
+// the JSON body, depending on the interval.
+func createResponseObject(interval flags.Interval) (Response, error) {
+	var obj Response
+
+	switch interval {
+	case flags.IntervalDaily:
+		obj = &Daily{}
+	case flags.IntervalWeekly:
+		obj = &Weekly{}
+	case flags.IntervalMonthly:
+		obj = &Monthly{}
+	default:
+		return obj, errors.New("[crypto.rate.createResponseObject] invalid interval")
+	}
+
+	return obj, nil
+}
+
+// getDates returns the dates in the time series that are within the specified interval.
+func getDates(timeSeries map[time.Time]TypedPrices, begin time.Time, end time.Time) []time.Time {
+	var dates []time.Time
+	for date := range timeSeries {
+		if !(date.Before(begin) || date.After(end)) {
+			dates = append(dates, date)
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	return dates
+}
+
+// records converts the prices of the given dates into records, using the closing price as their amount. The price of
+// the day of the last refresh gets its time.
+func records(metadata TypedMetadata, timeSeries map[time.Time]TypedPrices, dates []time.Time) []render.Record {
+	records := make([]render.Record, 0, len(dates))
+	for _, date := range dates {
+		prices := timeSeries[date]
+		records = append(records, render.Record{
+			Date:      render.Timestamp(date, metadata.LastRefreshed),
+			Commodity: metadata.DigitalCurrencyCode,
+			Amount:    prices.Close,
+			Currency:  metadata.MarketCode,
+			Open:      prices.Open,
+			High:      prices.High,
+			Low:       prices.Low,
+			Close:     prices.Close,
+			Volume:    prices.Volume,
+			Source:    internal.SourceAlphaVantage,
+			Given:     render.FieldsRange | render.FieldsVolume,
+		})
+	}
+	return records
+}
+
+func generateMetadataTable(from string, to string, lastRefreshed time.Time) string {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"From", "To", "Last Refreshed"})
+	t.AppendRow(table.Row{from, to, lastRefreshed.Format("2006-01-02 15:04:05")})
+	return t.Render() + "\n"
+}
+
+// generateTimeSeriesTableShort generates a table with the open, high, low and close prices of each date.
+func generateTimeSeriesTableShort(timeSeries map[time.Time]TypedPrices, dates []time.Time, from string, to string) string {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Date", "Open", "High", "Low", "Close"})
+	for _, date := range dates {
+		prices := timeSeries[date]
+		t.AppendRow([]interface{}{
+			date.Format("2006-01-02"),
+			internal.FormatPrice(prices.Open, from, to),
+			internal.FormatPrice(prices.High, from, to),
+			internal.FormatPrice(prices.Low, from, to),
+			internal.FormatPrice(prices.Close, from, to),
+		})
+	}
+	return t.Render() + "\n"
+}
+
+// generateTimeSeriesTableLong generates the same table as generateTimeSeriesTableShort, but also with the volume.
+func generateTimeSeriesTableLong(timeSeries map[time.Time]TypedPrices, dates []time.Time, from string, to string) string {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Date", "Open", "High", "Low", "Close", "Volume"})
+	for _, date := range dates {
+		prices := timeSeries[date]
+		t.AppendRow([]interface{}{
+			date.Format("2006-01-02"),
+			internal.FormatPrice(prices.Open, from, to),
+			internal.FormatPrice(prices.High, from, to),
+			internal.FormatPrice(prices.Low, from, to),
+			internal.FormatPrice(prices.Close, from, to),
+			prices.Volume.Round(8).String(),
+		})
+	}
+	return t.Render() + "\n"
+}
+
+// generateOutput is shared by the GenerateOutput methods of every interval, since the digital currency endpoints all
+// return the same metadata and price structures.
+func generateOutput(metadata TypedMetadata, timeSeries map[time.Time]TypedPrices, begin time.Time, end time.Time, as string, format flags.OutputFormat) (string, error) {
+	if len(timeSeries) == 0 {
+		return "", fmt.Errorf("[crypto.rate.generateOutput] %w for %s/%s", internal.ErrEmptyTimeSeries, metadata.DigitalCurrencyCode, metadata.MarketCode)
+	}
+
+	dates := getDates(timeSeries, begin, end)
+
+	// The formats made of one directive per price are written by the formatters of the render package.
+	if _, ok := render.Lookup(format); ok {
+		return render.Render(format, records(metadata, timeSeries, dates), as)
+	}
+	if format != flags.OutputFormatTable && format != flags.OutputFormatTableLong {
+		return "", errors.New("[crypto.rate.generateOutput] invalid output format")
+	}
+
+	out := strings.Builder{}
+	out.WriteString(generateMetadataTable(metadata.DigitalCurrencyCode, metadata.MarketCode, metadata.LastRefreshed))
+	if format == flags.OutputFormatTable {
+		out.WriteString(generateTimeSeriesTableShort(timeSeries, dates, metadata.DigitalCurrencyCode, metadata.MarketCode))
+	} else {
+		out.WriteString(generateTimeSeriesTableLong(timeSeries, dates, metadata.DigitalCurrencyCode, metadata.MarketCode))
+	}
+	return out.String(), nil
+}
+
+// request fetches the body of the time series of a digital currency in a market from the Alpha Vantage API.
+func request(from string, to string, format flags.OutputFormat, interval flags.Interval) ([]byte, error) {
+	url, err := buildURL(from, to, format, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		return nil, fmt.Errorf("[crypto.rate.request] %w %s or %s: %w", internal.ErrInvalidCurrency, from, to, err)
+	}
+	return body, err
+}
+
+// CryptoSeries fetches the time series of a digital currency in a market from the Alpha Vantage API and casts it into
+// a Series. It is used by the commands that need the rates themselves rather than their output.
+func (AlphaVantage) CryptoSeries(from string, to string, interval flags.Interval) (Series, error) {
+	body, err := request(from, to, flags.OutputFormatHledger, interval)
+	if err != nil {
+		return Series{}, err
+	}
+
+	response, err := createResponseObject(interval)
+	if err != nil {
+		return Series{}, err
+	}
+
+	series, err := response.ParseBody(body)
+	if err != nil {
+		return Series{}, err
+	}
+	if len(series.TimeSeries) == 0 {
+		return Series{}, fmt.Errorf("[crypto.rate.(AlphaVantage).CryptoSeries] %w for %s/%s", internal.ErrEmptyTimeSeries, from, to)
+	}
+
+	return series, nil
+}
+
+// Execute is the core function of the rate package. It fetches the historical exchange rates between a
+// cryptocurrency and a physical currency from the Alpha Vantage API and returns them in the desired format. The formats
+// of the render package use the names of the journal, and name the cryptocurrency `as` when it is not empty.
+func Execute(from string, to string, as string, format flags.OutputFormat, interval flags.Interval, begin string, end string) (string, error) {
+	beginTime, endTime, err := internal.ValidateDates(begin, end)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := request(from, to, format, interval)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := createResponseObject(interval)
+	if err != nil {
+		return "", err
+	}
+
+	return response.GenerateOutput(body, beginTime, endTime, as, format)
+}
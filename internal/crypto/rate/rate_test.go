@@ -0,0 +1,204 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rate
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+func TestRate(t *testing.T) {
+	internal.ApiKey = "demo"
+
+	t.Run("success from BTC to EUR daily", func(t *testing.T) {
+		if _, err := Execute("BTC", "EUR", "", flags.OutputFormatHledger, flags.IntervalDaily, "", ""); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("success from BTC to EUR weekly", func(t *testing.T) {
+		if _, err := Execute("BTC", "EUR", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", ""); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("success from BTC to EUR monthly", func(t *testing.T) {
+		if _, err := Execute("BTC", "EUR", "", flags.OutputFormatHledger, flags.IntervalMonthly, "", ""); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("no origin cryptocurrency", func(t *testing.T) {
+		if _, err := Execute("", "EUR", "", flags.OutputFormatHledger, flags.IntervalDaily, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("no destination currency", func(t *testing.T) {
+		if _, err := Execute("BTC", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("physical currency as origin", func(t *testing.T) {
+		if _, err := Execute("USD", "EUR", "", flags.OutputFormatHledger, flags.IntervalDaily, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("invalid output format", func(t *testing.T) {
+		if _, err := Execute("BTC", "EUR", "", "invalid", flags.IntervalDaily, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("invalid interval", func(t *testing.T) {
+		if _, err := Execute("BTC", "EUR", "", flags.OutputFormatHledger, "invalid", "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	internal.ApiKey = ""
+
+	t.Run("no API key", func(t *testing.T) {
+		if _, err := Execute("BTC", "EUR", "", flags.OutputFormatHledger, flags.IntervalDaily, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestRateURLBuilder(t *testing.T) {
+	internal.ApiKey = "demo"
+
+	t.Run("daily", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=DIGITAL_CURRENCY_DAILY&symbol=BTC&market=EUR&apikey=demo"
+
+		url, err := buildURL("BTC", "EUR", flags.OutputFormatHledger, flags.IntervalDaily)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("weekly", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=DIGITAL_CURRENCY_WEEKLY&symbol=BTC&market=EUR&apikey=demo"
+
+		url, err := buildURL("BTC", "EUR", flags.OutputFormatHledger, flags.IntervalWeekly)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("monthly", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=DIGITAL_CURRENCY_MONTHLY&symbol=BTC&market=EUR&apikey=demo"
+
+		url, err := buildURL("BTC", "EUR", flags.OutputFormatHledger, flags.IntervalMonthly)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("raw CSV", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=DIGITAL_CURRENCY_DAILY&symbol=BTC&market=EUR&apikey=demo&datatype=csv"
+
+		url, err := buildURL("BTC", "EUR", flags.OutputFormatRawCSV, flags.IntervalDaily)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+}
+
+func TestRateGenerateOutput(t *testing.T) {
+	body := []byte(`{
+		"Meta Data": {
+			"1. Information": "Daily Prices and Volumes for Digital Currency",
+			"2. Digital Currency Code": "BTC",
+			"3. Digital Currency Name": "Bitcoin",
+			"4. Market Code": "EUR",
+			"5. Market Name": "Euro",
+			"6. Last Refreshed": "2025-04-05 00:00:00",
+			"7. Time Zone": "UTC"
+		},
+		"Time Series (Digital Currency Daily)": {
+			"2025-04-05": {
+				"1. open": "76210.12000000",
+				"2. high": "76600.00000000",
+				"3. low": "75900.50000000",
+				"4. close": "76100.25000000",
+				"5. volume": "12.34567890"
+			},
+			"2025-04-04": {
+				"1. open": "75000.00000000",
+				"2. high": "76500.00000000",
+				"3. low": "74800.00000000",
+				"4. close": "76210.12000000",
+				"5. volume": "45.00000000"
+			}
+		}
+	}`)
+
+	t.Run("hledger", func(t *testing.T) {
+		expected := "P 2025-04-04 BTC 76210.12 EUR\nP 2025-04-05 BTC 76100.25 EUR\n"
+
+		output, err := (&Daily{}).GenerateOutput(body, time.Time{}, time.Now(), "", flags.OutputFormatHledger)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("hledger with begin date", func(t *testing.T) {
+		expected := "P 2025-04-05 BTC 76100.25 EUR\n"
+		begin := time.Date(2025, 4, 5, 0, 0, 0, 0, time.UTC)
+
+		output, err := (&Daily{}).GenerateOutput(body, begin, time.Now(), "", flags.OutputFormatHledger)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("wrong interval structure", func(t *testing.T) {
+		_, err := (&Weekly{}).GenerateOutput(body, time.Time{}, time.Now(), "", flags.OutputFormatHledger)
+		if !errors.Is(err, internal.ErrEmptyTimeSeries) {
+			t.Errorf("expected %v, got %v", internal.ErrEmptyTimeSeries, err)
+		}
+	})
+}
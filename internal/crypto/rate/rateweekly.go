@@ -0,0 +1,101 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+)
+
+const apiFunctionCryptoRateWeekly = "DIGITAL_CURRENCY_WEEKLY"
+
+type RawWeekly struct {
+	MetaData   RawMetadata          `json:"Meta Data"`
+	TimeSeries map[string]RawPrices `json:"Time Series (Digital Currency Weekly)"`
+}
+
+type TypedWeekly struct {
+	MetaData   TypedMetadata
+	TimeSeries map[time.Time]TypedPrices
+}
+
+type Weekly struct {
+	Raw   RawWeekly
+	Typed TypedWeekly
+}
+
+func (obj *Weekly) TypeBody() error {
+	err := obj.Typed.MetaData.TypeBody(obj.Raw.MetaData)
+	if err != nil {
+		return fmt.Errorf("[(*Weekly).TypeBody] failure to cast metadata body: %w", err)
+	}
+
+	obj.Typed.TimeSeries = make(map[time.Time]TypedPrices, len(obj.Raw.TimeSeries))
+
+	for date, prices := range obj.Raw.TimeSeries {
+		dateTyped, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return fmt.Errorf("[(*Weekly).TypeBody] error parsing date: %w", err)
+		}
+
+		var pricesTyped TypedPrices
+		err = pricesTyped.TypeBody(prices)
+		if err != nil {
+			return fmt.Errorf("[(*Weekly).TypeBody] error casting prices body: %w", err)
+		}
+
+		obj.Typed.TimeSeries[dateTyped] = pricesTyped
+	}
+
+	return nil
+}
+
+func (obj *Weekly) ParseBody(body []byte) (Series, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Weekly).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Weekly).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return Series{MetaData: obj.Typed.MetaData, TimeSeries: obj.Typed.TimeSeries}, nil
+}
+
+func (obj *Weekly) GenerateOutput(body []byte, begin time.Time, end time.Time, as string, format flags.OutputFormat) (string, error) {
+	switch format {
+	case flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+		return string(body), nil
+	default:
+		// The other formats are checked by generateOutput.
+		series, err := obj.ParseBody(body)
+		if err != nil {
+			return "", err
+		}
+
+		return generateOutput(series.MetaData, series.TimeSeries, begin, end, as, format)
+	}
+}
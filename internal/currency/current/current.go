@@ -22,7 +22,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"io"
 	"strings"
 	"time"
 
@@ -31,16 +31,28 @@ import (
 	"github.com/lentidas/hledger-price-tracker/internal"
 	cryptoList "github.com/lentidas/hledger-price-tracker/internal/crypto/list"
 	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/pool"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
 )
 
 const apiFunctionSearch = "CURRENCY_EXCHANGE_RATE"
 
 type Response interface {
 	TypeBody() error
-	GenerateOutput(body []byte, format flags.OutputFormat) (string, error)
+	ParseBody(body []byte) (Typed, error)
 }
 
+// Provider is implemented by every price source able to return the current exchange rate between two currencies.
+// The raw body is returned alongside the typed exchange rate so the "raw-json" output format can be served as is.
+type Provider interface {
+	ExchangeRate(from string, to string, format flags.OutputFormat) (Typed, []byte, error)
+}
+
+// AlphaVantage implements Provider using the CURRENCY_EXCHANGE_RATE endpoint of the Alpha Vantage API.
+type AlphaVantage struct{}
+
 type Raw struct {
 	RealtimeCurrencyExchangeRate struct {
 		FromCurrencyCode string `json:"1. From_Currency Code"`
@@ -55,16 +67,21 @@ type Raw struct {
 	} `json:"Realtime Currency Exchange Rate"`
 }
 
+// Typed is the exchange rate between two currencies. Pivot is the currency through which the rate was computed, if it
+// is a cross rate (see Triangulate).
 type Typed struct {
 	FromCurrencyCode string
 	FromCurrencyName string
 	ToCurrencyCode   string
 	ToCurrencyName   string
-	ExchangeRate     float64
+	ExchangeRate     decimal.Decimal
 	LastRefreshed    time.Time
 	TimeZone         string
-	BidPrice         float64
-	AskPrice         float64
+	BidPrice         decimal.Decimal
+	AskPrice         decimal.Decimal
+	Pivot            string
+	Source           string
+	Fetched          time.Time
 }
 
 type Current struct {
@@ -78,15 +95,15 @@ func (obj *Current) TypeBody() error {
 		return fmt.Errorf("[(*Current).TypeBody] error parsing last refreshed date: %w", err)
 	}
 
-	exchangeRate, err := strconv.ParseFloat(obj.Raw.RealtimeCurrencyExchangeRate.ExchangeRate, 64)
+	exchangeRate, err := decimal.Parse(obj.Raw.RealtimeCurrencyExchangeRate.ExchangeRate)
 	if err != nil {
 		return fmt.Errorf("[(*Current).TypeBody] error parsing exchange rate: %w", err)
 	}
-	bidPrice, err := strconv.ParseFloat(obj.Raw.RealtimeCurrencyExchangeRate.BidPrice, 64)
+	bidPrice, err := decimal.Parse(obj.Raw.RealtimeCurrencyExchangeRate.BidPrice)
 	if err != nil {
 		return fmt.Errorf("[(*Current).TypeBody] error parsing bid price: %w", err)
 	}
-	askPrice, err := strconv.ParseFloat(obj.Raw.RealtimeCurrencyExchangeRate.AskPrice, 64)
+	askPrice, err := decimal.Parse(obj.Raw.RealtimeCurrencyExchangeRate.AskPrice)
 	if err != nil {
 		return fmt.Errorf("[(*Current).TypeBody] error parsing ask price: %w", err)
 	}
@@ -104,67 +121,72 @@ func (obj *Current) TypeBody() error {
 	return nil
 }
 
-func (obj *Current) GenerateOutput(body []byte, format flags.OutputFormat) (string, error) {
+func (obj *Current) ParseBody(body []byte) (Typed, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Current).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Current).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return obj.Typed, nil
+}
+
+// records converts an exchange rate into the record of its price.
+func records(typed Typed) []render.Record {
+	return []render.Record{{
+		Date:      typed.LastRefreshed,
+		Commodity: typed.FromCurrencyCode,
+		Amount:    typed.ExchangeRate,
+		Currency:  typed.ToCurrencyCode,
+		Close:     typed.ExchangeRate,
+		Source:    typed.Source,
+		Fetched:   typed.Fetched,
+	}}
+}
+
+// GenerateOutput renders an exchange rate in the desired format.
+// The "raw-json" format returns the raw body given by the provider.
+func GenerateOutput(typed Typed, body []byte, format flags.OutputFormat) (string, error) {
+	// The formats made of one directive per rate are written by the formatters of the render package.
+	if _, ok := render.Lookup(format); ok {
+		return render.Render(format, records(typed), "")
+	}
+
 	switch format {
-	case flags.OutputFormatCSV:
-		return "", errors.New("[(*Current).GenerateOutput] CSV output format not supported")
-	case flags.OutputFormatJSON:
+	case flags.OutputFormatRawCSV:
+		return "", errors.New("[currency.current.GenerateOutput] raw CSV output format not supported")
+	case flags.OutputFormatRawJSON:
 		return string(body), nil
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong:
-		// Parse the JSON body into the Raw struct.
-		err := json.Unmarshal(body, &obj.Raw)
-		if err != nil {
-			return "", fmt.Errorf("[(*Current).GenerateOutput] failure to unmarshal JSON body: %w", err)
+	case flags.OutputFormatTable, flags.OutputFormatTableLong:
+		t := table.NewWriter()
+		t.SetStyle(table.StyleLight)
+		header := table.Row{typed.FromCurrencyCode, typed.ToCurrencyCode}
+		row := table.Row{"1", internal.FormatPrice(typed.ExchangeRate, typed.FromCurrencyCode, typed.ToCurrencyCode)}
+		if format == flags.OutputFormatTableLong {
+			header = append(header, "Bid Price", "Ask Price")
+			row = append(row,
+				internal.FormatPrice(typed.BidPrice, typed.FromCurrencyCode, typed.ToCurrencyCode),
+				internal.FormatPrice(typed.AskPrice, typed.FromCurrencyCode, typed.ToCurrencyCode))
 		}
-
-		// Cast the attributes into proper types.
-		err = obj.TypeBody()
-		if err != nil {
-			return "", fmt.Errorf("[(*Current).GenerateOutput] error casting response attributes: %w", err)
+		// Mark the cross rates with the pivot currency they were computed through.
+		if typed.Pivot != "" {
+			header = append(header, "Via")
+			row = append(row, typed.Pivot+" (cross rate)")
 		}
+		header = append(header, "Last Refreshed")
+		row = append(row, typed.LastRefreshed.Format("2006-01-02 15:04:05"))
+		t.AppendHeader(header)
+		t.AppendRow(row)
 
-		if format == flags.OutputFormatHledger {
-			output := fmt.Sprintf("P %s %s %.2f %s\n",
-				obj.Typed.LastRefreshed.Format("2006-01-02"),
-				obj.Typed.FromCurrencyCode,
-				obj.Typed.ExchangeRate,
-				obj.Typed.ToCurrencyCode)
-			return output, nil
-		} else {
-			t := table.NewWriter()
-			t.SetStyle(table.StyleLight)
-			if format == flags.OutputFormatTable {
-				t.AppendHeader(table.Row{
-					obj.Typed.FromCurrencyCode,
-					obj.Typed.ToCurrencyCode,
-					"Last Refreshed",
-				})
-				t.AppendRow(table.Row{
-					"1",
-					obj.Typed.ExchangeRate,
-					obj.Typed.LastRefreshed.Format("2006-01-02 15:04:05"),
-				})
-			} else {
-				t.AppendHeader(table.Row{
-					obj.Typed.FromCurrencyCode,
-					obj.Typed.ToCurrencyCode,
-					"Bid Price",
-					"Ask Price",
-					"Last Refreshed",
-				})
-				t.AppendRow(table.Row{
-					"1",
-					obj.Typed.ExchangeRate,
-					obj.Typed.BidPrice,
-					obj.Typed.AskPrice,
-					obj.Typed.LastRefreshed.Format("2006-01-02 15:04:05"),
-				})
-			}
-
-			return t.Render() + "\n", nil
-		}
+		return t.Render() + "\n", nil
 	default:
-		return "", errors.New("[(*Current).GenerateOutput] invalid output format")
+		return "", errors.New("[currency.current.GenerateOutput] invalid output format")
 	}
 }
 
@@ -181,10 +203,10 @@ func buildURL(from string, to string) (string, error) {
 	}
 
 	// Validate the currency/crypto code.
-	fromBoolCurrency, fromErrorCurrency := currencyList.CurrencyExists(from)
-	toBoolCurrency, toErrorCurrency := currencyList.CurrencyExists(to)
-	fromBoolCrypto, fromErrorCrypto := cryptoList.CryptoExists(from)
-	toBoolCrypto, toErrorCrypto := cryptoList.CryptoExists(to)
+	fromBoolCurrency, fromErrorCurrency := currencyList.AlphaVantage{}.CurrencyExists(from)
+	toBoolCurrency, toErrorCurrency := currencyList.AlphaVantage{}.CurrencyExists(to)
+	fromBoolCrypto, fromErrorCrypto := cryptoList.AlphaVantage{}.CryptoExists(from)
+	toBoolCrypto, toErrorCrypto := cryptoList.AlphaVantage{}.CryptoExists(to)
 	if fromErrorCurrency != nil {
 		return "", fromErrorCurrency
 	} else if toErrorCurrency != nil {
@@ -195,10 +217,10 @@ func buildURL(from string, to string) (string, error) {
 		return "", toErrorCrypto
 	}
 	if !fromBoolCurrency && !fromBoolCrypto {
-		return "", errors.New("[currency/crypto.current.buildURL] from currency is not valid")
+		return "", fmt.Errorf("[currency/crypto.current.buildURL] %w: from currency %s", internal.ErrInvalidCurrency, from)
 	}
 	if !toBoolCurrency && !toBoolCrypto {
-		return "", errors.New("[currency/crypto.current.buildURL] to currency is not valid")
+		return "", fmt.Errorf("[currency/crypto.current.buildURL] %w: to currency %s", internal.ErrInvalidCurrency, to)
 	}
 
 	if from == to {
@@ -219,18 +241,173 @@ func buildURL(from string, to string) (string, error) {
 	return url.String(), nil
 }
 
-func Execute(from string, to string, format flags.OutputFormat) (string, error) {
+// ExchangeRate fetches the current exchange rate from the Alpha Vantage API and casts it into its proper types.
+func (AlphaVantage) ExchangeRate(from string, to string, format flags.OutputFormat) (Typed, []byte, error) {
 	url, err := buildURL(from, to)
 	if err != nil {
-		return "", err
+		return Typed{}, nil, err
 	}
 
 	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		return Typed{}, nil, fmt.Errorf("[currency/crypto.current.(AlphaVantage).ExchangeRate] %w %s or %s: %w", internal.ErrInvalidCurrency, from, to, err)
+	}
 	if err != nil {
-		return "", err
+		return Typed{}, nil, err
+	}
+
+	// The raw format does not need the body to be parsed.
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return Typed{}, body, nil
 	}
 
 	response := Current{}
+	typed, err := response.ParseBody(body)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	typed.Source = internal.SourceAlphaVantage
+	typed.Fetched = time.Now()
+
+	return typed, body, nil
+}
+
+// Triangulate combines the exchange rates from → pivot and pivot → to into the cross rate from → to. The cross rate is
+// only as fresh as the oldest of both legs.
+func Triangulate(first Typed, second Typed) (Typed, error) {
+	if first.ToCurrencyCode != second.FromCurrencyCode {
+		return Typed{}, fmt.Errorf("[currency.current.Triangulate] legs %s/%s and %s/%s do not share a pivot currency",
+			first.FromCurrencyCode, first.ToCurrencyCode, second.FromCurrencyCode, second.ToCurrencyCode)
+	}
+
+	cross := Typed{
+		FromCurrencyCode: first.FromCurrencyCode,
+		FromCurrencyName: first.FromCurrencyName,
+		ToCurrencyCode:   second.ToCurrencyCode,
+		ToCurrencyName:   second.ToCurrencyName,
+		ExchangeRate:     first.ExchangeRate.Mul(second.ExchangeRate),
+		LastRefreshed:    first.LastRefreshed,
+		TimeZone:         first.TimeZone,
+		BidPrice:         first.BidPrice.Mul(second.BidPrice),
+		AskPrice:         first.AskPrice.Mul(second.AskPrice),
+		Pivot:            first.ToCurrencyCode,
+		Source:           render.JoinSources(first.Source, second.Source),
+		Fetched:          first.Fetched,
+	}
+	if second.LastRefreshed.Before(cross.LastRefreshed) {
+		cross.LastRefreshed = second.LastRefreshed
+	}
+	if second.Fetched.After(cross.Fetched) {
+		cross.Fetched = second.Fetched
+	}
+
+	return cross, nil
+}
+
+// Pair is a pair of currencies whose current exchange rate is fetched. If Pivot is not empty, the rate is a cross rate
+// computed through it.
+type Pair struct {
+	From  string
+	To    string
+	Pivot string
+}
+
+// rate fetches the current exchange rate of a pair of currencies from the given provider.
+func rate(provider Provider, pair Pair, format flags.OutputFormat) (Typed, []byte, error) {
+	if pair.Pivot == "" {
+		return provider.ExchangeRate(pair.From, pair.To, format)
+	}
+
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return Typed{}, nil, errors.New("[currency.current.rate] raw JSON and CSV output formats not supported for cross rates")
+	}
+	if pair.Pivot == pair.From || pair.Pivot == pair.To {
+		return Typed{}, nil, errors.New("[currency.current.rate] pivot currency must be different from the from and to currencies")
+	}
 
-	return response.GenerateOutput(body, format)
+	first, _, err := provider.ExchangeRate(pair.From, pair.Pivot, format)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	second, _, err := provider.ExchangeRate(pair.Pivot, pair.To, format)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	cross, err := Triangulate(first, second)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	return cross, nil, nil
+}
+
+// Execute is the core function of the current package. It fetches the current exchange rate between two currencies
+// from the given provider and returns it in the desired format. If `pivot` is not empty, the rate is a cross rate
+// computed through the pivot currency. The formats of the render package use the names of the journal, and name the
+// origin currency `as` when it is not empty.
+func Execute(provider Provider, from string, to string, pivot string, as string, format flags.OutputFormat) (string, error) {
+	out := strings.Builder{}
+	if err := Write(&out, provider, []Pair{{From: from, To: to, Pivot: pivot}}, as, format); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// fetched is the result of the fetch of the exchange rate of a pair.
+type fetched struct {
+	typed Typed
+	body  []byte
+}
+
+// Write is like Execute for one or more pairs of currencies, but writes the rate of each pair to `out` as soon as it
+// and the ones of the previous pairs are fetched. The pairs are fetched concurrently by at most internal.Workers
+// workers, and written in the order they are given. A pair that fails does not prevent the others from being written,
+// and the errors of all of them are returned at the end.
+func Write(out io.Writer, provider Provider, pairs []Pair, as string, format flags.OutputFormat) error {
+	if len(pairs) == 0 {
+		return errors.New("[currency.current.Write] no currency pair provided")
+	}
+	if format == flags.OutputFormatRawJSON && len(pairs) > 1 {
+		return errors.New("[currency.current.Write] raw JSON output format only supports a single currency pair")
+	}
+
+	// The formats made of one directive per rate are written by a writer of the render package, which keeps the
+	// records of the formats that need all of them at once.
+	var writer *render.Writer
+	if _, ok := render.Lookup(format); ok {
+		var err error
+		writer, err = render.NewWriter(out, format, as)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	err := pool.Run(len(pairs), internal.Workers, func(i int) (fetched, error) {
+		typed, body, err := rate(provider, pairs[i], format)
+		return fetched{typed: typed, body: body}, err
+	}, func(i int, result fetched, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[currency.current.Write] failed to fetch the rate from %s to %s: %w", pairs[i].From, pairs[i].To, err))
+			return nil
+		}
+		if writer != nil {
+			return writer.Write(records(result.typed))
+		}
+		output, err := GenerateOutput(result.typed, result.body, format)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(out, output)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if writer != nil {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
 }
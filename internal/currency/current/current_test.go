@@ -19,51 +19,123 @@
 package current
 
 import (
+	"os"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
 func TestCurrent(t *testing.T) {
 	internal.ApiKey = "demo"
-	sleepTime := time.Duration(500) * time.Millisecond
 
 	t.Run("success from USD to JPY", func(t *testing.T) {
-		if _, err := Execute("USD", "JPY", flags.OutputFormatHledger); err != nil {
+		if _, err := Execute(AlphaVantage{}, "USD", "JPY", "", "", flags.OutputFormatHledger); err != nil {
 			t.Errorf("expected nil, got %v", err)
 		}
-		time.Sleep(sleepTime)
 	})
 
 	t.Run("success from BTC to EUR", func(t *testing.T) {
-		if _, err := Execute("BTC", "EUR", flags.OutputFormatHledger); err != nil {
+		if _, err := Execute(AlphaVantage{}, "BTC", "EUR", "", "", flags.OutputFormatHledger); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("success cross rate from BTC to USD", func(t *testing.T) {
+		expected := "P 2025-04-04 BTC 83534.58183 USD\n"
+		output, err := Execute(AlphaVantage{}, "BTC", "USD", "EUR", "", flags.OutputFormatHledger)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success cross rate beancount", func(t *testing.T) {
+		expected := "2025-04-04 price BTC 83534.58183 USD\n"
+		output, err := Execute(AlphaVantage{}, "BTC", "USD", "EUR", "", flags.OutputFormatBeancount)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success cross rate ledger", func(t *testing.T) {
+		expected := "P 2025/04/04 22:55:01 BTC 83534.58183 USD\n"
+		output, err := Execute(AlphaVantage{}, "BTC", "USD", "EUR", "", flags.OutputFormatLedger)
+		if err != nil {
 			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success cross rate table", func(t *testing.T) {
+		output, err := Execute(AlphaVantage{}, "BTC", "USD", "EUR", "", flags.OutputFormatTable)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.Contains(output, "EUR (cross rate)") {
+			t.Errorf("expected the cross rate to be marked, got %s", output)
+		}
+	})
+
+	t.Run("several pairs", func(t *testing.T) {
+		// The pairs are fetched concurrently, but written in order, and a failure does not stop the others.
+		internal.Workers = 3
+		defer func() { internal.Workers = 0 }()
+
+		expected := "P 2025-04-05 USD 146.88 JPY\nP 2025-04-04 BTC 83534.58183 USD\nP 2025-04-05 BTC 76120.45 EUR\n"
+		out := strings.Builder{}
+		pairs := []Pair{{From: "USD", To: "JPY"}, {From: "INVALID", To: "JPY"}, {From: "BTC", To: "USD", Pivot: "EUR"}, {From: "BTC", To: "EUR"}}
+		err := Write(&out, AlphaVantage{}, pairs, "", flags.OutputFormatHledger)
+		if err == nil || !strings.Contains(err.Error(), "INVALID") {
+			t.Errorf("expected an error for the invalid pair, got %v", err)
+		}
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("several pairs with raw JSON output format", func(t *testing.T) {
+		pairs := []Pair{{From: "USD", To: "JPY"}, {From: "BTC", To: "EUR"}}
+		if err := Write(&strings.Builder{}, AlphaVantage{}, pairs, "", flags.OutputFormatRawJSON); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("cross rate with raw JSON output format", func(t *testing.T) {
+		if _, err := Execute(AlphaVantage{}, "BTC", "USD", "EUR", "", flags.OutputFormatRawJSON); err == nil {
+			t.Error("expected error, got nil")
 		}
-		time.Sleep(sleepTime)
 	})
 
 	t.Run("no origin currency", func(t *testing.T) {
-		if _, err := Execute("", "JPY", flags.OutputFormatHledger); err == nil {
+		if _, err := Execute(AlphaVantage{}, "", "JPY", "", "", flags.OutputFormatHledger); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("no destination currency", func(t *testing.T) {
-		if _, err := Execute("USD", "", flags.OutputFormatHledger); err == nil {
+		if _, err := Execute(AlphaVantage{}, "USD", "", "", "", flags.OutputFormatHledger); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid currency", func(t *testing.T) {
-		if _, err := Execute("INVALID", "JPY", flags.OutputFormatHledger); err == nil {
+		if _, err := Execute(AlphaVantage{}, "INVALID", "JPY", "", "", flags.OutputFormatHledger); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid output format", func(t *testing.T) {
-		if _, err := Execute("USD", "JPY", "csv"); err == nil {
+		if _, err := Execute(AlphaVantage{}, "USD", "JPY", "", "", flags.OutputFormatRawCSV); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -71,7 +143,7 @@ func TestCurrent(t *testing.T) {
 	internal.ApiKey = ""
 
 	t.Run("no API key", func(t *testing.T) {
-		if _, err := Execute("USD", "JPY", flags.OutputFormatHledger); err == nil {
+		if _, err := Execute(AlphaVantage{}, "USD", "JPY", "", "", flags.OutputFormatHledger); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -81,7 +153,7 @@ func TestCurrentURLBuilder(t *testing.T) {
 	internal.ApiKey = "demo"
 
 	t.Run("currency to currency", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=CURRENCY_EXCHANGE_RATE&from_currency=USD&to_currency=JPY&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=CURRENCY_EXCHANGE_RATE&from_currency=USD&to_currency=JPY&apikey=demo"
 
 		url, err := buildURL("USD", "JPY")
 		if err != nil {
@@ -92,7 +164,7 @@ func TestCurrentURLBuilder(t *testing.T) {
 	})
 
 	t.Run("crypto to currency", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=CURRENCY_EXCHANGE_RATE&from_currency=BTC&to_currency=EUR&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=CURRENCY_EXCHANGE_RATE&from_currency=BTC&to_currency=EUR&apikey=demo"
 
 		url, err := buildURL("BTC", "EUR")
 		if err != nil {
@@ -28,45 +28,61 @@ import (
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
-const url = "https://www.alphavantage.co/physical_currency_list/"
-
+// Currencies maps the code of each physical currency to its name.
 type Currencies map[string]string
 
-func (obj *Currencies) GenerateOutput(body []byte, format flags.OutputFormat) (string, error) {
+// Provider is implemented by every price source able to list the physical currencies it supports.
+// The raw body is returned alongside the parsed list so the "csv" output format can be served as is.
+type Provider interface {
+	Currencies() (Currencies, []byte, error)
+	// CurrencyExists reports whether a physical currency is supported.
+	CurrencyExists(currencyCode string) (bool, error)
+}
+
+// AlphaVantage implements Provider using the CSV file with the physical currencies supported by Alpha Vantage.
+type AlphaVantage struct{}
+
+// GenerateOutput renders the list of currencies in the desired format.
+func GenerateOutput(currencies Currencies, body []byte, format flags.OutputFormat) (string, error) {
 	switch format {
 	case flags.OutputFormatHledger, flags.OutputFormatJSON, flags.OutputFormatTableLong:
-		errorMessage := fmt.Sprintf("[(*Currencies).GenerateOutput] %s output format not supported", format)
+		errorMessage := fmt.Sprintf("[currency.list.GenerateOutput] %s output format not supported", format)
 		return "", errors.New(errorMessage)
 	case flags.OutputFormatCSV:
 		return string(body), nil
 	case flags.OutputFormatTable:
-		data, err := internal.ParseCurrenciesCSV(body)
-		if err != nil {
-			return "", err
-		}
-		*obj = data
-
 		// Create a table and send it to the output.
 		t := table.NewWriter()
 		t.SetStyle(table.StyleLight)
 		t.AppendHeader(table.Row{"Code", "Currency Name"})
-		for currencyCode, currencyName := range *obj {
+		for currencyCode, currencyName := range currencies {
 			t.AppendRow(table.Row{currencyCode, currencyName})
 		}
 		t.SortBy([]table.SortBy{{Name: "Code", Mode: table.Asc}})
 		return t.Render() + "\n", nil
 	default:
-		return "", errors.New("[internal.search.generateSearchOutput] invalid output format")
+		return "", errors.New("[currency.list.GenerateOutput] invalid output format")
 	}
 }
 
-func CurrencyExists(currencyCode string) (bool, error) {
-	body, err := internal.HTTPRequest(url)
+// Currencies downloads the list of physical currencies supported by Alpha Vantage.
+func (AlphaVantage) Currencies() (Currencies, []byte, error) {
+	body, err := internal.HTTPRequest(internal.PhysicalCurrencyListUrl)
 	if err != nil {
-		return false, err
+		return nil, nil, err
 	}
 
 	currencies, err := internal.ParseCurrenciesCSV(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return currencies, body, nil
+}
+
+// CurrencyExists reports whether a physical currency is in the list of Alpha Vantage.
+func (p AlphaVantage) CurrencyExists(currencyCode string) (bool, error) {
+	currencies, _, err := p.Currencies()
 	if err != nil {
 		return false, err
 	}
@@ -76,13 +92,13 @@ func CurrencyExists(currencyCode string) (bool, error) {
 	return exists, nil
 }
 
-func Execute(format flags.OutputFormat) (string, error) {
-	body, err := internal.HTTPRequest(url)
+// Execute is the core function of the list package. It lists the physical currencies supported by the given
+// provider in the desired format.
+func Execute(provider Provider, format flags.OutputFormat) (string, error) {
+	currencies, body, err := provider.Currencies()
 	if err != nil {
 		return "", err
 	}
 
-	response := Currencies{}
-
-	return response.GenerateOutput(body, format)
+	return GenerateOutput(currencies, body, format)
 }
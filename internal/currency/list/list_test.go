@@ -18,7 +18,16 @@
 
 package list
 
-import "testing"
+import (
+	"os"
+	"testing"
+
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
 
 func TestListCurrencyExists(t *testing.T) {
 	// Define a slice of currencies to test.
@@ -26,7 +35,7 @@ func TestListCurrencyExists(t *testing.T) {
 
 	for _, currency := range currencies {
 		t.Run("currency exists "+currency, func(t *testing.T) {
-			result, err := CurrencyExists(currency)
+			result, err := AlphaVantage{}.CurrencyExists(currency)
 			if err != nil {
 				t.Errorf("expected nil, got %v", err)
 			} else if !result {
@@ -40,7 +49,7 @@ func TestListCurrencyExists(t *testing.T) {
 
 	for _, crypto := range cryptos {
 		t.Run("crypto does not exist "+crypto, func(t *testing.T) {
-			result, err := CurrencyExists(crypto)
+			result, err := AlphaVantage{}.CurrencyExists(crypto)
 			if err != nil {
 				t.Errorf("expected nil, got %v", err)
 			} else if result {
@@ -51,7 +60,7 @@ func TestListCurrencyExists(t *testing.T) {
 
 	// Test for empty currency.
 	t.Run("failure empty", func(t *testing.T) {
-		result, err := CurrencyExists("")
+		result, err := AlphaVantage{}.CurrencyExists("")
 		if err != nil {
 			t.Errorf("expected nil, got %v", err)
 		} else if result {
@@ -61,7 +70,7 @@ func TestListCurrencyExists(t *testing.T) {
 
 	// Test for non-existent currency.
 	t.Run("failure INVALID", func(t *testing.T) {
-		result, err := CurrencyExists("INVALID")
+		result, err := AlphaVantage{}.CurrencyExists("INVALID")
 		if err != nil {
 			t.Errorf("expected nil, got %v", err)
 		} else if result {
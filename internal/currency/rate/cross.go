@@ -0,0 +1,230 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	cryptoList "github.com/lentidas/hledger-price-tracker/internal/crypto/list"
+	cryptoRate "github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
+)
+
+// Sources is implemented by the providers able to fetch every leg of a cross rate. The FX series do not include the
+// digital currencies, so the provider must also tell which currencies are digital and return their own series.
+type Sources interface {
+	Provider
+	currencyList.Provider
+	cryptoList.Provider
+	cryptoRate.Provider
+}
+
+// isCrypto returns whether a currency is only known as a digital currency by the provider. The currencies that are both
+// physical and digital are considered physical, so they can be fetched as FX series.
+func isCrypto(provider Sources, currency string) (bool, error) {
+	physical, err := provider.CurrencyExists(currency)
+	if err != nil || physical {
+		return false, err
+	}
+	return provider.CryptoExists(currency)
+}
+
+// fromCryptoSeries converts the time series of a digital currency in a market into a Series.
+func fromCryptoSeries(crypto cryptoRate.Series) Series {
+	series := Series{
+		MetaData: TypedMetadata{
+			Information:   crypto.MetaData.Information,
+			FromSymbol:    crypto.MetaData.DigitalCurrencyCode,
+			ToSymbol:      crypto.MetaData.MarketCode,
+			LastRefreshed: crypto.MetaData.LastRefreshed,
+			TimeZone:      crypto.MetaData.TimeZone,
+		},
+		Source:     internal.SourceAlphaVantage,
+		Fetched:    time.Now(),
+		TimeSeries: make(map[time.Time]TypedPrices, len(crypto.TimeSeries)),
+	}
+	for date, prices := range crypto.TimeSeries {
+		series.TimeSeries[date] = TypedPrices{
+			Open:  prices.Open,
+			High:  prices.High,
+			Low:   prices.Low,
+			Close: prices.Close,
+		}
+	}
+	return series
+}
+
+// invert returns the series of the opposite exchange rates. The highest rate becomes the lowest and vice versa.
+// The dates with a zero rate are dropped, since it has no opposite.
+func invert(series Series) Series {
+	one := decimal.New(1, 0)
+	inverted := series
+	inverted.MetaData.FromSymbol, inverted.MetaData.ToSymbol = series.MetaData.ToSymbol, series.MetaData.FromSymbol
+	inverted.TimeSeries = make(map[time.Time]TypedPrices, len(series.TimeSeries))
+	for date, prices := range series.TimeSeries {
+		if prices.Open.IsZero() || prices.High.IsZero() || prices.Low.IsZero() || prices.Close.IsZero() {
+			continue
+		}
+		inverted.TimeSeries[date] = TypedPrices{
+			Open:  one.Div(prices.Open),
+			High:  one.Div(prices.Low),
+			Low:   one.Div(prices.High),
+			Close: one.Div(prices.Close),
+		}
+	}
+	return inverted
+}
+
+// fetchLeg fetches one of the legs of a cross rate. The FX series do not include the digital currencies, so a leg with
+// a digital currency on one side is read from the series of the digital currency instead.
+func fetchLeg(provider Sources, from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (Series, error) {
+	fromCrypto, err := isCrypto(provider, from)
+	if err != nil {
+		return Series{}, err
+	}
+	toCrypto, err := isCrypto(provider, to)
+	if err != nil {
+		return Series{}, err
+	}
+
+	switch {
+	case fromCrypto && toCrypto:
+		return Series{}, fmt.Errorf("[currency.rate.fetchLeg] no exchange rates between two digital currencies (%s and %s)", from, to)
+	case fromCrypto:
+		series, err := provider.CryptoSeries(from, to, interval)
+		if err != nil {
+			return Series{}, err
+		}
+		return fromCryptoSeries(series), nil
+	case toCrypto:
+		series, err := provider.CryptoSeries(to, from, interval)
+		if err != nil {
+			return Series{}, err
+		}
+		return invert(fromCryptoSeries(series)), nil
+	default:
+		series, _, err := provider.FXSeries(from, to, format, interval, full)
+		return series, err
+	}
+}
+
+// Period returns the key identifying the period of a point, so the points of two series can be aligned even if their
+// endpoints do not date them the same way (e.g. the weekly FX series end on Fridays, whereas the weekly digital
+// currency series end on Sundays).
+func Period(date time.Time, interval flags.Interval) string {
+	switch interval {
+	case flags.IntervalWeekly:
+		year, week := date.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case flags.IntervalMonthly:
+		return date.Format("2006-01")
+	case flags.IntervalDaily:
+		return date.Format("2006-01-02")
+	default:
+		return date.Format(time.RFC3339)
+	}
+}
+
+// Triangulate combines the series from → pivot and pivot → to into the series from → to. Only the periods present
+// in both legs are kept, and each point is dated as in the first leg. The open and close rates are exact, whereas
+// the high and low rates are bounds, since both legs do not necessarily reach their extremes at the same time.
+func Triangulate(first Series, second Series, interval flags.Interval) (Series, error) {
+	if first.MetaData.ToSymbol != second.MetaData.FromSymbol {
+		return Series{}, fmt.Errorf("[currency.rate.Triangulate] legs %s/%s and %s/%s do not share a pivot currency",
+			first.MetaData.FromSymbol, first.MetaData.ToSymbol, second.MetaData.FromSymbol, second.MetaData.ToSymbol)
+	}
+
+	seconds := make(map[string]TypedPrices, len(second.TimeSeries))
+	for date, prices := range second.TimeSeries {
+		seconds[Period(date, interval)] = prices
+	}
+
+	cross := Series{
+		MetaData: TypedMetadata{
+			Information:   fmt.Sprintf("Cross rates through %s", first.MetaData.ToSymbol),
+			FromSymbol:    first.MetaData.FromSymbol,
+			ToSymbol:      second.MetaData.ToSymbol,
+			LastRefreshed: first.MetaData.LastRefreshed,
+			TimeZone:      first.MetaData.TimeZone,
+		},
+		Intraday:   first.Intraday,
+		Pivot:      first.MetaData.ToSymbol,
+		Source:     render.JoinSources(first.Source, second.Source),
+		Fetched:    first.Fetched,
+		TimeSeries: make(map[time.Time]TypedPrices, len(first.TimeSeries)),
+	}
+	// The cross rates are only as fresh as the oldest of both legs.
+	if second.MetaData.LastRefreshed.Before(cross.MetaData.LastRefreshed) {
+		cross.MetaData.LastRefreshed = second.MetaData.LastRefreshed
+	}
+	// They were fetched when the last of both legs was.
+	if second.Fetched.After(cross.Fetched) {
+		cross.Fetched = second.Fetched
+	}
+
+	for date, prices := range first.TimeSeries {
+		other, ok := seconds[Period(date, interval)]
+		if !ok {
+			continue
+		}
+		cross.TimeSeries[date] = TypedPrices{
+			Open:  prices.Open.Mul(other.Open),
+			High:  prices.High.Mul(other.High),
+			Low:   prices.Low.Mul(other.Low),
+			Close: prices.Close.Mul(other.Close),
+		}
+	}
+
+	return cross, nil
+}
+
+// crossSeries fetches both legs of the cross rate between two currencies through a pivot currency and combines them.
+func crossSeries(provider Sources, from string, to string, pivot string, format flags.OutputFormat, interval flags.Interval, full bool) (Series, error) {
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return Series{}, errors.New("[currency.rate.crossSeries] raw JSON and CSV output formats not supported for cross rates")
+	}
+	if pivot == from || pivot == to {
+		return Series{}, errors.New("[currency.rate.crossSeries] pivot currency must be different from the from and to currencies")
+	}
+
+	first, err := fetchLeg(provider, from, pivot, format, interval, full)
+	if err != nil {
+		return Series{}, err
+	}
+	second, err := fetchLeg(provider, pivot, to, format, interval, full)
+	if err != nil {
+		return Series{}, err
+	}
+
+	cross, err := Triangulate(first, second, interval)
+	if err != nil {
+		return Series{}, err
+	}
+	if len(cross.TimeSeries) == 0 {
+		return Series{}, fmt.Errorf("[currency.rate.crossSeries] no common dates between %s/%s and %s/%s", from, pivot, pivot, to)
+	}
+
+	return cross, nil
+}
@@ -22,7 +22,6 @@ import (
 	"errors"
 	"fmt"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -30,16 +29,41 @@ import (
 
 	"github.com/lentidas/hledger-price-tracker/internal"
 	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
 )
 
 // TODO Add documentation to each of the functions
 
 type Response interface {
 	TypeBody() error
-	GenerateOutput(body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error)
+	ParseBody(body []byte) (Series, error)
 }
 
+// Series is the typed time series of exchange rates between two currencies, independent of the provider it came
+// from and of the interval between each point. Intraday series have a timestamp instead of a date as key.
+// Pivot is the currency through which the rates were computed, if they are cross rates (see Triangulate).
+type Series struct {
+	MetaData   TypedMetadata
+	Intraday   bool
+	Pivot      string
+	Source     string
+	Fetched    time.Time
+	TimeSeries map[time.Time]TypedPrices
+}
+
+// Provider is implemented by every price source able to return a time series of exchange rates.
+// The raw body is returned alongside the typed series so the "raw-json" and "raw-csv" output formats can be served
+// as is. When one of those formats is requested, providers are free to return an empty Series.
+type Provider interface {
+	FXSeries(from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (Series, []byte, error)
+}
+
+// AlphaVantage implements Provider using the FX_INTRADAY, FX_DAILY, FX_WEEKLY and FX_MONTHLY endpoints of the
+// Alpha Vantage API.
+type AlphaVantage struct{}
+
 type RawMetadata struct {
 	Information   string `json:"1. Information"`
 	FromSymbol    string `json:"2. From Symbol"`
@@ -121,6 +145,17 @@ func (typed *TypedMetadataDaily) TypeBody(raw RawMetadataDaily) error {
 	return nil
 }
 
+// toTypedMetadata drops the fields specific to the daily metadata, so it can be used in a Series.
+func (typed *TypedMetadataDaily) toTypedMetadata() TypedMetadata {
+	return TypedMetadata{
+		Information:   typed.Information,
+		FromSymbol:    typed.FromSymbol,
+		ToSymbol:      typed.ToSymbol,
+		LastRefreshed: typed.LastRefreshed,
+		TimeZone:      typed.TimeZone,
+	}
+}
+
 type RawPrices struct {
 	Open  string `json:"1. open"`
 	High  string `json:"2. high"`
@@ -129,26 +164,26 @@ type RawPrices struct {
 }
 
 type TypedPrices struct {
-	Open  float64
-	High  float64
-	Low   float64
-	Close float64
+	Open  decimal.Decimal
+	High  decimal.Decimal
+	Low   decimal.Decimal
+	Close decimal.Decimal
 }
 
 func (typed *TypedPrices) TypeBody(raw RawPrices) error {
-	openPrice, err := strconv.ParseFloat(raw.Open, 64)
+	openPrice, err := decimal.Parse(raw.Open)
 	if err != nil {
 		return fmt.Errorf("[currency.rate.(*TypedPrices).TypeBody] error parsing open price: %w", err)
 	}
-	highPrice, err := strconv.ParseFloat(raw.High, 64)
+	highPrice, err := decimal.Parse(raw.High)
 	if err != nil {
 		return fmt.Errorf("[currency.rate.(*TypedPrices).TypeBody] error parsing high price: %w", err)
 	}
-	lowPrice, err := strconv.ParseFloat(raw.Low, 64)
+	lowPrice, err := decimal.Parse(raw.Low)
 	if err != nil {
 		return fmt.Errorf("[currency.rate.(*TypedPrices).TypeBody] error parsing low price: %w", err)
 	}
-	closePrice, err := strconv.ParseFloat(raw.Close, 64)
+	closePrice, err := decimal.Parse(raw.Close)
 	if err != nil {
 		return fmt.Errorf("[currency.rate.(*TypedPrices).TypeBody] error parsing close price: %w", err)
 	}
@@ -166,29 +201,31 @@ func buildURL(from string, to string, format flags.OutputFormat, interval flags.
 		return "", errors.New("[currency.rate.buildURL] API key is required")
 	}
 
-	fromBoolCurrency, err := currencyList.CurrencyExists(from)
+	fromBoolCurrency, err := currencyList.AlphaVantage{}.CurrencyExists(from)
 	if err != nil {
 		return "", err
 	} else if !fromBoolCurrency {
-		return "", errors.New("[currency.rate.buildURL] from currency is not valid")
+		return "", fmt.Errorf("[currency.rate.buildURL] %w: from currency %s", internal.ErrInvalidCurrency, from)
 	}
 
-	toBoolCurrency, err := currencyList.CurrencyExists(to)
+	toBoolCurrency, err := currencyList.AlphaVantage{}.CurrencyExists(to)
 	if err != nil {
 		return "", err
 	} else if !toBoolCurrency {
-		return "", errors.New("[currency.rate.buildURL] to currency is not valid")
+		return "", fmt.Errorf("[currency.rate.buildURL] %w: to currency %s", internal.ErrInvalidCurrency, to)
 	}
 
 	if from == to {
 		return "", errors.New("[currency.rate.buildURL] from and to currencies must be different")
 	}
 
-	switch format {
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong, flags.OutputFormatJSON, flags.OutputFormatCSV:
-		// Do nothing.
-	default:
-		return "", errors.New("[currency.rate.buildURL] invalid output format")
+	if _, ok := render.Lookup(format); !ok {
+		switch format {
+		case flags.OutputFormatTable, flags.OutputFormatTableLong, flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+			// Do nothing.
+		default:
+			return "", errors.New("[currency.rate.buildURL] invalid output format")
+		}
 	}
 
 	url := strings.Builder{}
@@ -203,22 +240,29 @@ func buildURL(from string, to string, format flags.OutputFormat, interval flags.
 	case flags.IntervalMonthly:
 		url.WriteString(apiFunctionCurrencyRateMonthly)
 	default:
-		return "", errors.New("[currency.rate.buildURL] invalid interval for adjusted prices")
+		if !interval.Intraday() {
+			return "", errors.New("[currency.rate.buildURL] invalid interval")
+		}
+		url.WriteString(apiFunctionCurrencyRateIntraday)
 	}
 
 	url.WriteString("&from_symbol=")
 	url.WriteString(from)
 	url.WriteString("&to_symbol=")
 	url.WriteString(to)
+	if interval.Intraday() {
+		url.WriteString("&interval=")
+		url.WriteString(string(interval))
+	}
 
 	// Print entire time series if daily, because user can then limit the interval with `begin` and `end`.
-	if interval == flags.IntervalDaily && full {
+	if (interval == flags.IntervalDaily || interval.Intraday()) && full {
 		url.WriteString("&outputsize=full")
 	}
 	url.WriteString("&apikey=")
 	url.WriteString(internal.ApiKey)
 
-	if format == flags.OutputFormatCSV {
+	if format == flags.OutputFormatRawCSV {
 		url.WriteString("&datatype=csv")
 	}
 
@@ -238,17 +282,22 @@ func createResponseObject(interval flags.Interval) (Response, error) {
 	case flags.IntervalMonthly:
 		obj = &Monthly{}
 	default:
-		return obj, errors.New("[currency.rate.createResponseObject] invalid interval")
+		if !interval.Intraday() {
+			return obj, errors.New("[currency.rate.createResponseObject] invalid interval")
+		}
+		obj = &Intraday{Interval: interval}
 	}
 
 	return obj, nil
 }
 
 // getDates returns the dates in the time series that are within the specified interval.
+// Intraday timestamps are compared by their day, so the rates of the whole `end` day are kept.
 func getDates(timeSeries map[time.Time]TypedPrices, begin time.Time, end time.Time) []time.Time {
 	var dates []time.Time
 	for date := range timeSeries {
-		if !(date.Before(begin) || date.After(end)) {
+		day := date.Truncate(24 * time.Hour)
+		if !(day.Before(begin) || day.After(end)) {
 			dates = append(dates, date)
 		}
 	}
@@ -261,64 +310,161 @@ func getDates(timeSeries map[time.Time]TypedPrices, begin time.Time, end time.Ti
 	return dates
 }
 
-// generateOutputHledger generates the output in hledger format for non-adjusted prices.
-func generateOutputHledger(timeSeries map[time.Time]TypedPrices, dates []time.Time, from string, to string) string {
-	out := strings.Builder{}
+// records converts the rates of the given dates into records. The rate of the day of the last refresh gets its time,
+// while intraday rates have their own.
+func records(series Series, dates []time.Time) []render.Record {
+	lastRefreshed := series.MetaData.LastRefreshed
+	if series.Intraday {
+		lastRefreshed = time.Time{}
+	}
+
+	records := make([]render.Record, 0, len(dates))
 	for _, date := range dates {
-		out.WriteString(fmt.Sprintf("P %s %s %.2f %s\n",
-			date.Format("2006-01-02"),
-			from,
-			timeSeries[date].Close,
-			to))
+		prices := series.TimeSeries[date]
+		records = append(records, render.Record{
+			Date:      render.Timestamp(date, lastRefreshed),
+			Commodity: series.MetaData.FromSymbol,
+			Amount:    prices.Close,
+			Currency:  series.MetaData.ToSymbol,
+			Open:      prices.Open,
+			High:      prices.High,
+			Low:       prices.Low,
+			Close:     prices.Close,
+			Source:    series.Source,
+			Fetched:   series.Fetched,
+			Given:     render.FieldsRange,
+		})
 	}
-	return out.String()
+	return records
 }
 
-func generateMetadataTable(from string, to string, lastRefreshed time.Time) string {
+// generateMetadataTable generates the table describing a series. Cross rates have an additional column with the pivot
+// currency they were computed through.
+func generateMetadataTable(from string, to string, pivot string, lastRefreshed time.Time) string {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
-	t.AppendHeader(table.Row{"From", "To", "Last Refreshed"})
-	t.AppendRow(table.Row{from, to, lastRefreshed.Format("2006-01-02 15:04:05")})
+	if pivot == "" {
+		t.AppendHeader(table.Row{"From", "To", "Last Refreshed"})
+		t.AppendRow(table.Row{from, to, lastRefreshed.Format("2006-01-02 15:04:05")})
+	} else {
+		t.AppendHeader(table.Row{"From", "To", "Via", "Last Refreshed"})
+		t.AppendRow(table.Row{from, to, pivot + " (cross rate)", lastRefreshed.Format("2006-01-02 15:04:05")})
+	}
 	return t.Render() + "\n"
 }
 
-func generateTimeSeriesTable(timeSeries map[time.Time]TypedPrices, dates []time.Time) string {
+func generateTimeSeriesTable(timeSeries map[time.Time]TypedPrices, dates []time.Time, from string, to string, layout string) string {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	t.AppendHeader(table.Row{"Date", "Open", "High", "Low", "Close"})
 	for _, date := range dates {
 		prices := timeSeries[date]
 		t.AppendRow([]interface{}{
-			date.Format("2006-01-02"),
-			fmt.Sprintf("%.2f", prices.Open),
-			fmt.Sprintf("%.2f", prices.High),
-			fmt.Sprintf("%.2f", prices.Low),
-			fmt.Sprintf("%.2f", prices.Close),
+			date.Format(layout),
+			internal.FormatPrice(prices.Open, from, to),
+			internal.FormatPrice(prices.High, from, to),
+			internal.FormatPrice(prices.Low, from, to),
+			internal.FormatPrice(prices.Close, from, to),
 		})
 	}
 	return t.Render() + "\n"
 }
 
-func Execute(from string, to string, format flags.OutputFormat, interval flags.Interval, begin string, end string, full bool) (string, error) {
-	beginTime, endTime, err := internal.ValidateDates(begin, end)
-	if err != nil {
-		return "", err
+// GenerateOutput renders a series in the desired format, keeping only the dates between `begin` and `end`.
+// The "raw-json" and "raw-csv" formats return the raw body given by the provider. The formats of the render package use
+// the names of the journal, and name the origin currency `as` when it is not empty.
+func GenerateOutput(series Series, body []byte, begin time.Time, end time.Time, as string, format flags.OutputFormat) (string, error) {
+	// The formats made of one directive per rate are written by the formatters of the render package.
+	if _, ok := render.Lookup(format); ok {
+		return render.Render(format, records(series, getDates(series.TimeSeries, begin, end)), as)
+	}
+
+	switch format {
+	case flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+		return string(body), nil
+	case flags.OutputFormatTable, flags.OutputFormatTableLong:
+		dates := getDates(series.TimeSeries, begin, end)
+		layout := "2006-01-02"
+		if series.Intraday {
+			layout = "2006-01-02 15:04"
+		}
+		out := strings.Builder{}
+		out.WriteString(generateMetadataTable(
+			series.MetaData.FromSymbol,
+			series.MetaData.ToSymbol,
+			series.Pivot,
+			series.MetaData.LastRefreshed))
+		out.WriteString(generateTimeSeriesTable(
+			series.TimeSeries,
+			dates,
+			series.MetaData.FromSymbol,
+			series.MetaData.ToSymbol,
+			layout))
+		return out.String(), nil
+	default:
+		return "", errors.New("[currency.rate.GenerateOutput] invalid output format")
 	}
+}
 
+// FXSeries fetches the exchange rates from the Alpha Vantage API and casts them into a Series.
+func (AlphaVantage) FXSeries(from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (Series, []byte, error) {
 	url, err := buildURL(from, to, format, interval, full)
 	if err != nil {
-		return "", err
+		return Series{}, nil, err
 	}
 
 	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		return Series{}, nil, fmt.Errorf("[currency.rate.(AlphaVantage).FXSeries] %w %s or %s: %w", internal.ErrInvalidCurrency, from, to, err)
+	}
 	if err != nil {
-		return "", err
+		return Series{}, nil, err
+	}
+
+	// The raw formats do not need the body to be parsed.
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return Series{}, body, nil
 	}
 
 	response, err := createResponseObject(interval)
+	if err != nil {
+		return Series{}, nil, err
+	}
+
+	series, err := response.ParseBody(body)
+	if err != nil {
+		return Series{}, nil, err
+	}
+	if len(series.TimeSeries) == 0 {
+		return Series{}, nil, fmt.Errorf("[currency.rate.(AlphaVantage).FXSeries] %w for %s/%s", internal.ErrEmptyTimeSeries, from, to)
+	}
+	series.Source = internal.SourceAlphaVantage
+	series.Fetched = time.Now()
+
+	return series, body, nil
+}
+
+// Execute is the core function of the rate package. It fetches the exchange rates between two currencies from the
+// given provider and returns them in the desired format. If `pivot` is not empty, the rates are cross rates computed
+// through the pivot currency. The origin currency is named `as` when it is not empty (see GenerateOutput).
+func Execute(provider Sources, from string, to string, pivot string, as string, format flags.OutputFormat, interval flags.Interval, begin string, end string, full bool) (string, error) {
+	beginTime, endTime, err := internal.ValidateDates(begin, end)
+	if err != nil {
+		return "", err
+	}
+
+	if pivot != "" {
+		series, err := crossSeries(provider, from, to, pivot, format, interval, full)
+		if err != nil {
+			return "", err
+		}
+		return GenerateOutput(series, nil, beginTime, endTime, as, format)
+	}
+
+	series, body, err := provider.FXSeries(from, to, format, interval, full)
 	if err != nil {
 		return "", err
 	}
 
-	return response.GenerateOutput(body, beginTime, endTime, format)
+	return GenerateOutput(series, body, beginTime, endTime, as, format)
 }
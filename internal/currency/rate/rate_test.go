@@ -19,77 +19,208 @@
 package rate
 
 import (
+	"os"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/lentidas/hledger-price-tracker/internal"
+	cryptoList "github.com/lentidas/hledger-price-tracker/internal/crypto/list"
+	cryptoRate "github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+// sources combines the Alpha Vantage implementations of the FX series, of the lists of currencies and of the digital
+// currency series.
+type sources struct {
+	AlphaVantage
+}
+
+func (sources) Currencies() (currencyList.Currencies, []byte, error) {
+	return currencyList.AlphaVantage{}.Currencies()
+}
+
+func (sources) CurrencyExists(currencyCode string) (bool, error) {
+	return currencyList.AlphaVantage{}.CurrencyExists(currencyCode)
+}
+
+func (sources) CryptoExists(cryptoCode string) (bool, error) {
+	return cryptoList.AlphaVantage{}.CryptoExists(cryptoCode)
+}
+
+func (sources) CryptoSeries(from string, to string, interval flags.Interval) (cryptoRate.Series, error) {
+	return cryptoRate.AlphaVantage{}.CryptoSeries(from, to, interval)
+}
+
 func TestRate(t *testing.T) {
 	internal.ApiKey = "demo"
-	sleepTime := time.Duration(500) * time.Millisecond
 
 	t.Run("success from EUR to USD daily", func(t *testing.T) {
-		if _, err := Execute("EUR", "USD", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err != nil {
+		if _, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err != nil {
 			t.Errorf("expected nil, got %v", err)
 		}
-		time.Sleep(sleepTime)
 	})
 
 	t.Run("success from EUR to USD daily full", func(t *testing.T) {
-		if _, err := Execute("EUR", "USD", flags.OutputFormatHledger, flags.IntervalDaily, "", "", true); err != nil {
+		if _, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", true); err != nil {
 			t.Errorf("expected nil, got %v", err)
 		}
-		time.Sleep(sleepTime)
 	})
 
 	t.Run("success from EUR to USD weekly", func(t *testing.T) {
-		if _, err := Execute("EUR", "USD", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false); err != nil {
+		if _, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false); err != nil {
 			t.Errorf("expected nil, got %v", err)
 		}
-		time.Sleep(sleepTime)
 	})
 
 	t.Run("success from EUR to USD monthly", func(t *testing.T) {
-		if _, err := Execute("EUR", "USD", flags.OutputFormatHledger, flags.IntervalMonthly, "", "", false); err != nil {
+		if _, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.IntervalMonthly, "", "", false); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("success from EUR to USD intraday", func(t *testing.T) {
+		// Only the last rate of each day is kept.
+		expected := "P 2025-04-03 EUR 1.1045 USD\nP 2025-04-04 EUR 1.0956 USD\n"
+		output, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.Interval60Min, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success from EUR to USD intraday beancount", func(t *testing.T) {
+		expected := "2025-04-03 price EUR 1.1045 USD\n2025-04-04 price EUR 1.0956 USD\n"
+		output, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatBeancount, flags.Interval60Min, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success from EUR to USD intraday ledger", func(t *testing.T) {
+		// Ledger keeps every intraday rate with its time.
+		expected := "P 2025/04/03 21:00:00 EUR 1.1045 USD\nP 2025/04/04 12:00:00 EUR 1.1012 USD\nP 2025/04/04 21:00:00 EUR 1.0956 USD\n"
+		output, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatLedger, flags.Interval60Min, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.HasSuffix(output, expected) {
+			t.Errorf("expected %q at the end, got %q", expected, output)
+		}
+	})
+
+	t.Run("success from EUR to USD daily ledger", func(t *testing.T) {
+		// The rate of the last day has the time of the last refresh.
+		expected := "P 2025/04/03 EUR 1.0951 USD\nP 2025/04/04 16:00:00 EUR 1.0974 USD\n"
+		output, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatLedger, flags.IntervalDaily, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.HasSuffix(output, expected) {
+			t.Errorf("expected %q at the end, got %q", expected, output)
+		}
+	})
+
+	t.Run("success with the precision of the currency", func(t *testing.T) {
+		internal.Precisions = map[string]int{"usd": 2}
+		defer func() { internal.Precisions = nil }()
+		expected := "P 2025-04-03 EUR 1.10 USD\nP 2025-04-04 EUR 1.10 USD\n"
+		output, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.Interval60Min, "", "", false)
+		if err != nil {
 			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success from EUR to USD intraday table", func(t *testing.T) {
+		output, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatTable, flags.Interval60Min, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.Contains(output, "2025-04-04 12:00") {
+			t.Errorf("expected timestamps in the table, got %s", output)
+		}
+	})
+
+	t.Run("success cross rate from BTC to USD", func(t *testing.T) {
+		// The weekly digital currency series ends on Sundays, whereas the FX one ends on Fridays.
+		expected := "P 2025-03-09 BTC 79578.183414 USD\nP 2025-03-16 BTC 80673.848685 USD\n"
+		output, err := Execute(sources{}, "BTC", "USD", "EUR", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "2025-03-16", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success cross rate from XAF to USD table", func(t *testing.T) {
+		output, err := Execute(sources{}, "XAF", "USD", "EUR", "", flags.OutputFormatTable, flags.IntervalDaily, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.Contains(output, "EUR (cross rate)") || strings.Count(output, "2025-04-0") != 4 {
+			t.Errorf("expected 3 cross rates through EUR, got %s", output)
+		}
+	})
+
+	t.Run("success cross rate to a digital currency", func(t *testing.T) {
+		output, err := Execute(sources{}, "USD", "BTC", "EUR", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.HasPrefix(output, "P 2025-03-21 USD 0.00001222917182009293 BTC\n") || strings.Count(output, "\n") != 3 {
+			t.Errorf("expected 3 cross rates from USD to BTC, got %q", output)
+		}
+	})
+
+	t.Run("cross rate with raw JSON output format", func(t *testing.T) {
+		if _, err := Execute(sources{}, "XAF", "USD", "EUR", "", flags.OutputFormatRawJSON, flags.IntervalDaily, "", "", false); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("cross rate through one of the currencies", func(t *testing.T) {
+		if _, err := Execute(sources{}, "EUR", "USD", "USD", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
+			t.Error("expected error, got nil")
 		}
-		time.Sleep(sleepTime)
 	})
 
 	t.Run("no origin currency", func(t *testing.T) {
-		if _, err := Execute("", "USD", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
+		if _, err := Execute(sources{}, "", "USD", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("no destination currency", func(t *testing.T) {
-		if _, err := Execute("EUR", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
+		if _, err := Execute(sources{}, "EUR", "", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid origin currency", func(t *testing.T) {
-		if _, err := Execute("INVALID", "USD", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
+		if _, err := Execute(sources{}, "INVALID", "USD", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid destination currency", func(t *testing.T) {
-		if _, err := Execute("EUR", "INVALID", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
+		if _, err := Execute(sources{}, "EUR", "INVALID", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid output format", func(t *testing.T) {
-		if _, err := Execute("EUR", "USD", "invalid", flags.IntervalDaily, "", "", false); err == nil {
+		if _, err := Execute(sources{}, "EUR", "USD", "", "", "invalid", flags.IntervalDaily, "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid interval", func(t *testing.T) {
-		if _, err := Execute("EUR", "USD", flags.OutputFormatHledger, "invalid", "", "", false); err == nil {
+		if _, err := Execute(sources{}, "EUR", "USD", "", "", flags.OutputFormatHledger, "invalid", "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -97,7 +228,7 @@ func TestRate(t *testing.T) {
 	internal.ApiKey = ""
 
 	t.Run("no API key", func(t *testing.T) {
-		if _, err := Execute("USD", "JPY", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
+		if _, err := Execute(sources{}, "USD", "JPY", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "", "", false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -107,7 +238,7 @@ func TestRateURLBuilder(t *testing.T) {
 	internal.ApiKey = "demo"
 
 	t.Run("daily", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_DAILY&from_symbol=EUR&to_symbol=USD&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=FX_DAILY&from_symbol=EUR&to_symbol=USD&apikey=demo"
 
 		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.IntervalDaily, false)
 		if err != nil {
@@ -118,7 +249,7 @@ func TestRateURLBuilder(t *testing.T) {
 	})
 
 	t.Run("daily full", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_DAILY&from_symbol=EUR&to_symbol=USD&outputsize=full&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=FX_DAILY&from_symbol=EUR&to_symbol=USD&outputsize=full&apikey=demo"
 
 		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.IntervalDaily, true)
 		if err != nil {
@@ -129,7 +260,7 @@ func TestRateURLBuilder(t *testing.T) {
 	})
 
 	t.Run("weekly", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_WEEKLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=FX_WEEKLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
 
 		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.IntervalWeekly, false)
 		if err != nil {
@@ -140,7 +271,7 @@ func TestRateURLBuilder(t *testing.T) {
 	})
 
 	t.Run("weekly ignore full", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_WEEKLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=FX_WEEKLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
 
 		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.IntervalWeekly, true)
 		if err != nil {
@@ -151,7 +282,7 @@ func TestRateURLBuilder(t *testing.T) {
 	})
 
 	t.Run("monthly", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_MONTHLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=FX_MONTHLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
 
 		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.IntervalMonthly, false)
 		if err != nil {
@@ -162,7 +293,7 @@ func TestRateURLBuilder(t *testing.T) {
 	})
 
 	t.Run("monthly ignore full", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_MONTHLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=FX_MONTHLY&from_symbol=EUR&to_symbol=USD&apikey=demo"
 
 		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.IntervalMonthly, true)
 		if err != nil {
@@ -172,10 +303,21 @@ func TestRateURLBuilder(t *testing.T) {
 		}
 	})
 
-	t.Run("CSV", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=FX_DAILY&from_symbol=EUR&to_symbol=USD&outputsize=full&apikey=demo&datatype=csv"
+	t.Run("intraday", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=FX_INTRADAY&from_symbol=EUR&to_symbol=USD&interval=15min&apikey=demo"
+
+		url, err := buildURL("EUR", "USD", flags.OutputFormatHledger, flags.Interval15Min, false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("raw CSV", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=FX_DAILY&from_symbol=EUR&to_symbol=USD&outputsize=full&apikey=demo&datatype=csv"
 
-		url, err := buildURL("EUR", "USD", flags.OutputFormatCSV, flags.IntervalDaily, true)
+		url, err := buildURL("EUR", "USD", flags.OutputFormatRawCSV, flags.IntervalDaily, true)
 		if err != nil {
 			t.Errorf("expected nil, got %v", err)
 		} else if url != expected {
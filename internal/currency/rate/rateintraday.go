@@ -0,0 +1,154 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+)
+
+const apiFunctionCurrencyRateIntraday = "FX_INTRADAY"
+
+type RawMetadataIntraday struct {
+	Information   string `json:"1. Information"`
+	FromSymbol    string `json:"2. From Symbol"`
+	ToSymbol      string `json:"3. To Symbol"`
+	LastRefreshed string `json:"4. Last Refreshed"`
+	Interval      string `json:"5. Interval"`
+	OutputSize    string `json:"6. Output Size"`
+	TimeZone      string `json:"7. Time Zone"`
+}
+
+type TypedMetadataIntraday struct {
+	Information   string
+	FromSymbol    string
+	ToSymbol      string
+	LastRefreshed time.Time
+	Interval      string
+	OutputSize    string
+	TimeZone      string
+}
+
+func (typed *TypedMetadataIntraday) TypeBody(raw RawMetadataIntraday) error {
+	lastRefreshed, err := time.Parse("2006-01-02 15:04:05", raw.LastRefreshed)
+	if err != nil {
+		return fmt.Errorf("[currency.rate.(*TypedMetadataIntraday).TypeBody] error parsing last refreshed time: %w", err)
+	}
+
+	typed.Information = raw.Information
+	typed.FromSymbol = raw.FromSymbol
+	typed.ToSymbol = raw.ToSymbol
+	typed.LastRefreshed = lastRefreshed
+	typed.Interval = raw.Interval
+	typed.OutputSize = raw.OutputSize
+	typed.TimeZone = raw.TimeZone
+
+	return nil
+}
+
+// toTypedMetadata drops the fields specific to the intraday metadata, so it can be used in a Series.
+func (typed *TypedMetadataIntraday) toTypedMetadata() TypedMetadata {
+	return TypedMetadata{
+		Information:   typed.Information,
+		FromSymbol:    typed.FromSymbol,
+		ToSymbol:      typed.ToSymbol,
+		LastRefreshed: typed.LastRefreshed,
+		TimeZone:      typed.TimeZone,
+	}
+}
+
+// RawIntraday does not map the time series with a struct tag, because its key depends on the interval
+// (e.g. "Time Series FX (5min)").
+type RawIntraday struct {
+	MetaData   RawMetadataIntraday  `json:"Meta Data"`
+	TimeSeries map[string]RawPrices `json:"-"`
+}
+
+type TypedIntraday struct {
+	MetaData   TypedMetadataIntraday
+	TimeSeries map[time.Time]TypedPrices
+}
+
+type Intraday struct {
+	Interval flags.Interval
+	Raw      RawIntraday
+	Typed    TypedIntraday
+}
+
+func (obj *Intraday) TypeBody() error {
+	err := obj.Typed.MetaData.TypeBody(obj.Raw.MetaData)
+	if err != nil {
+		return fmt.Errorf("[(*Intraday).TypeBody] failure to cast metadata body: %w", err)
+	}
+
+	obj.Typed.TimeSeries = make(map[time.Time]TypedPrices, len(obj.Raw.TimeSeries))
+
+	for timestamp, prices := range obj.Raw.TimeSeries {
+		timestampTyped, err := time.Parse("2006-01-02 15:04:05", timestamp)
+		if err != nil {
+			return fmt.Errorf("[(*Intraday).TypeBody] error parsing timestamp: %w", err)
+		}
+
+		var pricesTyped TypedPrices
+		err = pricesTyped.TypeBody(prices)
+		if err != nil {
+			return fmt.Errorf("[(*Intraday).TypeBody] error casting prices body: %w", err)
+		}
+
+		obj.Typed.TimeSeries[timestampTyped] = pricesTyped
+	}
+
+	return nil
+}
+
+func (obj *Intraday) ParseBody(body []byte) (Series, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Intraday).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Get the time series from the key matching the interval.
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Intraday).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+	if timeSeries, ok := fields[fmt.Sprintf("Time Series FX (%s)", obj.Interval)]; ok {
+		err = json.Unmarshal(timeSeries, &obj.Raw.TimeSeries)
+		if err != nil {
+			return Series{}, fmt.Errorf("[(*Intraday).ParseBody] failure to unmarshal time series: %w", err)
+		}
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Intraday).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return Series{
+		MetaData:   obj.Typed.MetaData.toTypedMetadata(),
+		Intraday:   true,
+		TimeSeries: obj.Typed.TimeSeries,
+	}, nil
+}
@@ -20,12 +20,8 @@ package rate
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"strings"
 	"time"
-
-	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
 const apiFunctionCurrencyRateWeekly = "FX_WEEKLY"
@@ -71,44 +67,21 @@ func (obj *Weekly) TypeBody() error {
 	return nil
 }
 
-func (obj *Weekly) GenerateOutput(body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
-	switch format {
-	case flags.OutputFormatJSON, flags.OutputFormatCSV:
-		return string(body), nil
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong:
-		// Parse the JSON body into the Raw struct.
-		err := json.Unmarshal(body, &obj.Raw)
-		if err != nil {
-			return "", fmt.Errorf("[(*Weekly).GenerateOutput] failure to unmarshal JSON body: %w", err)
-		}
-
-		// Cast the attributes into proper types.
-		err = obj.TypeBody()
-		if err != nil {
-			return "", fmt.Errorf("[(*Weekly).GenerateOutput] error casting response attributes: %w", err)
-		}
-
-		dates := getDates(obj.Typed.TimeSeries, begin, end)
+func (obj *Weekly) ParseBody(body []byte) (Series, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Weekly).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
 
-		if format == flags.OutputFormatHledger {
-			return generateOutputHledger(
-					obj.Typed.TimeSeries,
-					dates,
-					obj.Typed.MetaData.FromSymbol,
-					obj.Typed.MetaData.ToSymbol),
-				nil
-		} else {
-			out := strings.Builder{}
-			out.WriteString(generateMetadataTable(
-				obj.Typed.MetaData.FromSymbol,
-				obj.Typed.MetaData.ToSymbol,
-				obj.Typed.MetaData.LastRefreshed))
-			out.WriteString(generateTimeSeriesTable(
-				obj.Typed.TimeSeries,
-				dates))
-			return out.String(), nil
-		}
-	default:
-		return "", errors.New("[(*Weekly).GenerateOutput] invalid output format")
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Weekly).ParseBody] error casting response attributes: %w", err)
 	}
+
+	return Series{
+		MetaData:   obj.Typed.MetaData,
+		TimeSeries: obj.Typed.TimeSeries,
+	}, nil
 }
@@ -0,0 +1,258 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package decimal implements the exact decimal numbers used for the prices, so that the digits given by the APIs are
+// kept as they are instead of being rounded by a float64 (e.g. the price of a low-priced crypto or a JPY rate).
+package decimal
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DivisionScale is the number of decimals kept by Div when the quotient cannot be represented exactly.
+const DivisionScale = 16
+
+// Decimal is an exact decimal number, i.e. an integer coefficient divided by a power of ten given by its scale.
+// A parsed decimal keeps the scale of its string (e.g. "1.09740" has a scale of 5), and the zero value is zero.
+// Decimals are immutable: every operation returns a new one.
+type Decimal struct {
+	coefficient *big.Int
+	scale       int32
+}
+
+var ten = big.NewInt(10)
+
+// New returns the decimal value * 10^-scale.
+func New(value int64, scale int32) Decimal {
+	return Decimal{coefficient: big.NewInt(value), scale: scale}.normalize()
+}
+
+// Parse parses a decimal number such as "-12.3400" or "1.5e-3", keeping all its digits.
+func Parse(s string) (Decimal, error) {
+	number := strings.TrimSpace(s)
+
+	var exponent int64
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		var err error
+		exponent, err = strconv.ParseInt(number[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("[decimal.Parse] invalid exponent in %q", s)
+		}
+		number = number[:i]
+	}
+
+	sign := ""
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "+") {
+		sign, number = number[:1], number[1:]
+	}
+	integer, fraction, _ := strings.Cut(number, ".")
+	digits := integer + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("[decimal.Parse] invalid decimal number %q", s)
+	}
+
+	coefficient, ok := new(big.Int).SetString(sign+digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("[decimal.Parse] invalid decimal number %q", s)
+	}
+	return Decimal{coefficient: coefficient, scale: int32(int64(len(fraction)) - exponent)}.normalize(), nil
+}
+
+// MustParse is like Parse but panics if the string is not a decimal number. It is meant for constants.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecodeHook decodes the decimals of the configuration file (see viper.DecodeHook), which are given either as numbers
+// (e.g. `withholding-tax: 15`) or as strings (e.g. `withholding-tax: "12.5"`).
+func DecodeHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeFor[Decimal]() {
+		return data, nil
+	}
+	switch from.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return Parse(fmt.Sprint(data))
+	}
+	return data, nil
+}
+
+// normalize makes the coefficient non-nil and the scale non-negative.
+func (d Decimal) normalize() Decimal {
+	if d.coefficient == nil {
+		d.coefficient = new(big.Int)
+	}
+	if d.scale < 0 {
+		d.coefficient = new(big.Int).Mul(d.coefficient, pow10(-d.scale))
+		d.scale = 0
+	}
+	return d
+}
+
+// int returns the coefficient, which is nil for the zero value.
+func (d Decimal) int() *big.Int {
+	if d.coefficient == nil {
+		return new(big.Int)
+	}
+	return d.coefficient
+}
+
+// pow10 returns 10^n.
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
+
+// rescale returns the decimal with a scale at least as large as its own, without changing its value.
+func (d Decimal) rescale(scale int32) Decimal {
+	if scale <= d.scale {
+		return d
+	}
+	return Decimal{coefficient: new(big.Int).Mul(d.int(), pow10(scale-d.scale)), scale: scale}
+}
+
+// quotient divides n by m, rounding half away from zero.
+func quotient(n *big.Int, m *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, m, new(big.Int))
+	twice := new(big.Int).Lsh(new(big.Int).Abs(r), 1)
+	if twice.Cmp(new(big.Int).Abs(m)) >= 0 {
+		if n.Sign()*m.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// Add returns d + other.
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{coefficient: new(big.Int).Add(d.rescale(scale).int(), other.rescale(scale).int()), scale: scale}
+}
+
+// Sub returns d - other.
+func (d Decimal) Sub(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{coefficient: new(big.Int).Sub(d.rescale(scale).int(), other.rescale(scale).int()), scale: scale}
+}
+
+// Mul returns d * other. The product is exact, but its trailing zeros are dropped down to the largest scale of the
+// operands (e.g. 1.50 * 2.0 gives 3.00).
+func (d Decimal) Mul(other Decimal) Decimal {
+	product := Decimal{coefficient: new(big.Int).Mul(d.int(), other.int()), scale: d.scale + other.scale}
+	return product.Trim(max(d.scale, other.scale))
+}
+
+// Div returns d / other, rounded half away from zero to DivisionScale decimals (or more if an operand has more),
+// with its trailing zeros dropped down to the largest scale of the operands. It panics if other is zero.
+func (d Decimal) Div(other Decimal) Decimal {
+	if other.IsZero() {
+		panic("[decimal.Div] division by zero")
+	}
+	scale := max(DivisionScale, d.scale, other.scale)
+	numerator := new(big.Int).Mul(d.int(), pow10(scale-d.scale+other.scale))
+	q := Decimal{coefficient: quotient(numerator, other.int()), scale: scale}
+	return q.Trim(max(d.scale, other.scale))
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{coefficient: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Round returns d rounded half away from zero to the given number of decimals. The result always has exactly this
+// number of decimals, so it is padded with zeros if needed (e.g. 1.5 rounded to 2 decimals gives 1.50).
+func (d Decimal) Round(places int32) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if places >= d.scale {
+		return d.rescale(places).normalize()
+	}
+	return Decimal{coefficient: quotient(d.int(), pow10(d.scale-places)), scale: places}
+}
+
+// Trim drops the trailing zeros of the decimals of d, while keeping at least the given number of decimals (padding
+// with zeros if needed). The value is unchanged (e.g. 227.4800 trimmed to 2 decimals gives 227.48, and 3 gives 3.00).
+func (d Decimal) Trim(places int32) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	d = d.rescale(places).normalize()
+	coefficient := new(big.Int).Set(d.coefficient)
+	scale := d.scale
+	remainder := new(big.Int)
+	for scale > places {
+		q, r := new(big.Int).QuoRem(coefficient, ten, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		coefficient = q
+		scale--
+	}
+	return Decimal{coefficient: coefficient, scale: scale}
+}
+
+// Cmp compares d and other, and returns -1, 0 or +1 if d is respectively lower than, equal to or greater than other.
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).int().Cmp(other.rescale(scale).int())
+}
+
+// Equal reports whether d and other have the same value, whatever their scales.
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsZero reports whether d is zero.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Scale returns the number of decimals of d.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// String returns d with all its decimals, e.g. "1.09740" for a decimal parsed from this string.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(digits) <= int(d.scale) {
+			digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
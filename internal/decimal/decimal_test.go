@@ -0,0 +1,172 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package decimal
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("valid decimal numbers", func(t *testing.T) {
+		tests := map[string]string{
+			"227.4800":   "227.4800",
+			"0.00001234": "0.00001234",
+			"-1.5":       "-1.5",
+			"+42":        "42",
+			".5":         "0.5",
+			"1.5e-3":     "0.0015",
+			"12E2":       "1200",
+		}
+		for input, expected := range tests {
+			d, err := Parse(input)
+			if err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+			if d.String() != expected {
+				t.Errorf("expected %s, got %s", expected, d.String())
+			}
+		}
+	})
+
+	t.Run("invalid decimal numbers", func(t *testing.T) {
+		for _, input := range []string{"", "-", "abc", "1.2.3", "1e", "12,5", "NaN"} {
+			if _, err := Parse(input); err == nil {
+				t.Errorf("expected error for %q, got nil", input)
+			}
+		}
+	})
+}
+
+func TestArithmetic(t *testing.T) {
+	t.Run("exact operations", func(t *testing.T) {
+		tests := []struct {
+			result   Decimal
+			expected string
+		}{
+			{MustParse("0.1").Add(MustParse("0.2")), "0.3"},
+			{MustParse("1.00").Sub(MustParse("0.015")), "0.985"},
+			{MustParse("227.4800").Mul(MustParse("0.91120")), "207.279776"},
+			{MustParse("1.50").Mul(MustParse("2.0")), "3.00"},
+			{MustParse("362.5000").Div(New(100, 0)), "3.6250"},
+			{MustParse("1").Div(MustParse("3")), "0.3333333333333333"},
+			{MustParse("2").Div(MustParse("3")), "0.6666666666666667"},
+			{MustParse("-2").Div(MustParse("3")), "-0.6666666666666667"},
+		}
+		for _, test := range tests {
+			if test.result.String() != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, test.result.String())
+			}
+		}
+	})
+
+	t.Run("division by zero", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic, got nil")
+			}
+		}()
+		MustParse("1").Div(Decimal{})
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var zero Decimal
+		if !zero.IsZero() || zero.String() != "0" {
+			t.Errorf("expected 0, got %s", zero.String())
+		}
+		if sum := zero.Add(MustParse("1.5")); sum.String() != "1.5" {
+			t.Errorf("expected 1.5, got %s", sum.String())
+		}
+	})
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		places   int32
+		expected string
+	}{
+		{"0.00001234", 2, "0.00"},
+		{"0.00001234", 8, "0.00001234"},
+		{"1.005", 2, "1.01"},
+		{"-1.005", 2, "-1.01"},
+		{"1.004", 2, "1.00"},
+		{"1.5", 2, "1.50"},
+		{"149.5", 0, "150"},
+	}
+	for _, test := range tests {
+		if result := MustParse(test.input).Round(test.places).String(); result != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, result)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		input    string
+		places   int32
+		expected string
+	}{
+		{"227.4800", 2, "227.48"},
+		{"1.09740", 2, "1.0974"},
+		{"3", 2, "3.00"},
+		{"0.00001234", 2, "0.00001234"},
+		{"100", 0, "100"},
+	}
+	for _, test := range tests {
+		if result := MustParse(test.input).Trim(test.places).String(); result != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, result)
+		}
+	}
+}
+
+func TestCmp(t *testing.T) {
+	if !MustParse("1.50").Equal(MustParse("1.5")) {
+		t.Error("expected 1.50 to equal 1.5")
+	}
+	if MustParse("-2").Cmp(MustParse("1")) != -1 {
+		t.Error("expected -2 to be lower than 1")
+	}
+}
+
+func TestDecodeHook(t *testing.T) {
+	to := reflect.TypeFor[Decimal]()
+	for _, data := range []any{"12.5", 12.5, 15, "15"} {
+		result, err := DecodeHook(reflect.TypeOf(data), to, data)
+		if err != nil {
+			t.Errorf("expected nil for %v, got %v", data, err)
+		} else if expected := fmt.Sprint(data); result.(Decimal).String() != expected {
+			t.Errorf("expected %s, got %v", expected, result)
+		}
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := DecodeHook(reflect.TypeFor[string](), to, "15%"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("other types", func(t *testing.T) {
+		result, err := DecodeHook(reflect.TypeFor[int](), reflect.TypeFor[int](), 15)
+		if err != nil || result != 15 {
+			t.Errorf("expected 15, got %v (%v)", result, err)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Errors returned when a request fails for a known reason. They can be checked with errors.Is, and each one has its
+// own exit code so that scripts can decide whether to retry later or to alert.
+var (
+	ErrRateLimited       = errors.New("rate limit exceeded")
+	ErrQuotaExhausted    = errors.New("daily quota exhausted")
+	ErrPremiumOnly       = errors.New("premium feature")
+	ErrInvalidAPIKey     = errors.New("invalid API key")
+	ErrUnknownSymbol     = errors.New("unknown symbol")
+	ErrInvalidCurrency   = errors.New("invalid currency")
+	ErrEmptyTimeSeries   = errors.New("empty time series")
+	ErrInvalidAPICall    = errors.New("invalid API call")
+	ErrUnknownAPIFailure = errors.New("unknown API failure")
+)
+
+// Exit codes of the program. Any error that does not have its own exit code exits with ExitFailure.
+const (
+	ExitSuccess = iota
+	ExitFailure
+	ExitRateLimited
+	ExitQuotaExhausted
+	ExitPremiumOnly
+	ExitInvalidAPIKey
+	ExitUnknownSymbol
+	ExitInvalidCurrency
+	ExitEmptyTimeSeries
+)
+
+// APIError is an error envelope returned by the Alpha Vantage API. Err is the sentinel error describing the failure
+// and Message is the message sent by the API.
+type APIError struct {
+	Err     error
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("[internal.HTTPRequest] Alpha Vantage API error (%s): %s", e.Err, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// classifyAPIError finds the sentinel error matching the message of an Alpha Vantage error envelope. The API does not
+// return error codes, so the only way to know what happened is to look at the message.
+func classifyAPIError(message string) error {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "apikey") && (strings.Contains(lower, "invalid") || strings.Contains(lower, "missing")),
+		strings.Contains(lower, "api key") && strings.Contains(lower, "invalid"):
+		return ErrInvalidAPIKey
+	case strings.Contains(lower, "per minute"), strings.Contains(lower, "per second"), strings.Contains(lower, "sparingly"):
+		return ErrRateLimited
+	case strings.Contains(lower, "per day"):
+		return ErrQuotaExhausted
+	case strings.Contains(lower, "premium"):
+		return ErrPremiumOnly
+	case strings.Contains(lower, "invalid api call"):
+		return ErrInvalidAPICall
+	default:
+		return ErrUnknownAPIFailure
+	}
+}
+
+// ExitCode returns the exit code of the program for an error.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitSuccess
+	case errors.Is(err, ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, ErrQuotaExhausted):
+		return ExitQuotaExhausted
+	case errors.Is(err, ErrPremiumOnly):
+		return ExitPremiumOnly
+	case errors.Is(err, ErrInvalidAPIKey):
+		return ExitInvalidAPIKey
+	case errors.Is(err, ErrUnknownSymbol):
+		return ExitUnknownSymbol
+	case errors.Is(err, ErrInvalidCurrency):
+		return ExitInvalidCurrency
+	case errors.Is(err, ErrEmptyTimeSeries):
+		return ExitEmptyTimeSeries
+	default:
+		return ExitFailure
+	}
+}
+
+// CheckErr prints the error and exits with its exit code if the error is not nil. It replaces cobra.CheckErr, which
+// always exits with 1.
+func CheckErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(ExitCode(err))
+	}
+}
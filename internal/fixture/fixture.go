@@ -0,0 +1,103 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lentidas/hledger-price-tracker/internal/cache"
+)
+
+// ErrNotFound is returned when replaying a request that was never recorded.
+var ErrNotFound = errors.New("fixture not found")
+
+// Name returns the file name of the fixture of a URL. It only depends on the path and the query of the URL, without
+// the API key, so the same fixtures can be served from any host and shared without leaking the key.
+// Every character that is not allowed in file names is replaced by an underscore.
+func Name(rawURL string) string {
+	parsed, err := url.Parse(cache.StripURL(rawURL))
+	if err != nil {
+		parsed = &url.URL{Path: rawURL}
+	}
+
+	// Keep the extension of files (e.g. the ECB archives), otherwise guess it from the requested data type.
+	ext := path.Ext(parsed.Path)
+	base := strings.TrimSuffix(parsed.Path, ext)
+	if ext == "" {
+		ext = ".json"
+		if parsed.Query().Get("datatype") == "csv" || strings.HasSuffix(parsed.Path, "_list/") {
+			ext = ".csv"
+		}
+	}
+	if parsed.RawQuery != "" {
+		base += "?" + parsed.RawQuery
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, strings.Trim(base, "/"))
+	return name + ext
+}
+
+// Load returns the recorded response of a URL.
+func Load(dir string, rawURL string) ([]byte, error) {
+	body, err := os.ReadFile(filepath.Join(dir, Name(rawURL)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("[fixture.Load] %w for %s in %s", ErrNotFound, cache.StripURL(rawURL), dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[fixture.Load] failed to read fixture: %w", err)
+	}
+	return body, nil
+}
+
+// Save records the response of a URL in the directory, creating it if needed.
+func Save(dir string, rawURL string, body []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("[fixture.Save] failed to create fixtures directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, Name(rawURL)), body, 0o644); err != nil {
+		return fmt.Errorf("[fixture.Save] failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// NewServer starts an HTTP server that stands in for the APIs, answering every request with its recorded response
+// in the directory, or with a 404 if there is none. It must be closed by the caller.
+func NewServer(dir string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := Load(dir, r.URL.RequestURI())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+}
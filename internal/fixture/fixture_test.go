@@ -0,0 +1,90 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fixture
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"https://www.alphavantage.co/query?function=FX_DAILY&from_symbol=EUR&to_symbol=USD&apikey=secret": "query_from_symbol_EUR_function_FX_DAILY_to_symbol_USD.json",
+		"http://127.0.0.1:8080/query?function=FX_DAILY&from_symbol=EUR&to_symbol=USD":                     "query_from_symbol_EUR_function_FX_DAILY_to_symbol_USD.json",
+		"/query?function=SYMBOL_SEARCH&keywords=tesco&datatype=csv":                                       "query_datatype_csv_function_SYMBOL_SEARCH_keywords_tesco.csv",
+		"https://www.alphavantage.co/physical_currency_list/":                                             "physical_currency_list.csv",
+		"https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.zip":                                    "stats_eurofxref_eurofxref-hist.zip",
+	}
+
+	for url, expected := range tests {
+		if result := Name(url); result != expected {
+			t.Errorf("expected %s for %s, got %s", expected, url, result)
+		}
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	url := "https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco&apikey=secret"
+
+	t.Run("not recorded", func(t *testing.T) {
+		if _, err := Load(dir, url); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected %v, got %v", ErrNotFound, err)
+		}
+	})
+
+	t.Run("recorded", func(t *testing.T) {
+		if err := Save(dir, url, []byte(`{"bestMatches": []}`)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		body, err := Load(dir, url)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if string(body) != `{"bestMatches": []}` {
+			t.Errorf("expected the recorded body, got %s", body)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		server := NewServer(dir)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/query?function=SYMBOL_SEARCH&keywords=tesco&apikey=other")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `{"bestMatches": []}` {
+			t.Errorf("expected the recorded body, got %d %s", resp.StatusCode, body)
+		}
+
+		resp, err = http.Get(server.URL + "/query?function=GLOBAL_QUOTE&symbol=IBM")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
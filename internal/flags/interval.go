@@ -30,6 +30,13 @@ const (
 	IntervalDaily   Interval = "daily"
 	IntervalWeekly  Interval = "weekly"
 	IntervalMonthly Interval = "monthly"
+
+	// Intraday intervals, only available for stocks and physical currencies.
+	Interval1Min  Interval = "1min"
+	Interval5Min  Interval = "5min"
+	Interval15Min Interval = "15min"
+	Interval30Min Interval = "30min"
+	Interval60Min Interval = "60min"
 )
 
 // String returns the string representation of the Interval type.
@@ -42,11 +49,22 @@ func (i *Interval) String() string {
 // Must have a pointer receiver so it doesn't actually change the value of a copy and not the value itself.
 func (i *Interval) Set(value string) error {
 	switch value {
-	case "daily", "weekly", "monthly":
+	case "daily", "weekly", "monthly", "1min", "5min", "15min", "30min", "60min":
 		*i = Interval(value)
 		return nil
 	default:
-		return errors.New("possible values are \"daily\", \"weekly\", \"monthly\"")
+		return errors.New("possible values are \"daily\", \"weekly\", \"monthly\", \"1min\", \"5min\", \"15min\", \"30min\", \"60min\"")
+	}
+}
+
+// Intraday returns true if the interval is shorter than a day, in which case each point of a time series has a
+// timestamp instead of a date.
+func (i Interval) Intraday() bool {
+	switch i {
+	case Interval1Min, Interval5Min, Interval15Min, Interval30Min, Interval60Min:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -61,5 +79,10 @@ func IntervalCompletion(cmd *cobra.Command, args []string, toComplete string) ([
 		"daily\tdaily stock prices",
 		"weekly\tweekly stock prices",
 		"monthly\tmonthly stock prices",
+		"1min\tintraday prices every minute",
+		"5min\tintraday prices every 5 minutes",
+		"15min\tintraday prices every 15 minutes",
+		"30min\tintraday prices every 30 minutes",
+		"60min\tintraday prices every hour",
 	}, cobra.ShellCompDirectiveDefault
 }
@@ -31,8 +31,14 @@ type OutputFormat string
 
 const (
 	OutputFormatHledger   OutputFormat = "hledger"
+	OutputFormatBeancount OutputFormat = "beancount"
+	OutputFormatLedger    OutputFormat = "ledger"
+	OutputFormatTemplate  OutputFormat = "template"
 	OutputFormatJSON      OutputFormat = "json"
 	OutputFormatCSV       OutputFormat = "csv"
+	OutputFormatNDJSON    OutputFormat = "ndjson"
+	OutputFormatRawJSON   OutputFormat = "raw-json"
+	OutputFormatRawCSV    OutputFormat = "raw-csv"
 	OutputFormatTable     OutputFormat = "table"
 	OutputFormatTableLong OutputFormat = "table-long"
 )
@@ -47,11 +53,11 @@ func (o *OutputFormat) String() string {
 // Must have a pointer receiver so it doesn't actually change the value of a copy and not the value itself.
 func (o *OutputFormat) Set(value string) error {
 	switch value {
-	case "hledger", "json", "csv", "table", "table-long":
+	case "hledger", "beancount", "ledger", "template", "json", "csv", "ndjson", "raw-json", "raw-csv", "table", "table-long":
 		*o = OutputFormat(value)
 		return nil
 	default:
-		return errors.New("possible values are \"hledger\", \"json\", \"csv\", \"table\", \"table-long\"")
+		return errors.New("possible values are \"hledger\", \"beancount\", \"ledger\", \"template\", \"json\", \"csv\", \"ndjson\", \"raw-json\", \"raw-csv\", \"table\", \"table-long\"")
 	}
 }
 
@@ -63,8 +69,14 @@ func (o *OutputFormat) Type() string {
 func OutputFormatCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	return []string{
 		"hledger\toutput the results as hledger records format",
+		"beancount\toutput the results as Beancount price directives",
+		"ledger\toutput the results as Ledger price directives (pricedb)",
+		"template\toutput the results with the Go template given by --template or --template-file",
 		"json\toutput the results in JSON format",
 		"csv\toutput the results in CSV format",
+		"ndjson\toutput the results as one JSON object per line, written as soon as each symbol is fetched",
+		"raw-json\toutput the JSON body returned by the provider as is",
+		"raw-csv\toutput the CSV body returned by the provider as is",
 		"table\toutput the results in a table format",
 		"table-long\toutput the results in a table format (long version)",
 	}, cobra.ShellCompDirectiveDefault
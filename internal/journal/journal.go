@@ -0,0 +1,385 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+)
+
+// Journal contains the information of an hledger journal (and of the files it includes) that is needed to know
+// which prices are missing.
+type Journal struct {
+	// Files is the list of files that were read, in the order they were read.
+	Files []string
+	// FirstPosting is the date of the first transaction with a posting in each commodity.
+	FirstPosting map[string]time.Time
+	// LatestPrice is the date of the latest `P` directive for each commodity.
+	LatestPrice map[string]time.Time
+	// Movements are the postings of each commodity, which are needed to know the quantity held at a certain date.
+	Movements map[string][]Movement
+}
+
+// Movement is the quantity of a commodity added to (or removed from) an account by a posting.
+type Movement struct {
+	Date     time.Time
+	Account  string
+	Quantity decimal.Decimal
+}
+
+// dateFormats are the formats accepted by hledger for full dates. The month and the day may be zero-padded or not
+// (e.g. `2024-01-05` or `2024/1/5`).
+var dateFormats = []string{"2006-1-2", "2006/1/2", "2006.1.2"}
+
+// parseDate parses a full date in any of the formats accepted by hledger.
+// Secondary dates (e.g. `2024-01-02=2024-01-05`) are ignored.
+func parseDate(s string) (time.Time, error) {
+	s, _, _ = strings.Cut(s, "=")
+	for _, layout := range dateFormats {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("[journal.parseDate] invalid date %q", s)
+}
+
+// partialDate reports whether a date has no year (e.g. `1/5` or `01-05`), in which case hledger takes the year from
+// the `Y` directive or the current year.
+func partialDate(s string) bool {
+	s, _, _ = strings.Cut(s, "=")
+	return len(strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '/' || r == '.' })) == 2
+}
+
+// stripComment removes the inline comment of a line, if there is one.
+func stripComment(line string) string {
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// splitCommodity reads the commodity symbol at the start of `s`, which may be enclosed in double quotes.
+// It returns the symbol and the rest of the string.
+func splitCommodity(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "\"") {
+		if symbol, rest, ok := strings.Cut(s[1:], "\""); ok {
+			return symbol, rest
+		}
+		return "", s
+	}
+	symbol, rest, _ := strings.Cut(s, " ")
+	return symbol, rest
+}
+
+// ParseCommodity returns the commodity symbol of an hledger amount (e.g. `$100`, `-10.5 EUR`, `3 "VWRL.L"`).
+// It returns an empty string if the amount has no commodity.
+func ParseCommodity(amount string) string {
+	amount = strings.TrimSpace(amount)
+	if start := strings.Index(amount, "\""); start >= 0 {
+		if end := strings.Index(amount[start+1:], "\""); end >= 0 {
+			return amount[start+1 : start+1+end]
+		}
+	}
+
+	for _, field := range strings.Fields(amount) {
+		symbol := strings.Map(func(r rune) rune {
+			if strings.ContainsRune("0123456789+-.,' ", r) {
+				return -1
+			}
+			return r
+		}, field)
+		if symbol != "" {
+			return symbol
+		}
+	}
+	return ""
+}
+
+// postingAmount returns the amount of a posting line, without the account name, the cost and the balance assertion.
+func postingAmount(line string) string {
+	_, amount := splitPosting(line)
+	return amount
+}
+
+// splitPosting returns the account name of a posting line, without the brackets of virtual postings, and its amount,
+// without the cost, the lot price and the balance assertion.
+func splitPosting(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "! ") {
+		line = strings.TrimSpace(line[2:])
+	}
+
+	// The account name is separated from the amount by a tab or at least two spaces.
+	i := strings.Index(line, "  ")
+	if j := strings.Index(line, "\t"); j >= 0 && (i < 0 || j < i) {
+		i = j
+	}
+	if i < 0 {
+		return strings.Trim(line, "()[]"), ""
+	}
+
+	amount := line[i:]
+	if k := strings.IndexAny(amount, "@={"); k >= 0 {
+		amount = amount[:k]
+	}
+	return strings.Trim(line[:i], "()[]"), strings.TrimSpace(amount)
+}
+
+// parseQuantity returns the number of an hledger amount, once its commodity is removed. Both `.` and `,` are accepted
+// as decimal mark; when both are present, the first one is taken as the digit group mark.
+func parseQuantity(amount string, commodity string) (decimal.Decimal, error) {
+	number := strings.Replace(amount, "\""+commodity+"\"", "", 1)
+	if number == amount {
+		number = strings.Replace(amount, commodity, "", 1)
+	}
+	number = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, number)
+
+	dot, comma := strings.LastIndex(number, "."), strings.LastIndex(number, ",")
+	switch {
+	case dot >= 0 && comma >= 0 && dot < comma:
+		number = strings.ReplaceAll(number, ".", "")
+		number = strings.Replace(number, ",", ".", 1)
+	case dot >= 0 && comma >= 0:
+		number = strings.ReplaceAll(number, ",", "")
+	case strings.Count(number, ",") > 1:
+		number = strings.ReplaceAll(number, ",", "")
+	case comma >= 0:
+		number = strings.Replace(number, ",", ".", 1)
+	}
+
+	quantity, err := decimal.Parse(number)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("[journal.parseQuantity] invalid amount %q", amount)
+	}
+	return quantity, nil
+}
+
+// Holding returns the quantity of a commodity held at the end of a day in an account and its subaccounts.
+func (j *Journal) Holding(commodity string, account string, date time.Time) decimal.Decimal {
+	var quantity decimal.Decimal
+	for _, held := range j.Holdings(commodity, account, date) {
+		quantity = quantity.Add(held)
+	}
+	return quantity
+}
+
+// Holdings returns the quantity of a commodity held at the end of a day in each of the subaccounts of an account
+// (including the account itself). Accounts whose balance is zero are left out.
+func (j *Journal) Holdings(commodity string, account string, date time.Time) map[string]decimal.Decimal {
+	holdings := make(map[string]decimal.Decimal)
+	for _, movement := range j.Movements[commodity] {
+		if movement.Date.After(date) {
+			continue
+		}
+		if movement.Account == account || strings.HasPrefix(movement.Account, account+":") {
+			holdings[movement.Account] = holdings[movement.Account].Add(movement.Quantity)
+		}
+	}
+	for account, quantity := range holdings {
+		if quantity.IsZero() {
+			delete(holdings, account)
+		}
+	}
+	return holdings
+}
+
+// Parse reads an hledger journal, following its `include` directives.
+func Parse(path string) (*Journal, error) {
+	j := &Journal{
+		FirstPosting: make(map[string]time.Time),
+		LatestPrice:  make(map[string]time.Time),
+		Movements:    make(map[string][]Movement),
+	}
+	if err := j.Read(path); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Read reads another journal file into j, following its `include` directives. Files that were already read are
+// skipped, so it is safe to read a file that might already be included by the journal.
+func (j *Journal) Read(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("[journal.(*Journal).Read] failed to resolve path: %w", err)
+	}
+	for _, file := range j.Files {
+		if file == path {
+			return nil
+		}
+	}
+	j.Files = append(j.Files, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("[journal.(*Journal).Read] failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var inTransaction, inComment bool
+	var date time.Time
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+
+		// Skip comment blocks and comment lines.
+		if inComment {
+			inComment = strings.TrimSpace(line) != "end comment"
+			continue
+		}
+		if strings.TrimSpace(line) == "comment" {
+			inComment = true
+			continue
+		}
+		if strings.TrimSpace(line) == "" || strings.ContainsRune(";#*%", rune(line[0])) {
+			inTransaction = false
+			continue
+		}
+
+		// Indented lines are postings if they belong to a transaction, or are ignored otherwise
+		// (e.g. subdirectives or postings of periodic and auto transactions).
+		if line[0] == ' ' || line[0] == '\t' {
+			if !inTransaction {
+				continue
+			}
+			account, amount := splitPosting(stripComment(line))
+			commodity := ParseCommodity(amount)
+			if commodity == "" {
+				continue
+			}
+			if first, ok := j.FirstPosting[commodity]; !ok || (!date.IsZero() && date.Before(first)) {
+				j.FirstPosting[commodity] = date
+			}
+			quantity, err := parseQuantity(amount, commodity)
+			if err != nil {
+				return fmt.Errorf("[journal.(*Journal).Read] %s:%d: %w", path, lineNumber, err)
+			}
+			j.Movements[commodity] = append(j.Movements[commodity], Movement{Date: date, Account: account, Quantity: quantity})
+			continue
+		}
+
+		inTransaction = false
+		line = stripComment(line)
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "include":
+			if err := j.include(filepath.Dir(path), strings.TrimSpace(strings.TrimPrefix(line, "include"))); err != nil {
+				return err
+			}
+		case fields[0] == "P":
+			if len(fields) < 3 {
+				return fmt.Errorf("[journal.(*Journal).Read] %s:%d: invalid price directive", path, lineNumber)
+			}
+			priceDate, err := parseDate(fields[1])
+			if err != nil {
+				return fmt.Errorf("[journal.(*Journal).Read] %s:%d: %w", path, lineNumber, err)
+			}
+			rest := strings.TrimSpace(line[1:])
+			commodity, _ := splitCommodity(rest[len(fields[1]):])
+			if latest, ok := j.LatestPrice[commodity]; !ok || priceDate.After(latest) {
+				j.LatestPrice[commodity] = priceDate
+			}
+		case line[0] >= '0' && line[0] <= '9':
+			inTransaction = true
+			// Dates without a year cannot be placed in time, so the transaction is kept without a date.
+			if partialDate(fields[0]) {
+				date = time.Time{}
+				continue
+			}
+			date, err = parseDate(fields[0])
+			if err != nil {
+				return fmt.Errorf("[journal.(*Journal).Read] %s:%d: %w", path, lineNumber, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("[journal.(*Journal).Read] failed to read journal: %w", err)
+	}
+
+	return nil
+}
+
+// ExpandHome replaces a leading `~/` in a path by the home directory of the user.
+func ExpandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("[journal.ExpandHome] failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, path[2:]), nil
+}
+
+// Quote encloses a commodity symbol in double quotes when hledger requires it, i.e. when it contains anything other
+// than letters and currency signs.
+func Quote(commodity string) string {
+	if strings.IndexFunc(commodity, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.Is(unicode.Sc, r) }) >= 0 {
+		return "\"" + commodity + "\""
+	}
+	return commodity
+}
+
+// include reads the files matching the pattern of an `include` directive, relative to the directory of the file
+// containing the directive.
+func (j *Journal) include(dir string, pattern string) error {
+	if pattern == "" {
+		return errors.New("[journal.(*Journal).include] include directive without a file")
+	}
+	pattern, err := ExpandHome(pattern)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("[journal.(*Journal).include] invalid include pattern: %w", err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("[journal.(*Journal).include] no file matches %q", pattern)
+	}
+	for _, match := range matches {
+		if err := j.Read(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+)
+
+func TestParseCommodity(t *testing.T) {
+	tests := map[string]string{
+		"$100":         "$",
+		"-$1,000.50":   "$",
+		"-10.5 EUR":    "EUR",
+		"EUR 10":       "EUR",
+		"100EUR":       "EUR",
+		`3 "VWRL.L"`:   "VWRL.L",
+		`-3.5 "ABC 1"`: "ABC 1",
+		"1 000,00 BTC": "BTC",
+		"42":           "",
+		"":             "",
+	}
+
+	for amount, expected := range tests {
+		if result := ParseCommodity(amount); result != expected {
+			t.Errorf("expected %q for %q, got %q", expected, amount, result)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		amount    string
+		commodity string
+		expected  string
+	}{
+		{"$100", "$", "100"},
+		{"-$1,000.50", "$", "-1000.50"},
+		{"-10.5 EUR", "EUR", "-10.5"},
+		{"EUR 10", "EUR", "10"},
+		{`3 "VWRL.L"`, "VWRL.L", "3"},
+		{"1 000,25 BTC", "BTC", "1000.25"},
+		{"1.000.000,5 EUR", "EUR", "1000000.5"},
+		{"1,000,000 EUR", "EUR", "1000000"},
+	}
+
+	for _, test := range tests {
+		quantity, err := parseQuantity(test.amount, test.commodity)
+		if err != nil {
+			t.Errorf("expected nil for %q, got %v", test.amount, err)
+		} else if quantity.String() != test.expected {
+			t.Errorf("expected %s for %q, got %s", test.expected, test.amount, quantity)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := map[string]string{
+		"2024-01-05":            "2024-01-05",
+		"2024/1/5":              "2024-01-05",
+		"2024-1-05":             "2024-01-05",
+		"2024.12.31":            "2024-12-31",
+		"2024-07-01=2024-07-02": "2024-07-01",
+	}
+
+	for s, expected := range tests {
+		result, err := parseDate(s)
+		if err != nil {
+			t.Errorf("expected nil for %q, got %v", s, err)
+		} else if !result.Equal(date(expected)) {
+			t.Errorf("expected %s for %q, got %s", expected, s, result)
+		}
+	}
+
+	for _, s := range []string{"2024-13-01", "2024-1/5", "24-01-05", "yesterday"} {
+		if _, err := parseDate(s); err == nil {
+			t.Errorf("expected error for %q, got nil", s)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.journal"), `; main journal
+include prices.journal
+include years/*.journal
+
+2024-01-02 buy
+    assets:broker   10 "VWRL.L" @ 100 EUR
+    assets:cash     -1000 EUR  ; comment
+
+~ monthly
+    expenses:rent   1000 GBP
+    assets:cash
+
+comment
+2020-01-01 ignored
+    assets:x  1 XYZ
+end comment
+`)
+	writeFile(t, filepath.Join(dir, "prices.journal"), `P 2024-01-05 "VWRL.L" 101 EUR
+P 2024/2/5 VWRL.L 102 EUR
+P 2024-01-10 "VWRL.L" 101.5 EUR
+`)
+	writeFile(t, filepath.Join(dir, "years", "2023.journal"), `2023-06-01 * coins
+    assets:btc      0.1 BTC = 0.1 BTC
+    assets:usd      $-3000
+
+2023-07-01=2023-07-02 ! more coins
+    assets:btc      0.2 BTC
+    * assets:eur    -500 EUR
+`)
+
+	j, err := Parse(filepath.Join(dir, "main.journal"))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	t.Run("files", func(t *testing.T) {
+		if len(j.Files) != 3 {
+			t.Errorf("expected 3 files, got %v", j.Files)
+		}
+	})
+
+	t.Run("first postings", func(t *testing.T) {
+		expected := map[string]time.Time{
+			"VWRL.L": date("2024-01-02"),
+			"EUR":    date("2023-07-01"),
+			"BTC":    date("2023-06-01"),
+			"$":      date("2023-06-01"),
+		}
+		if len(j.FirstPosting) != len(expected) {
+			t.Errorf("expected %v, got %v", expected, j.FirstPosting)
+		}
+		for commodity, first := range expected {
+			if !j.FirstPosting[commodity].Equal(first) {
+				t.Errorf("expected %s for %s, got %s", first, commodity, j.FirstPosting[commodity])
+			}
+		}
+	})
+
+	t.Run("latest prices", func(t *testing.T) {
+		if len(j.LatestPrice) != 1 || !j.LatestPrice["VWRL.L"].Equal(date("2024-02-05")) {
+			t.Errorf("expected VWRL.L on 2024-02-05, got %v", j.LatestPrice)
+		}
+	})
+
+	t.Run("holdings", func(t *testing.T) {
+		if quantity := j.Holding("BTC", "assets", date("2023-06-30")); !quantity.Equal(decimal.MustParse("0.1")) {
+			t.Errorf("expected 0.1 BTC, got %s", quantity)
+		}
+		// The quantities are exact, so 0.1 + 0.2 is 0.3.
+		if quantity := j.Holding("BTC", "assets:btc", date("2023-07-01")); quantity.String() != "0.3" {
+			t.Errorf("expected 0.3 BTC, got %s", quantity)
+		}
+		if quantity := j.Holding("VWRL.L", "assets:broker", date("2024-01-01")); !quantity.IsZero() {
+			t.Errorf("expected no VWRL.L before the purchase, got %s", quantity)
+		}
+		if quantity := j.Holding("VWRL.L", "assets:broke", date("2024-12-31")); !quantity.IsZero() {
+			t.Errorf("expected no VWRL.L in another account, got %s", quantity)
+		}
+		if quantity := j.Holding("VWRL.L", "assets", date("2024-12-31")); !quantity.Equal(decimal.New(10, 0)) {
+			t.Errorf("expected 10 VWRL.L, got %s", quantity)
+		}
+		if holdings := j.Holdings("EUR", "assets", date("2024-12-31")); len(holdings) != 2 || !holdings["assets:eur"].Equal(decimal.New(-500, 0)) || !holdings["assets:cash"].Equal(decimal.New(-1000, 0)) {
+			t.Errorf("expected EUR in assets:eur and assets:cash, got %v", holdings)
+		}
+	})
+
+	t.Run("file read twice", func(t *testing.T) {
+		if err := j.Read(filepath.Join(dir, "prices.journal")); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if len(j.Files) != 3 {
+			t.Errorf("expected 3 files, got %v", j.Files)
+		}
+	})
+
+	t.Run("invalid transaction date", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir, "invalid.journal"), "2024-01-02 buy\n    assets:cash  1 EUR\n\n2024-02-30 sell\n    assets:cash  -1 EUR\n")
+		_, err := Parse(filepath.Join(dir, "invalid.journal"))
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if !strings.Contains(err.Error(), "invalid.journal:4") {
+			t.Errorf("expected the file and line number in %q", err)
+		}
+	})
+
+	t.Run("date without year", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir, "partial.journal"), "1/5 buy\n    assets:cash  1 GBP\n")
+		j, err := Parse(filepath.Join(dir, "partial.journal"))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if first, ok := j.FirstPosting["GBP"]; !ok || !first.IsZero() {
+			t.Errorf("expected GBP without a date, got %v", j.FirstPosting)
+		}
+	})
+
+	t.Run("missing include", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir, "broken.journal"), "include missing.journal\n")
+		if _, err := Parse(filepath.Join(dir, "broken.journal")); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,56 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package ledger formats the prices as Ledger `P` directives, as found in a pricedb file.
+package ledger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// reservedCharacters are the characters that Ledger does not accept in a commodity unless it is quoted.
+const reservedCharacters = ".,;:?!-+*/^&|=<>{}[]()@\""
+
+// Commodity returns the name of a commodity as written by Ledger. The names containing digits, spaces or one of the
+// reserved characters of Ledger are quoted (e.g. "TSCO.LON" is written `"TSCO.LON"`, while "IBM" is kept as is).
+func Commodity(symbol string) string {
+	if strings.IndexFunc(symbol, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune(reservedCharacters, r)
+	}) >= 0 {
+		return "\"" + strings.ReplaceAll(symbol, "\"", "") + "\""
+	}
+	return symbol
+}
+
+// Price returns the `P` directive of a commodity at a given moment. The time is only written when `date` has one.
+// The amount is written as is, so it must already be formatted (see internal.FormatPrice).
+func Price(date time.Time, commodity string, amount string, currency string) string {
+	layout := "2006/01/02"
+	if hasTime(date) {
+		layout = "2006/01/02 15:04:05"
+	}
+	return fmt.Sprintf("P %s %s %s %s\n", date.Format(layout), Commodity(commodity), amount, Commodity(currency))
+}
+
+// hasTime reports whether a moment has a time of day other than midnight.
+func hasTime(date time.Time) bool {
+	return date.Hour() != 0 || date.Minute() != 0 || date.Second() != 0
+}
@@ -26,14 +26,60 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal/cache"
+	"github.com/lentidas/hledger-price-tracker/internal/fixture"
+	"github.com/lentidas/hledger-price-tracker/internal/period"
+	"github.com/lentidas/hledger-price-tracker/internal/ratelimit"
 )
 
-const ApiBaseUrl string = "https://www.alphavantage.co/query?"
+// DefaultBaseUrl is the address of the Alpha Vantage API.
+const DefaultBaseUrl string = "https://www.alphavantage.co"
+
+// SourceAlphaVantage is the name given to the Alpha Vantage API as the source of the prices.
+const SourceAlphaVantage string = "alphavantage"
+
+// ApiBaseUrl, PhysicalCurrencyListUrl and DigitalCurrencyListUrl are the addresses used to reach the Alpha Vantage API.
+// They are derived from the base URL given to SetBaseUrl, so the API can be replaced by a mirror or a local stand-in.
+var ApiBaseUrl string
+var PhysicalCurrencyListUrl string
+var DigitalCurrencyListUrl string
+
+// Record and Replay are directories where the responses of the APIs are recorded as fixtures, or replayed from.
+var Record string
+var Replay string
+
+func init() {
+	SetBaseUrl(DefaultBaseUrl)
+}
+
+// SetBaseUrl changes the address of the Alpha Vantage API (e.g. "http://127.0.0.1:8080").
+func SetBaseUrl(baseUrl string) {
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	ApiBaseUrl = baseUrl + "/query?"
+	PhysicalCurrencyListUrl = baseUrl + "/physical_currency_list/"
+	DigitalCurrencyListUrl = baseUrl + "/digital_currency_list/"
+}
 
 var ApiKey string
 var DefaultCurrency string
 var DebugMode bool
+var ProviderName string
+var NoCache bool
+var RefreshCache bool
+var Plan string
+var RequestsPerMinute int
+var RequestsPerDay int
+
+// Workers is the maximum number of symbols whose prices are fetched at the same time.
+var Workers int
+
+// Limiter spaces out the requests made to the Alpha Vantage API and keeps track of the daily budget.
+// It is nil when no limits apply (e.g. in the tests).
+var Limiter *ratelimit.Limiter
 
 // httpGet performs a single HTTP GET and returns the raw response body.
 func httpGet(url string) ([]byte, error) {
@@ -47,51 +93,92 @@ func httpGet(url string) ([]byte, error) {
 	if err != nil {
 		return []byte{}, fmt.Errorf("[internal.HTTPRequest] failure to read HTTP body: %w", err)
 	}
+	if resp.StatusCode != http.StatusOK {
+		return []byte{}, fmt.Errorf("[internal.HTTPRequest] unexpected HTTP status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
 
 	return body, nil
 }
 
-// apiErrorMessage checks whether a response body is an Alpha Vantage error envelope
-// (rate-limit, daily cap, invalid key, etc.) and returns the message, or an empty
-// string when the body is a normal response.
-func apiErrorMessage(body []byte) string {
+// apiError checks whether a response body is an Alpha Vantage error envelope (rate-limit, daily cap, invalid key, etc.)
+// and returns it as an *APIError, or nil when the body is a normal response.
+func apiError(body []byte) error {
 	if len(body) == 0 || body[0] != '{' {
-		return ""
+		return nil
 	}
 	var envelope struct {
-		Note        string `json:"Note"`
-		Information string `json:"Information"`
+		Note         string `json:"Note"`
+		Information  string `json:"Information"`
+		ErrorMessage string `json:"Error Message"`
 	}
 	if err := json.Unmarshal(body, &envelope); err != nil {
-		return ""
+		return nil
 	}
-	if envelope.Note != "" {
-		return envelope.Note
+
+	for _, message := range []string{envelope.ErrorMessage, envelope.Note, envelope.Information} {
+		if message != "" {
+			return &APIError{Err: classifyAPIError(message), Message: message}
+		}
 	}
-	return envelope.Information
+	return nil
 }
 
 // HTTPRequest makes an HTTP GET request and returns the body as a byte slice.
 // This is the main entry point for all API calls, so it also checks for Alpha Vantage error envelopes
 // and sends them back to the caller if an error happens.
-// I've hardcoded a single retry after one second to circumvent the per-second rate-limit that Alpha Vantage added
-// recently. If an user uses a premium API key they won't be affected by this, but free users will get a transparent
-// retry when they hit the limit.
-// After a single retry, if the response is still an error envelope, the message is returned directly to the caller.
+// Requests to the Alpha Vantage API first go through the Limiter, which waits as needed to respect the limit per minute
+// of the user's plan and refuses to make the request if the daily budget is exhausted.
+// Successful responses are stored in the on-disk cache and served from it while they are fresh, unless the user
+// disabled the cache (`--no-cache`) or asked to refresh it (`--refresh`).
+// When replaying (`--replay`), the responses only come from the fixtures and nothing else is used. When recording
+// (`--record`), every response coming from the network is saved as a fixture, including the error envelopes, so that
+// failures can be reproduced later.
 func HTTPRequest(url string) ([]byte, error) {
+	if Replay != "" {
+		body, err := fixture.Load(Replay, url)
+		if err != nil {
+			return []byte{}, err
+		}
+		if err := apiError(body); err != nil {
+			return []byte{}, err
+		}
+		return body, nil
+	}
+
+	if !NoCache && !RefreshCache && Record == "" {
+		if body, ok := cache.Get(url); ok {
+			return body, nil
+		}
+	}
+
+	if Limiter != nil && strings.HasPrefix(url, ApiBaseUrl) {
+		if err := Limiter.Acquire(); err != nil {
+			if errors.Is(err, ratelimit.ErrDailyBudgetExceeded) {
+				return []byte{}, fmt.Errorf("[internal.HTTPRequest] %w: %w", ErrQuotaExhausted, err)
+			}
+			return []byte{}, err
+		}
+	}
+
 	body, err := httpGet(url)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	if msg := apiErrorMessage(body); msg != "" {
-		time.Sleep(time.Second)
-		body, err = httpGet(url)
-		if err != nil {
+	if Record != "" {
+		if err := fixture.Save(Record, url, body); err != nil {
 			return []byte{}, err
 		}
-		if msg = apiErrorMessage(body); msg != "" {
-			return []byte{}, fmt.Errorf("[internal.HTTPRequest] Alpha Vantage API error: %s", msg)
+	}
+
+	if err := apiError(body); err != nil {
+		return []byte{}, err
+	}
+
+	if !NoCache {
+		// A failure to write to the cache should never prevent the user from getting their prices.
+		if err := cache.Put(url, body); err != nil && DebugMode {
+			fmt.Fprintln(os.Stderr, err)
 		}
 	}
 
@@ -129,25 +216,32 @@ func ParseCurrenciesCSV(body []byte) (map[string]string, error) {
 }
 
 // ValidateDates checks if begin and end dates are valid then returns its parsed values
-// in the time.Time format.
+// in the time.Time format. Both can be dates or hledger-style period expressions (e.g. "lastmonth" or "-30d", see the
+// period package): the begin date is the first day of its period and the end date, which is included, is the last one.
 func ValidateDates(begin, end string) (time.Time, time.Time, error) {
 	var err error
 	var beginTime time.Time
 	var endTime time.Time = time.Now()
+	// The parsed dates are at midnight UTC, like the dates of the prices.
+	today := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.UTC)
 	if begin != "" {
-		beginTime, err = time.Parse("2006-01-02", begin)
+		beginTime, err = period.Begin(begin, endTime)
 		if err != nil {
 			return time.Now(), time.Now(), fmt.Errorf("[internal.ValidateDates] failed to parse begin date: %w", err)
 		}
-		if beginTime.After(time.Now()) {
+		if beginTime.After(today) {
 			return time.Now(), time.Now(), errors.New("[internal.ValidateDates] begin date is in the future")
 		}
 	}
 	if end != "" {
-		endTime, err = time.Parse("2006-01-02", end)
+		parsed, err := period.End(end, endTime)
 		if err != nil {
 			return time.Now(), time.Now(), fmt.Errorf("[internal.ValidateDates] failed to parse end date: %w", err)
 		}
+		// A period without end (e.g. "from 2024") ends now.
+		if !parsed.IsZero() {
+			endTime = parsed
+		}
 	}
 	if beginTime.After(endTime) {
 		return time.Now(), time.Now(), errors.New("[internal.ValidateDates] begin date is after end date")
@@ -19,9 +19,13 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
 )
 
 func TestParseCurrenciesCSV(t *testing.T) {
@@ -80,4 +84,130 @@ func TestValidateDates(t *testing.T) {
 			t.Error("expected error, got nil")
 		}
 	})
+
+	t.Run("period expressions", func(t *testing.T) {
+		begin, end, err := ValidateDates("2025Q1", "2025/04")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if expected := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); !begin.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, begin)
+		}
+		if expected := time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC); !end.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, end)
+		}
+	})
+
+	t.Run("begin date today", func(t *testing.T) {
+		if _, _, err := ValidateDates("today", ""); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("invalid period expression", func(t *testing.T) {
+		if _, _, err := ValidateDates("lastfortnight", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestAPIError(t *testing.T) {
+	tests := map[string]error{
+		`{"Error Message": "the parameter apikey is invalid or missing. Please claim your free API key on (https://www.alphavantage.co/support/#api-key)."}`:                                                                                                                                ErrInvalidAPIKey,
+		`{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 500 calls per day. Please visit https://www.alphavantage.co/premium/ if you would like to target a higher API call frequency."}`:                                            ErrRateLimited,
+		`{"Information": "We have detected your API key as demo and our standard API rate limit is 25 requests per day. Please subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly remove all daily rate limits."}`:                                  ErrQuotaExhausted,
+		`{"Information": "Thank you for using Alpha Vantage! The outputsize=full parameter value is a premium feature for the TIME_SERIES_DAILY endpoint. You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly unlock all premium features"}`: ErrPremiumOnly,
+		`{"Error Message": "Invalid API call. Please retry or visit the documentation (https://www.alphavantage.co/documentation/) for TIME_SERIES_DAILY."}`:                                                                                                                                ErrInvalidAPICall,
+		`{"Information": "Something unexpected happened."}`: ErrUnknownAPIFailure,
+	}
+
+	for body, expected := range tests {
+		err := apiError([]byte(body))
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %v, got %v", expected, err)
+		}
+	}
+
+	t.Run("normal responses", func(t *testing.T) {
+		for _, body := range []string{`{"Meta Data": {}}`, "code,name\nUSD,United States Dollar", ""} {
+			if err := apiError([]byte(body)); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+		}
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	tests := map[error]int{
+		nil:                         ExitSuccess,
+		errors.New("anything else"): ExitFailure,
+		&APIError{Err: ErrRateLimited, Message: "slow down"}:          ExitRateLimited,
+		fmt.Errorf("wrapped: %w", ErrQuotaExhausted):                  ExitQuotaExhausted,
+		fmt.Errorf("[x] %w: %w", ErrUnknownSymbol, ErrInvalidAPICall): ExitUnknownSymbol,
+		errors.Join(errors.New("first"), ErrEmptyTimeSeries):          ExitEmptyTimeSeries,
+	}
+
+	for err, expected := range tests {
+		if result := ExitCode(err); result != expected {
+			t.Errorf("expected %d for %v, got %d", expected, err, result)
+		}
+	}
+}
+
+func TestMajorUnit(t *testing.T) {
+	t.Run("minor unit", func(t *testing.T) {
+		currency, divisor := MajorUnit("GBX")
+		if currency != "GBP" || divisor.String() != "100" {
+			t.Errorf("expected GBP and 100, got %s and %v", currency, divisor)
+		}
+	})
+
+	t.Run("major unit", func(t *testing.T) {
+		currency, divisor := MajorUnit("USD")
+		if currency != "USD" || divisor.String() != "1" {
+			t.Errorf("expected USD and 1, got %s and %v", currency, divisor)
+		}
+	})
+
+	t.Run("keep minor units", func(t *testing.T) {
+		KeepMinorUnits = true
+		defer func() { KeepMinorUnits = false }()
+		currency, divisor := MajorUnit("ZAc")
+		if currency != "ZAc" || divisor.String() != "1" {
+			t.Errorf("expected ZAc and 1, got %s and %v", currency, divisor)
+		}
+	})
+}
+
+func TestFormatPrice(t *testing.T) {
+	price := decimal.MustParse("0.00001234")
+
+	t.Run("digits of the API", func(t *testing.T) {
+		if result := FormatPrice(price, "SHIB", "USD"); result != "0.00001234" {
+			t.Errorf("expected 0.00001234, got %s", result)
+		}
+		if result := FormatPrice(decimal.MustParse("227.4800"), "IBM", "USD"); result != "227.48" {
+			t.Errorf("expected 227.48, got %s", result)
+		}
+	})
+
+	t.Run("precision of the commodity and of the currency", func(t *testing.T) {
+		Precisions = map[string]int{"shib": 6, "jpy": 0}
+		defer func() { Precisions = nil }()
+		if result := FormatPrice(price, "SHIB", "USD"); result != "0.000012" {
+			t.Errorf("expected 0.000012, got %s", result)
+		}
+		if result := FormatPrice(decimal.MustParse("162.535"), "EUR", "JPY"); result != "163" {
+			t.Errorf("expected 163, got %s", result)
+		}
+	})
+
+	t.Run("precision flag", func(t *testing.T) {
+		Precision = 4
+		Precisions = map[string]int{"shib": 6}
+		defer func() { Precision, Precisions = -1, nil }()
+		if result := FormatPrice(price, "SHIB", "USD"); result != "0.0000" {
+			t.Errorf("expected 0.0000, got %s", result)
+		}
+	})
 }
@@ -0,0 +1,286 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+// Package period parses the dates and periods given to the `--begin`, `--end` and `--period` flags, which follow the
+// syntax of the period expressions of hledger (see https://hledger.org/hledger.html#period-expressions).
+package period
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is a range of days, both included. A zero Begin or End means that the period is open on that side.
+type Period struct {
+	Begin time.Time
+	End   time.Time
+}
+
+// Dates returns the first and last days of the period in the YYYY-MM-DD format, or an empty string for an open side.
+func (p Period) Dates() (string, string) {
+	format := func(date time.Time) string {
+		if date.IsZero() {
+			return ""
+		}
+		return date.Format("2006-01-02")
+	}
+	return format(p.Begin), format(p.End)
+}
+
+var (
+	// relative is a number of days, weeks, months, quarters or years before (-) or after (+) today (e.g. "-30d").
+	relative = regexp.MustCompile(`^([+-]\d+)([dwmqy])$`)
+	// quarter is a quarter of a year (e.g. "2025q1").
+	quarter = regexp.MustCompile(`^(\d{4})q([1-4])$`)
+	// numeric is a year, a month or a day, with "-", "/" or "." between its parts (e.g. "2025/04/05").
+	numeric = regexp.MustCompile(`^(\d{4})(?:[-/.](\d{1,2})(?:[-/.](\d{1,2}))?)?$`)
+	// compact is a month or a day without separators (e.g. "20250405").
+	compact = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})?$`)
+)
+
+// units are the lengths of the periods that can be named relative to today (e.g. "lastmonth").
+var units = []string{"day", "week", "month", "quarter", "year"}
+
+// Parse parses a period expression relative to `today`. It is either:
+//   - a date or a named period (e.g. "2025/04/05", "2025-04", "2025q1", "lastmonth"), covering all its days;
+//   - a range such as "from 2024-01 to 2024-06", where, as in hledger, the end is excluded (January to May);
+//     "since" is a synonym of "from", "until" and ".." are synonyms of "to", and either side may be left out;
+//   - "ytd", "qtd", "mtd" or "wtd", from the start of the current year, quarter, month or week to today;
+//   - a relative date such as "-30d", from that day to today.
+func Parse(expression string, today time.Time) (Period, error) {
+	today = day(today)
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(expression, "..", " to ")))
+	if len(words) == 0 {
+		return Period{}, errors.New("[period.Parse] empty period expression")
+	}
+
+	// Ranges.
+	for i, word := range words {
+		if word != "to" && word != "until" {
+			continue
+		}
+		p := Period{}
+		if begin := strings.Join(trimFrom(words[:i]), " "); begin != "" {
+			span, err := parseDate(begin, today)
+			if err != nil {
+				return Period{}, fmt.Errorf("[period.Parse] invalid period expression %q: %w", expression, err)
+			}
+			p.Begin = span.Begin
+		}
+		span, err := parseDate(strings.Join(words[i+1:], " "), today)
+		if err != nil {
+			return Period{}, fmt.Errorf("[period.Parse] invalid period expression %q: %w", expression, err)
+		}
+		p.End = span.Begin.AddDate(0, 0, -1)
+		if !p.Begin.IsZero() && p.End.Before(p.Begin) {
+			return Period{}, fmt.Errorf("[period.Parse] invalid period expression %q: the end is before the beginning", expression)
+		}
+		return p, nil
+	}
+	if words[0] == "from" || words[0] == "since" {
+		span, err := parseDate(strings.Join(words[1:], " "), today)
+		if err != nil {
+			return Period{}, fmt.Errorf("[period.Parse] invalid period expression %q: %w", expression, err)
+		}
+		return Period{Begin: span.Begin}, nil
+	}
+
+	// Periods to date.
+	text := strings.Join(words, "")
+	switch text {
+	case "ytd":
+		return Period{Begin: start(today, "year"), End: today}, nil
+	case "qtd":
+		return Period{Begin: start(today, "quarter"), End: today}, nil
+	case "mtd":
+		return Period{Begin: start(today, "month"), End: today}, nil
+	case "wtd":
+		return Period{Begin: start(today, "week"), End: today}, nil
+	}
+	if relative.MatchString(text) {
+		span, err := parseDate(text, today)
+		if err != nil {
+			return Period{}, fmt.Errorf("[period.Parse] invalid period expression %q: %w", expression, err)
+		}
+		if span.Begin.After(today) {
+			return Period{Begin: today, End: span.Begin}, nil
+		}
+		return Period{Begin: span.Begin, End: today}, nil
+	}
+
+	span, err := parseDate(text, today)
+	if err != nil {
+		return Period{}, fmt.Errorf("[period.Parse] invalid period expression %q: %w", expression, err)
+	}
+	return span, nil
+}
+
+// Begin returns the first day given by the expression of a `--begin` flag: the date itself, or the first day of a
+// period (e.g. "2025-04" begins on the 1st of April).
+func Begin(expression string, today time.Time) (time.Time, error) {
+	if span, err := parseDate(expression, day(today)); err == nil {
+		return span.Begin, nil
+	}
+	p, err := Parse(expression, today)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return p.Begin, nil
+}
+
+// End returns the last day given by the expression of an `--end` flag, which is included: the date itself, or the
+// last day of a period (e.g. "2025-04" ends on the 30th of April).
+func End(expression string, today time.Time) (time.Time, error) {
+	if span, err := parseDate(expression, day(today)); err == nil {
+		return span.End, nil
+	}
+	p, err := Parse(expression, today)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return p.End, nil
+}
+
+// trimFrom removes the "from" or "since" keyword at the start of the beginning of a range.
+func trimFrom(words []string) []string {
+	if len(words) > 0 && (words[0] == "from" || words[0] == "since") {
+		return words[1:]
+	}
+	return words
+}
+
+// parseDate parses a single date or named period (e.g. "2025-04-05", "lastmonth" or "-30d") into the days it covers.
+func parseDate(text string, today time.Time) (Period, error) {
+	text = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(text)), " ", "")
+
+	switch text {
+	case "":
+		return Period{}, errors.New("missing date")
+	case "today":
+		return Period{Begin: today, End: today}, nil
+	case "yesterday":
+		return Period{Begin: today.AddDate(0, 0, -1), End: today.AddDate(0, 0, -1)}, nil
+	case "tomorrow":
+		return Period{Begin: today.AddDate(0, 0, 1), End: today.AddDate(0, 0, 1)}, nil
+	}
+
+	for _, unit := range units {
+		for prefix, offset := range map[string]int{"this": 0, "last": -1, "next": 1} {
+			if text == prefix+unit {
+				begin := shift(start(today, unit), unit, offset)
+				return Period{Begin: begin, End: shift(begin, unit, 1).AddDate(0, 0, -1)}, nil
+			}
+		}
+	}
+
+	if match := relative.FindStringSubmatch(text); match != nil {
+		count, err := strconv.Atoi(match[1])
+		if err != nil {
+			return Period{}, fmt.Errorf("invalid relative date %q: %w", text, err)
+		}
+		unit := map[string]string{"d": "day", "w": "week", "m": "month", "q": "quarter", "y": "year"}[match[2]]
+		date := shift(today, unit, count)
+		return Period{Begin: date, End: date}, nil
+	}
+
+	if match := quarter.FindStringSubmatch(text); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		q, _ := strconv.Atoi(match[2])
+		begin := time.Date(year, time.Month(3*(q-1)+1), 1, 0, 0, 0, 0, time.UTC)
+		return Period{Begin: begin, End: begin.AddDate(0, 3, -1)}, nil
+	}
+
+	match := numeric.FindStringSubmatch(text)
+	if match == nil {
+		match = compact.FindStringSubmatch(text)
+	}
+	if match == nil {
+		return Period{}, fmt.Errorf("unknown date %q", text)
+	}
+	year, _ := strconv.Atoi(match[1])
+	switch {
+	case match[2] == "":
+		begin := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return Period{Begin: begin, End: begin.AddDate(1, 0, -1)}, nil
+	case match[3] == "":
+		month, _ := strconv.Atoi(match[2])
+		if month < 1 || month > 12 {
+			return Period{}, fmt.Errorf("invalid month in %q", text)
+		}
+		begin := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		return Period{Begin: begin, End: begin.AddDate(0, 1, -1)}, nil
+	default:
+		month, _ := strconv.Atoi(match[2])
+		dayOfMonth, _ := strconv.Atoi(match[3])
+		date := time.Date(year, time.Month(month), dayOfMonth, 0, 0, 0, 0, time.UTC)
+		// time.Date normalises the dates that do not exist (e.g. the 30th of February).
+		if date.Month() != time.Month(month) || date.Day() != dayOfMonth {
+			return Period{}, fmt.Errorf("invalid day %q", text)
+		}
+		return Period{Begin: date, End: date}, nil
+	}
+}
+
+// day returns the day of a time, at midnight UTC like the dates of the prices.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// start returns the first day of the day, week (starting on Monday), month, quarter or year of a date.
+func start(date time.Time, unit string) time.Time {
+	switch unit {
+	case "week":
+		return date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+	case "month":
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		return time.Date(date.Year(), date.Month()-(date.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return date
+	}
+}
+
+// shift moves a date by a number of days, weeks, months, quarters or years. When the day does not exist in the
+// resulting month, the last day of that month is used instead (e.g. one month before the 31st of March is the 28th or
+// 29th of February).
+func shift(date time.Time, unit string, count int) time.Time {
+	switch unit {
+	case "week":
+		return date.AddDate(0, 0, 7*count)
+	case "month":
+		return addMonths(date, count)
+	case "quarter":
+		return addMonths(date, 3*count)
+	case "year":
+		return addMonths(date, 12*count)
+	default:
+		return date.AddDate(0, 0, count)
+	}
+}
+
+// addMonths moves a date by a number of months, keeping it in the resulting month.
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return time.Date(first.Year(), first.Month(), min(date.Day(), last), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,134 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+// today is a Wednesday, in the middle of the second quarter.
+var today = time.Date(2025, 5, 14, 15, 30, 0, 0, time.Local)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]Period{
+		"2025/04/05":                 {date(2025, 4, 5), date(2025, 4, 5)},
+		"2025-04-05":                 {date(2025, 4, 5), date(2025, 4, 5)},
+		"2025.4.5":                   {date(2025, 4, 5), date(2025, 4, 5)},
+		"20250405":                   {date(2025, 4, 5), date(2025, 4, 5)},
+		"2024-02":                    {date(2024, 2, 1), date(2024, 2, 29)},
+		"2024":                       {date(2024, 1, 1), date(2024, 12, 31)},
+		"2025Q1":                     {date(2025, 1, 1), date(2025, 3, 31)},
+		"2025q4":                     {date(2025, 10, 1), date(2025, 12, 31)},
+		"today":                      {date(2025, 5, 14), date(2025, 5, 14)},
+		"yesterday":                  {date(2025, 5, 13), date(2025, 5, 13)},
+		"lastmonth":                  {date(2025, 4, 1), date(2025, 4, 30)},
+		"last month":                 {date(2025, 4, 1), date(2025, 4, 30)},
+		"thisweek":                   {date(2025, 5, 12), date(2025, 5, 18)},
+		"lastweek":                   {date(2025, 5, 5), date(2025, 5, 11)},
+		"lastquarter":                {date(2025, 1, 1), date(2025, 3, 31)},
+		"thisyear":                   {date(2025, 1, 1), date(2025, 12, 31)},
+		"nextyear":                   {date(2026, 1, 1), date(2026, 12, 31)},
+		"ytd":                        {date(2025, 1, 1), date(2025, 5, 14)},
+		"qtd":                        {date(2025, 4, 1), date(2025, 5, 14)},
+		"mtd":                        {date(2025, 5, 1), date(2025, 5, 14)},
+		"-30d":                       {date(2025, 4, 14), date(2025, 5, 14)},
+		"-2w":                        {date(2025, 4, 30), date(2025, 5, 14)},
+		"-1y":                        {date(2024, 5, 14), date(2025, 5, 14)},
+		"from 2024-01 to 2024-06":    {date(2024, 1, 1), date(2024, 5, 31)},
+		"since 2024-01 until 2024Q3": {date(2024, 1, 1), date(2024, 6, 30)},
+		"2024-01..2024-06":           {date(2024, 1, 1), date(2024, 5, 31)},
+		"from lastmonth":             {date(2025, 4, 1), time.Time{}},
+		"to 2024":                    {time.Time{}, date(2023, 12, 31)},
+	}
+	for expression, expected := range tests {
+		t.Run(expression, func(t *testing.T) {
+			p, err := Parse(expression, today)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if !p.Begin.Equal(expected.Begin) || !p.End.Equal(expected.End) {
+				t.Errorf("expected %v, got %v", expected, p)
+			}
+		})
+	}
+
+	for _, expression := range []string{"", "lastfortnight", "2025-13", "2025-02-30", "2025Q5", "from", "from 2024 to 2023", "-30x"} {
+		t.Run("invalid "+expression, func(t *testing.T) {
+			if _, err := Parse(expression, today); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestBeginEnd(t *testing.T) {
+	tests := []struct {
+		expression string
+		begin      time.Time
+		end        time.Time
+	}{
+		{"2025-04-05", date(2025, 4, 5), date(2025, 4, 5)},
+		{"2025Q1", date(2025, 1, 1), date(2025, 3, 31)},
+		{"lastmonth", date(2025, 4, 1), date(2025, 4, 30)},
+		{"ytd", date(2025, 1, 1), date(2025, 5, 14)},
+		// A relative date is a single day, unless it is the whole period.
+		{"-30d", date(2025, 4, 14), date(2025, 4, 14)},
+		{"from 2024-01 to 2024-06", date(2024, 1, 1), date(2024, 5, 31)},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			begin, err := Begin(test.expression, today)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if !begin.Equal(test.begin) {
+				t.Errorf("expected the beginning on %v, got %v", test.begin, begin)
+			}
+			end, err := End(test.expression, today)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if !end.Equal(test.end) {
+				t.Errorf("expected the end on %v, got %v", test.end, end)
+			}
+		})
+	}
+
+	t.Run("end of month", func(t *testing.T) {
+		// One month before the 31st of March is the last day of February.
+		begin, err := Begin("-1m", time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if expected := date(2025, 2, 28); !begin.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, begin)
+		}
+	})
+}
+
+func TestDates(t *testing.T) {
+	begin, end := Period{Begin: date(2024, 1, 1)}.Dates()
+	if begin != "2024-01-01" || end != "" {
+		t.Errorf("expected \"2024-01-01\" and an open end, got %q and %q", begin, end)
+	}
+}
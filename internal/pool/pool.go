@@ -0,0 +1,91 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+// Package pool runs the fetches of several commodities concurrently with a bounded number of workers, while giving
+// back their results in the order in which they were asked. The requests made by the workers still go through the
+// rate limiter of the API (see internal.Limiter), which is safe for concurrent use.
+package pool
+
+import "sync"
+
+// result is the outcome of the task of an index.
+type result[T any] struct {
+	index int
+	value T
+	err   error
+}
+
+// Run calls `task` for every index from 0 to `n` excluded, with at most `workers` tasks running at the same time.
+// `done` is called with the result of each index in increasing order, as soon as it and all the previous ones are
+// known, and never concurrently. When `done` returns an error, no new task is started and Run returns that error once
+// the running ones are over. Less than one worker is taken as one.
+func Run[T any](n int, workers int, task func(index int) (T, error), done func(index int, value T, err error) error) error {
+	workers = max(1, min(workers, n))
+
+	indexes := make(chan int)
+	results := make(chan result[T])
+	stop := make(chan struct{})
+
+	// Give the indexes to the workers in order, until all of them are given or `done` fails.
+	go func() {
+		defer close(indexes)
+		for index := range n {
+			select {
+			case indexes <- index:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				value, err := task(index)
+				results <- result[T]{index: index, value: value, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Keep the results that arrive early until the previous ones are known.
+	pending := make(map[int]result[T])
+	next := 0
+	var failure error
+	for r := range results {
+		pending[r.index] = r
+		for failure == nil {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := done(r.index, r.value, r.err); err != nil {
+				failure = err
+				close(stop)
+			}
+		}
+	}
+	return failure
+}
@@ -0,0 +1,124 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("results in order", func(t *testing.T) {
+		// The first tasks are the slowest, so they finish last.
+		var order []int
+		err := Run(5, 5, func(index int) (int, error) {
+			time.Sleep(time.Duration(5-index) * 5 * time.Millisecond)
+			return index * index, nil
+		}, func(index int, value int, err error) error {
+			if err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+			if value != index*index {
+				t.Errorf("expected %d for index %d, got %d", index*index, index, value)
+			}
+			order = append(order, index)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if fmt.Sprint(order) != "[0 1 2 3 4]" {
+			t.Errorf("expected the results in order, got %v", order)
+		}
+	})
+
+	t.Run("bounded workers", func(t *testing.T) {
+		var running, highest atomic.Int32
+		err := Run(10, 3, func(index int) (struct{}, error) {
+			current := running.Add(1)
+			for {
+				seen := highest.Load()
+				if current <= seen || highest.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return struct{}{}, nil
+		}, func(int, struct{}, error) error { return nil })
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if highest.Load() > 3 {
+			t.Errorf("expected at most 3 tasks at the same time, got %d", highest.Load())
+		}
+	})
+
+	t.Run("failed tasks", func(t *testing.T) {
+		failed := errors.New("failed")
+		var errs []error
+		err := Run(4, 2, func(index int) (int, error) {
+			if index%2 == 1 {
+				return 0, failed
+			}
+			return index, nil
+		}, func(index int, value int, err error) error {
+			errs = append(errs, err)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if fmt.Sprint(errs) != "[<nil> failed <nil> failed]" {
+			t.Errorf("expected every task to be reported, got %v", errs)
+		}
+	})
+
+	t.Run("done fails", func(t *testing.T) {
+		failed := errors.New("failed")
+		var started atomic.Int32
+		err := Run(100, 1, func(index int) (int, error) {
+			started.Add(1)
+			return index, nil
+		}, func(index int, value int, err error) error {
+			if index == 1 {
+				return failed
+			}
+			return nil
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("expected %v, got %v", failed, err)
+		}
+		if started.Load() == 100 {
+			t.Error("expected the remaining tasks not to be started")
+		}
+	})
+
+	t.Run("no tasks", func(t *testing.T) {
+		err := Run(0, 4, func(int) (int, error) {
+			t.Error("expected no task to be started")
+			return 0, nil
+		}, func(int, int, error) error { return nil })
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+}
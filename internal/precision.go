@@ -0,0 +1,49 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package internal
+
+import (
+	"strings"
+
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+)
+
+// Precision is the number of decimals of the prices in the output. It is negative when unset, in which case the
+// precisions of the commodities are used.
+var Precision = -1
+
+// Precisions are the number of decimals of the prices of each commodity, from the `precisions` section of the
+// configuration file. The commodities are in lowercase, since Viper lowercases the keys of the maps.
+var Precisions map[string]int
+
+// FormatPrice formats the price of a commodity expressed in a currency. Its number of decimals is, in order of
+// precedence, the one given with `--precision`, the one of the commodity in Precisions, or the one of the currency.
+// Without any of them, the price keeps the digits given by the API, without the trailing zeros but with at least two
+// decimals (e.g. "227.4800" gives "227.48" and "0.00001234" stays as it is).
+func FormatPrice(price decimal.Decimal, commodity string, currency string) string {
+	if Precision >= 0 {
+		return price.Round(int32(Precision)).String()
+	}
+	for _, name := range []string{commodity, currency} {
+		if places, ok := Precisions[strings.ToLower(name)]; ok {
+			return price.Round(int32(places)).String()
+		}
+	}
+	return price.Trim(2).String()
+}
@@ -0,0 +1,93 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package provider
+
+import (
+	cryptoList "github.com/lentidas/hledger-price-tracker/internal/crypto/list"
+	cryptoRate "github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
+	currencyCurrent "github.com/lentidas/hledger-price-tracker/internal/currency/current"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	stockDividends "github.com/lentidas/hledger-price-tracker/internal/stock/dividends"
+	stockPrice "github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	stockQuote "github.com/lentidas/hledger-price-tracker/internal/stock/quote"
+	stockSearch "github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	stockSplits "github.com/lentidas/hledger-price-tracker/internal/stock/splits"
+)
+
+// AlphaVantage is the provider backed by the Alpha Vantage API. The requests themselves are implemented in each of the
+// command packages, this type only puts them together.
+type AlphaVantage struct{}
+
+func init() {
+	Register(AlphaVantage{})
+}
+
+func (AlphaVantage) Name() string {
+	return "alphavantage"
+}
+
+func (AlphaVantage) ExchangeRate(from string, to string, format flags.OutputFormat) (currencyCurrent.Typed, []byte, error) {
+	return currencyCurrent.AlphaVantage{}.ExchangeRate(from, to, format)
+}
+
+func (AlphaVantage) FXSeries(from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (currencyRate.Series, []byte, error) {
+	return currencyRate.AlphaVantage{}.FXSeries(from, to, format, interval, full)
+}
+
+func (AlphaVantage) Currencies() (currencyList.Currencies, []byte, error) {
+	return currencyList.AlphaVantage{}.Currencies()
+}
+
+func (AlphaVantage) CurrencyExists(currencyCode string) (bool, error) {
+	return currencyList.AlphaVantage{}.CurrencyExists(currencyCode)
+}
+
+func (AlphaVantage) CryptoExists(cryptoCode string) (bool, error) {
+	return cryptoList.AlphaVantage{}.CryptoExists(cryptoCode)
+}
+
+func (AlphaVantage) CryptoSeries(from string, to string, interval flags.Interval) (cryptoRate.Series, error) {
+	return cryptoRate.AlphaVantage{}.CryptoSeries(from, to, interval)
+}
+
+func (AlphaVantage) Dividends(symbol string, format flags.OutputFormat) (stockDividends.Typed, []byte, error) {
+	return stockDividends.AlphaVantage{}.Dividends(symbol, format)
+}
+
+func (AlphaVantage) StockSeries(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (stockPrice.Series, []byte, error) {
+	return stockPrice.AlphaVantage{}.StockSeries(symbol, format, interval, adjusted, full)
+}
+
+func (AlphaVantage) StockQuote(symbol string, format flags.OutputFormat) (stockQuote.Typed, []byte, error) {
+	return stockQuote.AlphaVantage{}.StockQuote(symbol, format)
+}
+
+func (AlphaVantage) Splits(symbol string, format flags.OutputFormat) (stockSplits.Typed, []byte, error) {
+	return stockSplits.AlphaVantage{}.Splits(symbol, format)
+}
+
+func (AlphaVantage) SymbolSearch(query string, format flags.OutputFormat) (stockSearch.Typed, []byte, error) {
+	return stockSearch.AlphaVantage{}.SymbolSearch(query, format)
+}
+
+func (AlphaVantage) StockCurrency(symbol string) (string, error) {
+	return stockSearch.AlphaVantage{}.StockCurrency(symbol)
+}
@@ -0,0 +1,205 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cryptoRate "github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
+	currencyCurrent "github.com/lentidas/hledger-price-tracker/internal/currency/current"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	stockDividends "github.com/lentidas/hledger-price-tracker/internal/stock/dividends"
+	stockPrice "github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	stockQuote "github.com/lentidas/hledger-price-tracker/internal/stock/quote"
+	stockSearch "github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	stockSplits "github.com/lentidas/hledger-price-tracker/internal/stock/splits"
+)
+
+// chain is a list of providers that are tried in order until one of them succeeds.
+// When all of them fail, the errors of each provider are returned together.
+type chain []Provider
+
+func (c chain) Name() string {
+	names := make([]string, len(c))
+	for i, provider := range c {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// wrap prefixes the error of a provider with its name, so the user knows which one failed.
+func wrap(provider Provider, err error) error {
+	return fmt.Errorf("%s: %w", provider.Name(), err)
+}
+
+func (c chain) ExchangeRate(from string, to string, format flags.OutputFormat) (currencyCurrent.Typed, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		typed, body, err := provider.ExchangeRate(from, to, format)
+		if err == nil {
+			return typed, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return currencyCurrent.Typed{}, nil, errors.Join(errs...)
+}
+
+func (c chain) FXSeries(from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (currencyRate.Series, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		series, body, err := provider.FXSeries(from, to, format, interval, full)
+		if err == nil {
+			return series, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return currencyRate.Series{}, nil, errors.Join(errs...)
+}
+
+func (c chain) Currencies() (currencyList.Currencies, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		currencies, body, err := provider.Currencies()
+		if err == nil {
+			return currencies, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return nil, nil, errors.Join(errs...)
+}
+
+// exists asks each provider whether it supports a currency, and reports whether one of them does. The errors are only
+// returned when no provider could answer.
+func (c chain) exists(supports func(provider Provider) (bool, error)) (bool, error) {
+	var errs []error
+	for _, provider := range c {
+		exists, err := supports(provider)
+		if err != nil {
+			errs = append(errs, wrap(provider, err))
+			continue
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	if len(errs) == len(c) {
+		return false, errors.Join(errs...)
+	}
+	return false, nil
+}
+
+func (c chain) CurrencyExists(currencyCode string) (bool, error) {
+	return c.exists(func(provider Provider) (bool, error) {
+		return provider.CurrencyExists(currencyCode)
+	})
+}
+
+func (c chain) CryptoExists(cryptoCode string) (bool, error) {
+	return c.exists(func(provider Provider) (bool, error) {
+		return provider.CryptoExists(cryptoCode)
+	})
+}
+
+func (c chain) CryptoSeries(from string, to string, interval flags.Interval) (cryptoRate.Series, error) {
+	var errs []error
+	for _, provider := range c {
+		series, err := provider.CryptoSeries(from, to, interval)
+		if err == nil {
+			return series, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return cryptoRate.Series{}, errors.Join(errs...)
+}
+
+func (c chain) Dividends(symbol string, format flags.OutputFormat) (stockDividends.Typed, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		typed, body, err := provider.Dividends(symbol, format)
+		if err == nil {
+			return typed, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return stockDividends.Typed{}, nil, errors.Join(errs...)
+}
+
+func (c chain) StockSeries(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (stockPrice.Series, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		series, body, err := provider.StockSeries(symbol, format, interval, adjusted, full)
+		if err == nil {
+			return series, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return stockPrice.Series{}, nil, errors.Join(errs...)
+}
+
+func (c chain) StockQuote(symbol string, format flags.OutputFormat) (stockQuote.Typed, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		typed, body, err := provider.StockQuote(symbol, format)
+		if err == nil {
+			return typed, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return stockQuote.Typed{}, nil, errors.Join(errs...)
+}
+
+func (c chain) Splits(symbol string, format flags.OutputFormat) (stockSplits.Typed, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		typed, body, err := provider.Splits(symbol, format)
+		if err == nil {
+			return typed, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return stockSplits.Typed{}, nil, errors.Join(errs...)
+}
+
+func (c chain) SymbolSearch(query string, format flags.OutputFormat) (stockSearch.Typed, []byte, error) {
+	var errs []error
+	for _, provider := range c {
+		typed, body, err := provider.SymbolSearch(query, format)
+		if err == nil {
+			return typed, body, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return stockSearch.Typed{}, nil, errors.Join(errs...)
+}
+
+func (c chain) StockCurrency(symbol string) (string, error) {
+	var errs []error
+	for _, provider := range c {
+		currency, err := provider.StockCurrency(symbol)
+		if err == nil {
+			return currency, nil
+		}
+		errs = append(errs, wrap(provider, err))
+	}
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,495 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	currencyCurrent "github.com/lentidas/hledger-price-tracker/internal/currency/current"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+)
+
+const (
+	ecbBaseCurrency = "EUR"
+	ecbTimeZone     = "CET"
+	ecbInformation  = "Euro foreign exchange reference rates"
+
+	ecbUrlDaily       = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	ecbUrlHistory90d  = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+	ecbUrlHistoryFull = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.zip"
+)
+
+// ECBFile is the path to a file previously downloaded from the ECB website (XML, CSV or ZIP). When set, the ECB provider
+// parses it instead of downloading the reference rates.
+var ECBFile string
+
+// ecbCurrencyNames contains the names of the currencies published by the ECB, since the files only contain their codes.
+var ecbCurrencyNames = map[string]string{
+	"AUD": "Australian Dollar",
+	"BGN": "Bulgarian Lev",
+	"BRL": "Brazilian Real",
+	"CAD": "Canadian Dollar",
+	"CHF": "Swiss Franc",
+	"CNY": "Chinese Yuan",
+	"CZK": "Czech Koruna",
+	"DKK": "Danish Krone",
+	"EUR": "Euro",
+	"GBP": "British Pound Sterling",
+	"HKD": "Hong Kong Dollar",
+	"HUF": "Hungarian Forint",
+	"IDR": "Indonesian Rupiah",
+	"ILS": "Israeli New Sheqel",
+	"INR": "Indian Rupee",
+	"ISK": "Icelandic Krona",
+	"JPY": "Japanese Yen",
+	"KRW": "South Korean Won",
+	"MXN": "Mexican Peso",
+	"MYR": "Malaysian Ringgit",
+	"NOK": "Norwegian Krone",
+	"NZD": "New Zealand Dollar",
+	"PHP": "Philippine Peso",
+	"PLN": "Polish Zloty",
+	"RON": "Romanian Leu",
+	"SEK": "Swedish Krona",
+	"SGD": "Singapore Dollar",
+	"THB": "Thai Baht",
+	"TRY": "Turkish Lira",
+	"USD": "United States Dollar",
+	"ZAR": "South African Rand",
+}
+
+// ecbRates maps each date to the rates of every currency against the euro on that date.
+type ecbRates map[time.Time]map[string]decimal.Decimal
+
+// ECB is the provider backed by the euro foreign exchange reference rates published daily by the European Central Bank.
+// It does not need an API key, but only knows about the currencies the ECB publishes and nothing about stocks.
+// Since all rates are given against the euro, the rate between two other currencies is computed through the euro.
+type ECB struct {
+	Unsupported
+}
+
+func init() {
+	Register(ECB{})
+}
+
+func (ECB) Name() string {
+	return "ecb"
+}
+
+// ecbXML is the structure of the XML files published by the ECB. The namespaces are ignored.
+type ecbXML struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// parseECBXML parses the eurofxref XML files (daily, last 90 days or entire history).
+func parseECBXML(body []byte) (ecbRates, error) {
+	var raw ecbXML
+	err := xml.Unmarshal(body, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("[provider.parseECBXML] failure to unmarshal XML body: %w", err)
+	}
+
+	rates := make(ecbRates, len(raw.Cube.Days))
+	for _, day := range raw.Cube.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("[provider.parseECBXML] error parsing date: %w", err)
+		}
+
+		rates[date] = make(map[string]decimal.Decimal, len(day.Rates))
+		for _, rate := range day.Rates {
+			value, err := decimal.Parse(rate.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("[provider.parseECBXML] error parsing rate of %s: %w", rate.Currency, err)
+			}
+			rates[date][rate.Currency] = value
+		}
+	}
+
+	return rates, nil
+}
+
+// parseECBCSV parses the eurofxref CSV files. The daily file uses dates like "04 April 2025" and pads every field with
+// a space, while the history file uses ISO dates and "N/A" for currencies that were not quoted on a certain date.
+func parseECBCSV(body []byte) (ecbRates, error) {
+	csvReader := csv.NewReader(bytes.NewReader(body))
+	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+	data, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("[provider.parseECBCSV] failure to read CSV data: %w", err)
+	}
+	if len(data) < 1 {
+		return nil, errors.New("[provider.parseECBCSV] empty CSV data")
+	}
+
+	header := data[0]
+	rates := make(ecbRates, len(data)-1)
+	for _, line := range data[1:] {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(line[0]))
+		if err != nil {
+			date, err = time.Parse("02 January 2006", strings.TrimSpace(line[0]))
+			if err != nil {
+				return nil, fmt.Errorf("[provider.parseECBCSV] error parsing date: %w", err)
+			}
+		}
+
+		rates[date] = make(map[string]decimal.Decimal, len(line)-1)
+		for i := 1; i < len(line) && i < len(header); i++ {
+			currency := strings.TrimSpace(header[i])
+			field := strings.TrimSpace(line[i])
+			if currency == "" || field == "" || field == "N/A" {
+				continue
+			}
+			value, err := decimal.Parse(field)
+			if err != nil {
+				return nil, fmt.Errorf("[provider.parseECBCSV] error parsing rate of %s: %w", currency, err)
+			}
+			rates[date][currency] = value
+		}
+	}
+
+	return rates, nil
+}
+
+// parseECBZip extracts the CSV file from the eurofxref ZIP archives and parses it.
+func parseECBZip(body []byte) (ecbRates, error) {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("[provider.parseECBZip] failure to open ZIP archive: %w", err)
+	}
+
+	for _, file := range archive.File {
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".csv") {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("[provider.parseECBZip] failure to open %s: %w", file.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("[provider.parseECBZip] failure to read %s: %w", file.Name, err)
+		}
+
+		return parseECBCSV(content)
+	}
+
+	return nil, errors.New("[provider.parseECBZip] no CSV file found in ZIP archive")
+}
+
+// parseECB detects the format of a file published by the ECB and parses it.
+func parseECB(body []byte) (ecbRates, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("PK")):
+		return parseECBZip(body)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseECBXML(trimmed)
+	default:
+		return parseECBCSV(trimmed)
+	}
+}
+
+// loadECB returns the reference rates, either from the file given by the user or downloaded from the given URL.
+func loadECB(url string) (ecbRates, error) {
+	var body []byte
+	var err error
+
+	if ECBFile != "" {
+		body, err = os.ReadFile(ECBFile)
+		if err != nil {
+			return nil, fmt.Errorf("[provider.loadECB] failure to read file: %w", err)
+		}
+	} else {
+		body, err = internal.HTTPRequest(url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parseECB(body)
+}
+
+// rate returns the rate between two currencies on a given date, computed through the euro if needed.
+// The boolean is false when one of the currencies was not quoted on that date.
+func (rates ecbRates) rate(date time.Time, from string, to string) (decimal.Decimal, bool) {
+	day := rates[date]
+	fromRate, toRate := decimal.New(1, 0), decimal.New(1, 0)
+	var ok bool
+
+	if from != ecbBaseCurrency {
+		if fromRate, ok = day[from]; !ok || fromRate.IsZero() {
+			return decimal.Decimal{}, false
+		}
+	}
+	if to != ecbBaseCurrency {
+		if toRate, ok = day[to]; !ok {
+			return decimal.Decimal{}, false
+		}
+	}
+
+	return toRate.Div(fromRate), true
+}
+
+// dates returns all the dates with rates, sorted chronologically.
+func (rates ecbRates) dates() []time.Time {
+	dates := make([]time.Time, 0, len(rates))
+	for date := range rates {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates
+}
+
+// validate checks that both currencies are published by the ECB.
+func (rates ecbRates) validate(from string, to string) error {
+	known := map[string]bool{ecbBaseCurrency: true}
+	for _, day := range rates {
+		for currency := range day {
+			known[currency] = true
+		}
+	}
+
+	if !known[from] {
+		return fmt.Errorf("[provider.(ECB).validate] %w: from currency %s is not published by the ECB", internal.ErrInvalidCurrency, from)
+	}
+	if !known[to] {
+		return fmt.Errorf("[provider.(ECB).validate] %w: to currency %s is not published by the ECB", internal.ErrInvalidCurrency, to)
+	}
+	if from == to {
+		return errors.New("[provider.(ECB).validate] from and to currencies must be different")
+	}
+	return nil
+}
+
+// periodKey returns a value that is the same for all the dates in the same interval.
+func periodKey(date time.Time, interval flags.Interval) (string, error) {
+	switch interval {
+	case flags.IntervalDaily:
+		return date.Format("2006-01-02"), nil
+	case flags.IntervalWeekly:
+		year, week := date.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	case flags.IntervalMonthly:
+		return date.Format("2006-01"), nil
+	default:
+		return "", errors.New("[provider.periodKey] invalid interval")
+	}
+}
+
+// series aggregates the daily rates into the given interval. Like the Alpha Vantage API, each point is dated with the
+// last day of the interval that has a rate, and the open, high, low, and close are computed from the daily rates.
+func (rates ecbRates) series(from string, to string, interval flags.Interval) (currencyRate.Series, error) {
+	series := currencyRate.Series{
+		MetaData: currencyRate.TypedMetadata{
+			Information: ecbInformation,
+			FromSymbol:  from,
+			ToSymbol:    to,
+			TimeZone:    ecbTimeZone,
+		},
+		Source:     ECB{}.Name(),
+		Fetched:    time.Now(),
+		TimeSeries: make(map[time.Time]currencyRate.TypedPrices),
+	}
+
+	var currentKey string
+	var currentDate time.Time
+	var current currencyRate.TypedPrices
+	for _, date := range rates.dates() {
+		value, ok := rates.rate(date, from, to)
+		if !ok {
+			continue
+		}
+
+		key, err := periodKey(date, interval)
+		if err != nil {
+			return currencyRate.Series{}, err
+		}
+
+		if key != currentKey {
+			if currentKey != "" {
+				series.TimeSeries[currentDate] = current
+			}
+			currentKey = key
+			current = currencyRate.TypedPrices{Open: value, High: value, Low: value, Close: value}
+		} else {
+			if value.Cmp(current.High) > 0 {
+				current.High = value
+			}
+			if value.Cmp(current.Low) < 0 {
+				current.Low = value
+			}
+			current.Close = value
+		}
+		currentDate = date
+	}
+	if currentKey != "" {
+		series.TimeSeries[currentDate] = current
+		series.MetaData.LastRefreshed = currentDate
+	}
+
+	return series, nil
+}
+
+// FXSeries returns the reference rates between two currencies. Without `full`, only the last 90 days are downloaded.
+func (ECB) FXSeries(from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (currencyRate.Series, []byte, error) {
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return currencyRate.Series{}, nil, fmt.Errorf("[provider.(ECB).FXSeries] %s output format: %w", format, ErrNotSupported)
+	}
+	// The reference rates are published once a day.
+	if interval.Intraday() {
+		return currencyRate.Series{}, nil, fmt.Errorf("[provider.(ECB).FXSeries] %s interval: %w", interval, ErrNotSupported)
+	}
+
+	url := ecbUrlHistory90d
+	if full {
+		url = ecbUrlHistoryFull
+	}
+
+	rates, err := loadECB(url)
+	if err != nil {
+		return currencyRate.Series{}, nil, err
+	}
+	if err := rates.validate(from, to); err != nil {
+		return currencyRate.Series{}, nil, err
+	}
+
+	series, err := rates.series(from, to, interval)
+	if err != nil {
+		return currencyRate.Series{}, nil, err
+	}
+
+	return series, nil, nil
+}
+
+// ExchangeRate returns the latest reference rate between two currencies.
+// The ECB does not publish bid and ask prices, so both are set to the reference rate.
+func (ECB) ExchangeRate(from string, to string, format flags.OutputFormat) (currencyCurrent.Typed, []byte, error) {
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return currencyCurrent.Typed{}, nil, fmt.Errorf("[provider.(ECB).ExchangeRate] %s output format: %w", format, ErrNotSupported)
+	}
+
+	rates, err := loadECB(ecbUrlDaily)
+	if err != nil {
+		return currencyCurrent.Typed{}, nil, err
+	}
+	if err := rates.validate(from, to); err != nil {
+		return currencyCurrent.Typed{}, nil, err
+	}
+
+	dates := rates.dates()
+	for i := len(dates) - 1; i >= 0; i-- {
+		value, ok := rates.rate(dates[i], from, to)
+		if !ok {
+			continue
+		}
+
+		return currencyCurrent.Typed{
+			FromCurrencyCode: from,
+			FromCurrencyName: ecbCurrencyNames[from],
+			ToCurrencyCode:   to,
+			ToCurrencyName:   ecbCurrencyNames[to],
+			ExchangeRate:     value,
+			LastRefreshed:    dates[i],
+			TimeZone:         ecbTimeZone,
+			BidPrice:         value,
+			AskPrice:         value,
+			Source:           ECB{}.Name(),
+			Fetched:          time.Now(),
+		}, nil, nil
+	}
+
+	return currencyCurrent.Typed{}, nil, errors.New("[provider.(ECB).ExchangeRate] no rate found between the two currencies")
+}
+
+// Currencies returns the currencies present in the latest reference rates. The CSV body is generated in the same
+// format as the list of physical currencies of Alpha Vantage.
+func (ECB) Currencies() (currencyList.Currencies, []byte, error) {
+	rates, err := loadECB(ecbUrlDaily)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currencies := currencyList.Currencies{ecbBaseCurrency: ecbCurrencyNames[ecbBaseCurrency]}
+	for _, day := range rates {
+		for code := range day {
+			currencies[code] = ecbCurrencyNames[code]
+		}
+	}
+
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	body := bytes.Buffer{}
+	writer := csv.NewWriter(&body)
+	_ = writer.Write([]string{"currency code", "currency name"})
+	for _, code := range codes {
+		_ = writer.Write([]string{code, currencies[code]})
+	}
+	writer.Flush()
+
+	return currencies, body.Bytes(), nil
+}
+
+// CurrencyExists reports whether a currency is published by the ECB.
+func (p ECB) CurrencyExists(currencyCode string) (bool, error) {
+	currencies, _, err := p.Currencies()
+	if err != nil {
+		return false, err
+	}
+
+	_, exists := currencies[currencyCode]
+
+	return exists, nil
+}
+
+// CryptoExists always reports false, since the ECB does not publish any digital currency.
+func (ECB) CryptoExists(string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,258 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+)
+
+const ecbTestXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2025-04-04">
+			<Cube currency="USD" rate="1.1011"/>
+			<Cube currency="JPY" rate="161.02"/>
+		</Cube>
+		<Cube time="2025-04-03">
+			<Cube currency="USD" rate="1.1057"/>
+			<Cube currency="JPY" rate="162.11"/>
+		</Cube>
+		<Cube time="2025-04-02">
+			<Cube currency="USD" rate="1.0830"/>
+			<Cube currency="JPY" rate="161.95"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+const ecbTestCSVHistory = `Date,USD,JPY,CYP,
+2025-04-04,1.1011,161.02,N/A,
+2025-04-03,1.1057,162.11,N/A,
+2025-04-02,1.0830,161.95,N/A,
+`
+
+const ecbTestCSVDaily = `Date, USD, JPY, 
+04 April 2025, 1.1011, 161.02, 
+`
+
+func TestParseECB(t *testing.T) {
+	t.Run("XML", func(t *testing.T) {
+		rates, err := parseECB([]byte(ecbTestXML))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(rates) != 3 {
+			t.Fatalf("expected 3 dates, got %d", len(rates))
+		}
+		if rate := rates[time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC)]["JPY"]; rate.String() != "162.11" {
+			t.Errorf("expected 162.11, got %s", rate)
+		}
+	})
+
+	t.Run("CSV history", func(t *testing.T) {
+		rates, err := parseECB([]byte(ecbTestCSVHistory))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		day := rates[time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)]
+		if day["USD"].String() != "1.0830" {
+			t.Errorf("expected 1.0830, got %s", day["USD"])
+		}
+		if _, ok := day["CYP"]; ok {
+			t.Error("expected N/A rates to be skipped")
+		}
+	})
+
+	t.Run("CSV daily", func(t *testing.T) {
+		rates, err := parseECB([]byte(ecbTestCSVDaily))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if rate := rates[time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)]["USD"]; rate.String() != "1.1011" {
+			t.Errorf("expected 1.1011, got %s", rate)
+		}
+	})
+
+	t.Run("ZIP", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		archive := zip.NewWriter(&buffer)
+		file, err := archive.Create("eurofxref-hist.csv")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		file.Write([]byte(ecbTestCSVHistory))
+		archive.Close()
+
+		rates, err := parseECB(buffer.Bytes())
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(rates) != 3 {
+			t.Errorf("expected 3 dates, got %d", len(rates))
+		}
+	})
+
+	t.Run("malformed XML", func(t *testing.T) {
+		if _, err := parseECB([]byte(`<Envelope><Cube><Cube time="yesterday"></Cube></Cube></Envelope>`)); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestECBSeries(t *testing.T) {
+	rates, err := parseECB([]byte(ecbTestXML))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	t.Run("daily from EUR", func(t *testing.T) {
+		series, err := rates.series("EUR", "USD", flags.IntervalDaily)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(series.TimeSeries) != 3 {
+			t.Fatalf("expected 3 points, got %d", len(series.TimeSeries))
+		}
+		if price := series.TimeSeries[time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)]; price.Close.String() != "1.1011" {
+			t.Errorf("expected 1.1011, got %s", price.Close)
+		}
+	})
+
+	t.Run("daily to EUR", func(t *testing.T) {
+		series, err := rates.series("USD", "EUR", flags.IntervalDaily)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if price := series.TimeSeries[time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)]; price.Close.String() != "0.9081827263645445" {
+			t.Errorf("expected 0.9081827263645445, got %s", price.Close)
+		}
+	})
+
+	t.Run("cross rate through EUR", func(t *testing.T) {
+		series, err := rates.series("USD", "JPY", flags.IntervalDaily)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if price := series.TimeSeries[time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)]; price.Close.String() != "146.2355825992189629" {
+			t.Errorf("expected 146.2355825992189629, got %s", price.Close)
+		}
+	})
+
+	t.Run("weekly", func(t *testing.T) {
+		series, err := rates.series("EUR", "USD", flags.IntervalWeekly)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		expected := currencyRate.TypedPrices{
+			Open:  decimal.MustParse("1.0830"),
+			High:  decimal.MustParse("1.1057"),
+			Low:   decimal.MustParse("1.0830"),
+			Close: decimal.MustParse("1.1011"),
+		}
+		if len(series.TimeSeries) != 1 {
+			t.Fatalf("expected 1 point, got %d", len(series.TimeSeries))
+		}
+		if price := series.TimeSeries[time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)]; !reflect.DeepEqual(price, expected) {
+			t.Errorf("expected %v, got %v", expected, price)
+		}
+		if !series.MetaData.LastRefreshed.Equal(time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected last refreshed 2025-04-04, got %s", series.MetaData.LastRefreshed)
+		}
+	})
+
+	t.Run("unknown currency", func(t *testing.T) {
+		if err := rates.validate("EUR", "XAF"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestECBFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eurofxref-hist.xml")
+	if err := os.WriteFile(path, []byte(ecbTestXML), 0600); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	ECBFile = path
+	defer func() { ECBFile = "" }()
+
+	t.Run("exchange rate", func(t *testing.T) {
+		typed, _, err := ECB{}.ExchangeRate("EUR", "JPY", flags.OutputFormatHledger)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if typed.ExchangeRate.String() != "161.02" {
+			t.Errorf("expected 161.02, got %s", typed.ExchangeRate)
+		}
+	})
+
+	t.Run("currency exists", func(t *testing.T) {
+		exists, err := ECB{}.CurrencyExists("JPY")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !exists {
+			t.Error("expected true, got false")
+		}
+
+		exists, err = ECB{}.CurrencyExists("BTC")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if exists {
+			t.Error("expected false, got true")
+		}
+	})
+
+	t.Run("series", func(t *testing.T) {
+		output, err := currencyRate.Execute(ECB{}, "EUR", "USD", "", "", flags.OutputFormatHledger, flags.IntervalDaily, "2025-04-03", "", false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		expected := "P 2025-04-03 EUR 1.1057 USD\nP 2025-04-04 EUR 1.1011 USD\n"
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("raw output format", func(t *testing.T) {
+		if _, _, err := (ECB{}).FXSeries("EUR", "USD", flags.OutputFormatRawJSON, flags.IntervalDaily, false); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("intraday interval", func(t *testing.T) {
+		if _, _, err := (ECB{}).FXSeries("EUR", "USD", flags.OutputFormatHledger, flags.Interval5Min, false); !errors.Is(err, ErrNotSupported) {
+			t.Errorf("expected %v, got %v", ErrNotSupported, err)
+		}
+	})
+}
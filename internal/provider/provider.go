@@ -0,0 +1,158 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	cryptoList "github.com/lentidas/hledger-price-tracker/internal/crypto/list"
+	cryptoRate "github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
+	currencyCurrent "github.com/lentidas/hledger-price-tracker/internal/currency/current"
+	currencyList "github.com/lentidas/hledger-price-tracker/internal/currency/list"
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	stockDividends "github.com/lentidas/hledger-price-tracker/internal/stock/dividends"
+	stockPrice "github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	stockQuote "github.com/lentidas/hledger-price-tracker/internal/stock/quote"
+	stockSearch "github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	stockSplits "github.com/lentidas/hledger-price-tracker/internal/stock/splits"
+)
+
+// DefaultName is the name of the provider used when none is given with the `--provider` flag.
+const DefaultName = "alphavantage"
+
+// ErrNotSupported is returned by a provider when it cannot serve a certain kind of request
+// (e.g. a provider of exchange rates that knows nothing about stocks).
+var ErrNotSupported = errors.New("operation not supported by this provider")
+
+// Provider is a source of market prices. It groups the interfaces that each command package expects, so a single
+// value can be handed to any of them.
+type Provider interface {
+	Name() string
+	cryptoList.Provider
+	cryptoRate.Provider
+	currencyCurrent.Provider
+	currencyRate.Provider
+	currencyList.Provider
+	stockDividends.Provider
+	stockPrice.Provider
+	stockQuote.Provider
+	stockSearch.Provider
+	stockSplits.Provider
+}
+
+var registry = make(map[string]Provider)
+
+// Register makes a provider available under its name. It is meant to be called from the init function of the file
+// implementing the provider.
+func Register(provider Provider) {
+	registry[provider.Name()] = provider
+}
+
+// Names returns the names of all registered providers, sorted alphabetically.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the provider registered with the given name. A comma-separated list of names returns a provider that
+// tries each of them in order and uses the first one that succeeds, which allows combining several sources.
+func Get(names string) (Provider, error) {
+	var providers chain
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		provider, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("[provider.Get] unknown provider %q (possible values are %q)", name, strings.Join(Names(), "\", \""))
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return providers, nil
+}
+
+// Selected returns the provider chosen by the user through the `--provider` flag or the `provider` configuration key.
+func Selected() (Provider, error) {
+	if internal.ProviderName == "" {
+		return Get(DefaultName)
+	}
+	return Get(internal.ProviderName)
+}
+
+// Unsupported can be embedded in a provider to answer ErrNotSupported to every request it does not override.
+type Unsupported struct{}
+
+func (Unsupported) ExchangeRate(string, string, flags.OutputFormat) (currencyCurrent.Typed, []byte, error) {
+	return currencyCurrent.Typed{}, nil, ErrNotSupported
+}
+
+func (Unsupported) FXSeries(string, string, flags.OutputFormat, flags.Interval, bool) (currencyRate.Series, []byte, error) {
+	return currencyRate.Series{}, nil, ErrNotSupported
+}
+
+func (Unsupported) Currencies() (currencyList.Currencies, []byte, error) {
+	return nil, nil, ErrNotSupported
+}
+
+func (Unsupported) CurrencyExists(string) (bool, error) {
+	return false, ErrNotSupported
+}
+
+func (Unsupported) CryptoExists(string) (bool, error) {
+	return false, ErrNotSupported
+}
+
+func (Unsupported) CryptoSeries(string, string, flags.Interval) (cryptoRate.Series, error) {
+	return cryptoRate.Series{}, ErrNotSupported
+}
+
+func (Unsupported) Dividends(string, flags.OutputFormat) (stockDividends.Typed, []byte, error) {
+	return stockDividends.Typed{}, nil, ErrNotSupported
+}
+
+func (Unsupported) StockSeries(string, flags.OutputFormat, flags.Interval, bool, bool) (stockPrice.Series, []byte, error) {
+	return stockPrice.Series{}, nil, ErrNotSupported
+}
+
+func (Unsupported) StockQuote(string, flags.OutputFormat) (stockQuote.Typed, []byte, error) {
+	return stockQuote.Typed{}, nil, ErrNotSupported
+}
+
+func (Unsupported) Splits(string, flags.OutputFormat) (stockSplits.Typed, []byte, error) {
+	return stockSplits.Typed{}, nil, ErrNotSupported
+}
+
+func (Unsupported) SymbolSearch(string, flags.OutputFormat) (stockSearch.Typed, []byte, error) {
+	return stockSearch.Typed{}, nil, ErrNotSupported
+}
+
+func (Unsupported) StockCurrency(string) (string, error) {
+	return "", ErrNotSupported
+}
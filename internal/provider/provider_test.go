@@ -0,0 +1,138 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package provider
+
+import (
+	"errors"
+	"testing"
+
+	currencyCurrent "github.com/lentidas/hledger-price-tracker/internal/currency/current"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+)
+
+// fake is a provider that only knows a single exchange rate.
+type fake struct {
+	Unsupported
+	name string
+	rate decimal.Decimal
+}
+
+func (f fake) Name() string {
+	return f.name
+}
+
+func (f fake) ExchangeRate(from string, to string, _ flags.OutputFormat) (currencyCurrent.Typed, []byte, error) {
+	return currencyCurrent.Typed{FromCurrencyCode: from, ToCurrencyCode: to, ExchangeRate: f.rate}, nil, nil
+}
+
+func (f fake) CurrencyExists(currencyCode string) (bool, error) {
+	return currencyCode == "EUR" || currencyCode == "USD", nil
+}
+
+func TestGet(t *testing.T) {
+	t.Run("default provider", func(t *testing.T) {
+		provider, err := Get(DefaultName)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if provider.Name() != "alphavantage" {
+			t.Errorf("expected alphavantage, got %s", provider.Name())
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		if _, err := Get("invalid"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("unknown provider in chain", func(t *testing.T) {
+		if _, err := Get("alphavantage,invalid"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		provider, err := Get("alphavantage, alphavantage")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if provider.Name() != "alphavantage,alphavantage" {
+			t.Errorf("expected alphavantage,alphavantage, got %s", provider.Name())
+		}
+	})
+}
+
+func TestChain(t *testing.T) {
+	providers := chain{empty{}, fake{name: "fake", rate: decimal.MustParse("1.5")}}
+
+	t.Run("falls back to the next provider", func(t *testing.T) {
+		typed, _, err := providers.ExchangeRate("EUR", "USD", flags.OutputFormatHledger)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if typed.ExchangeRate.String() != "1.5" {
+			t.Errorf("expected 1.5, got %s", typed.ExchangeRate)
+		}
+	})
+
+	t.Run("all providers fail", func(t *testing.T) {
+		_, _, err := providers.FXSeries("EUR", "USD", flags.OutputFormatHledger, flags.IntervalDaily, false)
+		if !errors.Is(err, ErrNotSupported) {
+			t.Errorf("expected ErrNotSupported, got %v", err)
+		}
+	})
+
+	t.Run("currency known by one provider", func(t *testing.T) {
+		exists, err := providers.CurrencyExists("EUR")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !exists {
+			t.Error("expected true, got false")
+		}
+	})
+
+	t.Run("currency unknown by every provider", func(t *testing.T) {
+		exists, err := providers.CurrencyExists("XYZ")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if exists {
+			t.Error("expected false, got true")
+		}
+	})
+
+	t.Run("no provider checks the currency", func(t *testing.T) {
+		_, err := providers.CryptoExists("BTC")
+		if !errors.Is(err, ErrNotSupported) {
+			t.Errorf("expected ErrNotSupported, got %v", err)
+		}
+	})
+}
+
+// empty is a provider that does not support anything at all.
+type empty struct {
+	Unsupported
+}
+
+func (empty) Name() string {
+	return "empty"
+}
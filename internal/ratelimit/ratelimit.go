@@ -0,0 +1,256 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDailyBudgetExceeded is returned before making a request that would exceed the number of requests per day.
+var ErrDailyBudgetExceeded = errors.New("daily budget of API requests exhausted")
+
+const (
+	// lockPoll is the interval between two attempts to take the lock of the usage file.
+	lockPoll = 10 * time.Millisecond
+	// lockTimeout is how long to wait for the lock of the usage file before giving up.
+	lockTimeout = 30 * time.Second
+	// lockStale is the age after which a lock is considered left over by a process that crashed.
+	lockStale = time.Minute
+)
+
+// Plan describes the limits of an Alpha Vantage subscription. A limit of zero means there is no limit.
+type Plan struct {
+	PerMinute int
+	PerDay    int
+}
+
+// Plans are the subscriptions offered by Alpha Vantage (see https://www.alphavantage.co/premium/).
+var Plans = map[string]Plan{
+	"free":         {PerMinute: 5, PerDay: 25},
+	"premium-75":   {PerMinute: 75},
+	"premium-150":  {PerMinute: 150},
+	"premium-300":  {PerMinute: 300},
+	"premium-600":  {PerMinute: 600},
+	"premium-1200": {PerMinute: 1200},
+}
+
+// PlanNames returns the names of all the plans, sorted from the lowest to the highest limit per minute.
+func PlanNames() []string {
+	names := make([]string, 0, len(Plans))
+	for name := range Plans {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return Plans[names[i]].PerMinute < Plans[names[j]].PerMinute
+	})
+	return names
+}
+
+// Usage is the number of requests made on a certain day. It is persisted between runs of the program.
+type Usage struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// Limiter is a token bucket that spaces out the requests to respect the limit per minute, combined with a persisted
+// counter of the requests made today to respect the limit per day. It is safe for concurrent use.
+type Limiter struct {
+	mu sync.Mutex
+
+	perDay    int
+	usagePath string
+
+	// The bucket holds at most `capacity` tokens and is refilled at `rate` tokens per second.
+	// Alpha Vantage also refuses bursts of more than one request per second, so the capacity is always 1.
+	capacity float64
+	rate     float64
+	tokens   float64
+	last     time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// New creates a limiter for the given limits. The usage of the day is read from and written to `usagePath`.
+func New(perMinute int, perDay int, usagePath string) *Limiter {
+	limiter := &Limiter{
+		perDay:    perDay,
+		usagePath: usagePath,
+		capacity:  1,
+		tokens:    1,
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}
+	if perMinute > 0 {
+		limiter.rate = float64(perMinute) / 60
+	}
+	limiter.last = limiter.now()
+	return limiter
+}
+
+// UsagePath returns the default path of the file where the usage of the day is stored, which is
+// $XDG_STATE_HOME/hledger-price-tracker/usage.json (or ~/.local/state if the variable is not set).
+func UsagePath() (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("[ratelimit.UsagePath] failure to find the home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "hledger-price-tracker", "usage.json"), nil
+}
+
+// today returns the current day. Alpha Vantage does not document when the daily limit is reset, so UTC is used.
+func (l *Limiter) today() string {
+	return l.now().UTC().Format("2006-01-02")
+}
+
+// lock takes an exclusive lock on the usage file, shared by all the running instances of the program, so that their
+// read-modify-write cycles on the usage of the day do not overwrite each other. The returned function releases it.
+func (l *Limiter) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(l.usagePath), 0700); err != nil {
+		return nil, fmt.Errorf("[ratelimit.(*Limiter).lock] failure to create state directory: %w", err)
+	}
+
+	path := l.usagePath + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = file.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("[ratelimit.(*Limiter).lock] failure to create lock file: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			// The process holding the lock never released it.
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("[ratelimit.(*Limiter).lock] timed out waiting for %s, remove it if no other instance is running", path)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// readUsage returns the usage of today, which is empty when the stored usage is from another day.
+func (l *Limiter) readUsage() (Usage, error) {
+	usage := Usage{Date: l.today()}
+
+	content, err := os.ReadFile(l.usagePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return usage, nil
+	} else if err != nil {
+		return usage, fmt.Errorf("[ratelimit.(*Limiter).readUsage] failure to read usage file: %w", err)
+	}
+
+	var stored Usage
+	if err := json.Unmarshal(content, &stored); err != nil || stored.Date != usage.Date {
+		// A corrupted file or a file from another day is simply reset.
+		return usage, nil
+	}
+
+	return stored, nil
+}
+
+// writeUsage stores the usage of the day. The caller must hold the lock of the usage file.
+func (l *Limiter) writeUsage(usage Usage) error {
+	content, err := json.Marshal(usage)
+	if err != nil {
+		return fmt.Errorf("[ratelimit.(*Limiter).writeUsage] failure to marshal usage: %w", err)
+	}
+
+	if err := os.WriteFile(l.usagePath, content, 0600); err != nil {
+		return fmt.Errorf("[ratelimit.(*Limiter).writeUsage] failure to write usage file: %w", err)
+	}
+
+	return nil
+}
+
+// Usage returns the number of requests made today and the limit per day (zero if there is none).
+func (l *Limiter) Usage() (Usage, int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage, err := l.readUsage()
+	return usage, l.perDay, err
+}
+
+// Acquire blocks until a request can be made without exceeding the limit per minute and records it in the usage of
+// the day. It returns ErrDailyBudgetExceeded, without waiting, if the request would exceed the limit per day.
+func (l *Limiter) Acquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.record(); err != nil {
+		return err
+	}
+
+	if l.rate > 0 {
+		// Refill the bucket with the tokens accumulated since the last request.
+		now := l.now()
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+
+		// Wait for the missing fraction of a token.
+		if l.tokens < 1 {
+			wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+			l.sleep(wait)
+			l.tokens = 1
+			l.last = l.last.Add(wait)
+		}
+		l.tokens--
+	}
+
+	return nil
+}
+
+// record counts a request in the usage of the day, unless the limit per day is already reached. The usage file is
+// locked for the whole read-modify-write, so that several instances running at once share the same budget.
+func (l *Limiter) record() error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	usage, err := l.readUsage()
+	if err != nil {
+		return err
+	}
+	if l.perDay > 0 && usage.Count >= l.perDay {
+		return fmt.Errorf("[ratelimit.(*Limiter).Acquire] %w (%d of %d requests used today, the budget resets at midnight UTC)",
+			ErrDailyBudgetExceeded, usage.Count, l.perDay)
+	}
+
+	usage.Count++
+	return l.writeUsage(usage)
+}
@@ -0,0 +1,154 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ratelimit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLimiter creates a limiter with a fake clock that only advances when the limiter sleeps.
+func newTestLimiter(t *testing.T, perMinute int, perDay int) (*Limiter, *time.Time, *[]time.Duration) {
+	clock := time.Date(2025, 4, 5, 12, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+
+	limiter := New(perMinute, perDay, filepath.Join(t.TempDir(), "usage.json"))
+	limiter.now = func() time.Time { return clock }
+	limiter.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		clock = clock.Add(d)
+	}
+	limiter.last = clock
+
+	return limiter, &clock, &sleeps
+}
+
+func TestLimiterPerMinute(t *testing.T) {
+	limiter, clock, sleeps := newTestLimiter(t, 5, 0)
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Acquire(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+
+	// The first request is immediate, the following ones are spaced by 12 seconds.
+	if len(*sleeps) != 2 {
+		t.Fatalf("expected 2 sleeps, got %d", len(*sleeps))
+	}
+	for _, sleep := range *sleeps {
+		if sleep != 12*time.Second {
+			t.Errorf("expected 12s, got %s", sleep)
+		}
+	}
+
+	// After waiting long enough, there is no need to sleep.
+	*clock = clock.Add(time.Minute)
+	if err := limiter.Acquire(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(*sleeps) != 2 {
+		t.Errorf("expected no more sleeps, got %d", len(*sleeps))
+	}
+}
+
+func TestLimiterPerDay(t *testing.T) {
+	limiter, clock, _ := newTestLimiter(t, 0, 2)
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Acquire(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+
+	t.Run("budget exhausted", func(t *testing.T) {
+		if err := limiter.Acquire(); !errors.Is(err, ErrDailyBudgetExceeded) {
+			t.Errorf("expected ErrDailyBudgetExceeded, got %v", err)
+		}
+	})
+
+	t.Run("usage persisted", func(t *testing.T) {
+		other := New(0, 2, limiter.usagePath)
+		other.now = limiter.now
+		usage, perDay, err := other.Usage()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if usage.Count != 2 || perDay != 2 {
+			t.Errorf("expected 2 of 2, got %d of %d", usage.Count, perDay)
+		}
+	})
+
+	t.Run("budget reset the next day", func(t *testing.T) {
+		*clock = clock.Add(24 * time.Hour)
+		if err := limiter.Acquire(); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+}
+
+func TestLimiterSharedUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	t.Run("several instances", func(t *testing.T) {
+		// Each limiter stands for another instance of the program, so they only share the usage file.
+		var wg sync.WaitGroup
+		for range 4 {
+			limiter := New(0, 100, path)
+			wg.Go(func() {
+				for range 10 {
+					if err := limiter.Acquire(); err != nil {
+						t.Errorf("expected nil, got %v", err)
+					}
+				}
+			})
+		}
+		wg.Wait()
+
+		usage, _, err := New(0, 100, path).Usage()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if usage.Count != 40 {
+			t.Errorf("expected 40 requests, got %d", usage.Count)
+		}
+	})
+
+	t.Run("stale lock", func(t *testing.T) {
+		lock := path + ".lock"
+		if err := os.WriteFile(lock, nil, 0600); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		old := time.Now().Add(-2 * lockStale)
+		if err := os.Chtimes(lock, old, old); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if err := New(0, 100, path).Acquire(); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if _, err := os.Stat(lock); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected the lock to be released, got %v", err)
+		}
+	})
+}
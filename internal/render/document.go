@@ -0,0 +1,160 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+)
+
+// Normalised is a record as written by the "json" and "csv" formats. Unlike the bodies of the API, it has the same
+// schema for every command. The prices are numbers written with the precision of the commodity, and the fields that
+// the provider does not give are null (or empty in CSV).
+type Normalised struct {
+	Date          string       `json:"date"`
+	Symbol        string       `json:"symbol"`
+	Currency      string       `json:"currency"`
+	Open          *json.Number `json:"open"`
+	High          *json.Number `json:"high"`
+	Low           *json.Number `json:"low"`
+	Close         *json.Number `json:"close"`
+	AdjustedClose *json.Number `json:"adjusted_close"`
+	Volume        *json.Number `json:"volume"`
+	Dividend      *json.Number `json:"dividend"`
+}
+
+// normalisedHeader is the header of the "csv" format, in the same order as the fields of Normalised.
+var normalisedHeader = []string{"date", "symbol", "currency", "open", "high", "low", "close", "adjusted_close", "volume", "dividend"}
+
+// Normalise converts a record into its normalised form. The date has a time only when it is known.
+func Normalise(record Record) Normalised {
+	layout := "2006-01-02"
+	if hasTime(record.Date) {
+		layout = "2006-01-02 15:04:05"
+	}
+	price := func(value decimal.Decimal) *json.Number {
+		number := json.Number(internal.FormatPrice(value, record.Commodity, record.Currency))
+		return &number
+	}
+
+	normalised := Normalised{
+		Date:     record.Date.Format(layout),
+		Symbol:   record.Commodity,
+		Currency: record.Currency,
+		Close:    price(record.Close),
+	}
+	if record.Given.Has(FieldsRange) {
+		normalised.Open = price(record.Open)
+		normalised.High = price(record.High)
+		normalised.Low = price(record.Low)
+	}
+	if record.Given.Has(FieldsVolume) {
+		volume := json.Number(record.Volume.String())
+		normalised.Volume = &volume
+	}
+	if record.Given.Has(FieldsAdjusted) {
+		normalised.AdjustedClose = price(record.AdjustedClose)
+		normalised.Dividend = price(record.Dividend)
+	}
+	return normalised
+}
+
+// row returns the cells of a normalised record in the "csv" format.
+func (normalised Normalised) row() []string {
+	cell := func(number *json.Number) string {
+		if number == nil {
+			return ""
+		}
+		return number.String()
+	}
+	return []string{
+		normalised.Date,
+		normalised.Symbol,
+		normalised.Currency,
+		cell(normalised.Open),
+		cell(normalised.High),
+		cell(normalised.Low),
+		cell(normalised.Close),
+		cell(normalised.AdjustedClose),
+		cell(normalised.Volume),
+		cell(normalised.Dividend),
+	}
+}
+
+// documentJSON writes the records as a JSON array of normalised records.
+func documentJSON(records []Record) (string, error) {
+	normalised := make([]Normalised, 0, len(records))
+	for _, record := range records {
+		normalised = append(normalised, Normalise(record))
+	}
+
+	out, err := json.MarshalIndent(normalised, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("[render.documentJSON] error encoding the records: %w", err)
+	}
+	return string(out) + "\n", nil
+}
+
+// documentCSV writes the records as a CSV table of normalised records, with a header.
+func documentCSV(records []Record) (string, error) {
+	out := bytes.Buffer{}
+	w := csv.NewWriter(&out)
+	if err := w.Write(normalisedHeader); err != nil {
+		return "", fmt.Errorf("[render.documentCSV] error writing the header: %w", err)
+	}
+	for _, record := range records {
+		if err := w.Write(Normalise(record).row()); err != nil {
+			return "", fmt.Errorf("[render.documentCSV] error writing a record: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("[render.documentCSV] error writing the records: %w", err)
+	}
+	return out.String(), nil
+}
+
+// line is a normalised record as written by the "ndjson" format. It also tells where and when the price was fetched,
+// so the lines of several runs can still be told apart once collected (e.g. by a log shipper).
+type line struct {
+	Normalised
+	Source    string  `json:"source"`
+	FetchedAt *string `json:"fetched_at"`
+}
+
+// formatNDJSON writes a record as a normalised JSON object on a single line.
+func formatNDJSON(record Record) (string, error) {
+	object := line{Normalised: Normalise(record), Source: record.Source}
+	if !record.Fetched.IsZero() {
+		fetched := record.Fetched.Format(time.RFC3339)
+		object.FetchedAt = &fetched
+	}
+
+	out, err := json.Marshal(object)
+	if err != nil {
+		return "", fmt.Errorf("[render.formatNDJSON] error encoding the record: %w", err)
+	}
+	return string(out) + "\n", nil
+}
@@ -0,0 +1,60 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package render
+
+import (
+	"fmt"
+
+	"github.com/lentidas/hledger-price-tracker/internal/beancount"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/ledger"
+)
+
+func init() {
+	Register(flags.OutputFormatHledger, Formatter{DatesOnly: true, Format: formatHledger})
+	Register(flags.OutputFormatBeancount, Formatter{DatesOnly: true, APISymbols: true, Format: formatBeancount})
+	Register(flags.OutputFormatLedger, Formatter{Format: formatLedger})
+	Register(flags.OutputFormatTemplate, Formatter{Format: formatTemplate, Check: checkTemplate})
+	Register(flags.OutputFormatJSON, Formatter{Document: documentJSON})
+	Register(flags.OutputFormatCSV, Formatter{Document: documentCSV})
+	Register(flags.OutputFormatNDJSON, Formatter{Format: formatNDJSON})
+}
+
+// formatHledger writes a record as an hledger `P` directive. The symbols of the stocks are always quoted, since they
+// often contain characters that hledger does not accept in a bare commodity (e.g. "TSCO.LON"). The other names are
+// only quoted when hledger requires it (see journal.Quote), which matters for the names given in the journal.
+func formatHledger(record Record) (string, error) {
+	commodity := journal.Quote(record.Commodity)
+	if record.Stock {
+		commodity = "\"" + record.Commodity + "\""
+	}
+	return fmt.Sprintf("P %s %s %s %s\n", record.Date.Format("2006-01-02"), commodity, record.Price(), journal.Quote(record.Currency)), nil
+}
+
+// formatBeancount writes a record as a Beancount `price` directive. Its names are derived from the symbols of the API
+// (see beancount.Commodity), since the names of the journal are often not valid Beancount commodities.
+func formatBeancount(record Record) (string, error) {
+	return beancount.Price(record.Date, record.Commodity, record.Price(), record.Currency), nil
+}
+
+// formatLedger writes a record as a Ledger `P` directive, with its time when it is known.
+func formatLedger(record Record) (string, error) {
+	return ledger.Price(record.Date, record.Commodity, record.Price(), record.Currency), nil
+}
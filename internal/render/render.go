@@ -0,0 +1,261 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package render writes the prices of the commodities in the output formats that do not depend on the command (e.g.
+// "hledger" or "json"). The packages producing prices convert them into Records, which are rendered by the Formatter
+// registered for the chosen output format, so a new format only needs to be registered here.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+// Record is the normalised price of a commodity, as given to the formatters.
+type Record struct {
+	// Date of the price. It also has a time of day when it is known (see Timestamp).
+	Date time.Time
+	// Commodity is the symbol of the stock or the code of the currency that is priced.
+	Commodity string
+	// Amount is the price of one unit of the commodity, i.e. the close (or adjusted close) price.
+	Amount decimal.Decimal
+	// Currency in which the commodity is priced.
+	Currency string
+	Open     decimal.Decimal
+	High     decimal.Decimal
+	Low      decimal.Decimal
+	Close    decimal.Decimal
+	// Volume traded, which is zero when the provider does not give it (e.g. for exchange rates).
+	Volume decimal.Decimal
+	// AdjustedClose and Dividend are only given by the adjusted series of stock prices.
+	AdjustedClose decimal.Decimal
+	Dividend      decimal.Decimal
+	// Given tells which of the optional fields were given by the provider.
+	Given Fields
+	// Source is the name of the provider of the price (e.g. "alphavantage").
+	Source string
+	// Stock tells whether the commodity is a stock instead of a currency.
+	Stock bool
+	// Fetched is when the price was received from the provider, which is zero when unknown.
+	Fetched time.Time
+}
+
+// Fields is a set of optional fields of a Record.
+type Fields uint8
+
+const (
+	// FieldsRange are the open, high and low prices.
+	FieldsRange Fields = 1 << iota
+	// FieldsVolume is the volume traded.
+	FieldsVolume
+	// FieldsAdjusted are the adjusted close price and the dividend.
+	FieldsAdjusted
+)
+
+// Has reports whether all the given fields are in the set.
+func (fields Fields) Has(other Fields) bool {
+	return fields&other == other
+}
+
+// Price returns the amount formatted with the precision of the commodity (see internal.FormatPrice).
+func (record Record) Price() string {
+	return internal.FormatPrice(record.Amount, record.Commodity, record.Currency)
+}
+
+// JoinSources returns the source of a price computed from two others (e.g. a cross rate), which is the source of both
+// when they are the same.
+func JoinSources(first string, second string) string {
+	if first == second || second == "" {
+		return first
+	}
+	if first == "" {
+		return second
+	}
+	return first + "," + second
+}
+
+// Formatter writes the records in a given output format.
+type Formatter struct {
+	// DatesOnly tells whether the format only knows about dates, in which case the intraday prices are collapsed
+	// into the last one of each day.
+	DatesOnly bool
+	// APISymbols tells whether the format keeps the symbols of the API instead of the names of the journal, for the
+	// formats whose commodities cannot be named freely (e.g. Beancount, which would turn "€" into "X").
+	APISymbols bool
+	// Format returns the directive of a single record, ending with a newline.
+	Format func(record Record) (string, error)
+	// Document, when not nil, writes all the records at once instead of Format, for the formats that are not made of
+	// one line per record (e.g. a JSON array).
+	Document func(records []Record) (string, error)
+	// Check, when not nil, tells whether the formatter can be used (e.g. whether it has all its settings). It is
+	// called once before formatting the records.
+	Check func() error
+}
+
+var registry = make(map[flags.OutputFormat]Formatter)
+
+// Register makes a formatter available for an output format. It is meant to be called from an init function.
+func Register(format flags.OutputFormat, formatter Formatter) {
+	registry[format] = formatter
+}
+
+// Lookup returns the formatter registered for an output format. The formats that are not made of one directive per
+// price (e.g. "table") have none.
+func Lookup(format flags.OutputFormat) (Formatter, bool) {
+	formatter, ok := registry[format]
+	return formatter, ok
+}
+
+// Render writes the records, given in chronological order, in the given output format, with the names of the journal
+// (see NewWriter).
+func Render(format flags.OutputFormat, records []Record, as string) (string, error) {
+	out := strings.Builder{}
+	writer, err := NewWriter(&out, format, as)
+	if err != nil {
+		return "", err
+	}
+	if err := writer.Write(records); err != nil {
+		return "", err
+	}
+	if err := writer.Flush(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Writer writes the records of several batches (e.g. the prices of several commodities) to an io.Writer as soon as
+// each batch is given, so the output can be read while the others are still being fetched. The formats that write a
+// whole document (e.g. "json") keep the records until Flush is called.
+type Writer struct {
+	out       io.Writer
+	formatter Formatter
+	as        string
+	pending   []Record
+}
+
+// NewWriter returns a Writer of the given output format, after checking that its formatter can be used. The
+// commodities and currencies of the records are given the names of the journal (see symbols.ToJournal) before being
+// formatted, unless the format keeps the symbols of the API, and the commodity is named `as` instead when it is not
+// empty.
+func NewWriter(out io.Writer, format flags.OutputFormat, as string) (*Writer, error) {
+	formatter, ok := Lookup(format)
+	if !ok {
+		return nil, errors.New("[render.NewWriter] invalid output format")
+	}
+	if formatter.Check != nil {
+		if err := formatter.Check(); err != nil {
+			return nil, err
+		}
+	}
+	return &Writer{out: out, formatter: formatter, as: as}, nil
+}
+
+// Write writes a batch of records, given in chronological order. The directives of a batch are written at once, so
+// the io.Writer always receives whole lines.
+func (writer *Writer) Write(records []Record) error {
+	records = writer.rename(records)
+	if writer.formatter.DatesOnly {
+		records = lastOfEachDay(records)
+	}
+	if writer.formatter.Document != nil {
+		writer.pending = append(writer.pending, records...)
+		return nil
+	}
+
+	out := strings.Builder{}
+	for _, record := range records {
+		directive, err := writer.formatter.Format(record)
+		if err != nil {
+			return fmt.Errorf("[render.(*Writer).Write] error formatting the price of %s on %s: %w", record.Commodity, record.Date.Format("2006-01-02"), err)
+		}
+		out.WriteString(directive)
+	}
+	_, err := io.WriteString(writer.out, out.String())
+	return err
+}
+
+// Flush writes the document of the formats that need all the records at once. It does nothing for the others.
+func (writer *Writer) Flush() error {
+	if writer.formatter.Document == nil {
+		return nil
+	}
+	document, err := writer.formatter.Document(writer.pending)
+	if err != nil {
+		return err
+	}
+	writer.pending = nil
+	_, err = io.WriteString(writer.out, document)
+	return err
+}
+
+// rename returns a copy of the records with the names of the journal, or with the symbols of the API if the format
+// keeps them. The commodity is always named `as` when it is not empty.
+func (writer *Writer) rename(records []Record) []Record {
+	renamed := make([]Record, len(records))
+	for i, record := range records {
+		if writer.as != "" {
+			record.Commodity = writer.as
+		} else if !writer.formatter.APISymbols {
+			record.Commodity = symbols.ToJournal(record.Commodity)
+		}
+		if !writer.formatter.APISymbols {
+			record.Currency = symbols.ToJournal(record.Currency)
+		}
+		renamed[i] = record
+	}
+	return renamed
+}
+
+// Timestamp returns the date to put in the record of a price. Daily prices have no time, so the time of the last
+// refresh of the series is used for the price of that same day, when it is known.
+func Timestamp(date time.Time, lastRefreshed time.Time) time.Time {
+	if hasTime(date) || date.Format("2006-01-02") != lastRefreshed.Format("2006-01-02") {
+		return date
+	}
+	return lastRefreshed
+}
+
+// hasTime reports whether a date has a time of day other than midnight.
+func hasTime(date time.Time) bool {
+	return date.Hour() != 0 || date.Minute() != 0 || date.Second() != 0
+}
+
+// lastOfEachDay keeps only the last record of each day for each commodity, from a chronologically sorted list.
+func lastOfEachDay(records []Record) []Record {
+	var kept []Record
+	for i, record := range records {
+		if i == len(records)-1 || !sameDay(record, records[i+1]) {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// sameDay reports whether two records are prices of the same commodity on the same day.
+func sameDay(a Record, b Record) bool {
+	return a.Commodity == b.Commodity && a.Currency == b.Currency &&
+		a.Date.Format("2006-01-02") == b.Date.Format("2006-01-02")
+}
@@ -0,0 +1,339 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+// intraday are two prices of the same day, followed by a price of the next day.
+var intraday = []Record{
+	{Date: time.Date(2025, 4, 3, 12, 0, 0, 0, time.UTC), Commodity: "EUR", Amount: decimal.MustParse("1.1012"), Currency: "USD", Source: "alphavantage"},
+	{Date: time.Date(2025, 4, 3, 21, 0, 0, 0, time.UTC), Commodity: "EUR", Amount: decimal.MustParse("1.1045"), Currency: "USD", Source: "alphavantage"},
+	{Date: time.Date(2025, 4, 4, 21, 0, 0, 0, time.UTC), Commodity: "EUR", Amount: decimal.MustParse("1.0956"), Currency: "USD", Source: "alphavantage"},
+}
+
+func TestRender(t *testing.T) {
+	t.Run("success hledger", func(t *testing.T) {
+		// hledger only knows about dates, so the intraday prices are collapsed.
+		expected := "P 2025-04-03 EUR 1.1045 USD\nP 2025-04-04 EUR 1.0956 USD\n"
+		output, err := Render(flags.OutputFormatHledger, intraday, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success hledger stock", func(t *testing.T) {
+		expected := "P 2025-04-04 \"TSCO.LON\" 3.504 GBP\n"
+		output, err := Render(flags.OutputFormatHledger, []Record{
+			{Date: time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), Commodity: "TSCO.LON", Amount: decimal.MustParse("3.504"), Currency: "GBP", Stock: true},
+		}, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success ledger", func(t *testing.T) {
+		// Ledger keeps every intraday price with its time.
+		expected := "P 2025/04/03 12:00:00 EUR 1.1012 USD\nP 2025/04/03 21:00:00 EUR 1.1045 USD\nP 2025/04/04 21:00:00 EUR 1.0956 USD\n"
+		output, err := Render(flags.OutputFormatLedger, intraday, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success template", func(t *testing.T) {
+		Template = `{{.Date.Format "02/01/2006 15:04"}};{{.Commodity}};{{.Price}};{{lower .Currency}};{{round .Amount 2}};{{.Source}}`
+		defer func() { Template = "" }()
+
+		expected := "04/04/2025 21:00;EUR;1.0956;usd;1.10;alphavantage\n"
+		output, err := Render(flags.OutputFormatTemplate, intraday[2:], "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success json", func(t *testing.T) {
+		record := intraday[2]
+		record.Open, record.High, record.Low = decimal.MustParse("1.0974"), decimal.MustParse("1.1023"), decimal.MustParse("1.0914")
+		record.Close = record.Amount
+		record.Given = FieldsRange
+
+		expected := `[
+  {
+    "date": "2025-04-04 21:00:00",
+    "symbol": "EUR",
+    "currency": "USD",
+    "open": 1.0974,
+    "high": 1.1023,
+    "low": 1.0914,
+    "close": 1.0956,
+    "adjusted_close": null,
+    "volume": null,
+    "dividend": null
+  }
+]
+`
+		output, err := Render(flags.OutputFormatJSON, []Record{record}, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success csv", func(t *testing.T) {
+		record := Record{
+			Date:          time.Date(2025, 2, 14, 0, 0, 0, 0, time.UTC),
+			Commodity:     "IBM",
+			Amount:        decimal.MustParse("261.28"),
+			Currency:      "USD",
+			Open:          decimal.MustParse("252.40"),
+			High:          decimal.MustParse("263.99"),
+			Low:           decimal.MustParse("249.69"),
+			Close:         decimal.MustParse("261.28"),
+			Volume:        decimal.New(21581916, 0),
+			AdjustedClose: decimal.MustParse("261.28"),
+			Dividend:      decimal.MustParse("1.67"),
+			Given:         FieldsRange | FieldsVolume | FieldsAdjusted,
+			Stock:         true,
+		}
+
+		expected := "date,symbol,currency,open,high,low,close,adjusted_close,volume,dividend\n" +
+			"2025-02-14,IBM,USD,252.40,263.99,249.69,261.28,261.28,21581916,1.67\n"
+		output, err := Render(flags.OutputFormatCSV, []Record{record}, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success json without records", func(t *testing.T) {
+		output, err := Render(flags.OutputFormatJSON, nil, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != "[]\n" {
+			t.Errorf("expected an empty array, got %q", output)
+		}
+	})
+
+	t.Run("success ndjson", func(t *testing.T) {
+		records := append([]Record{}, intraday[1:]...)
+		records[0].Close, records[1].Close = records[0].Amount, records[1].Amount
+		records[0].Fetched = time.Date(2025, 4, 5, 8, 30, 0, 0, time.UTC)
+
+		expected := `{"date":"2025-04-03 21:00:00","symbol":"EUR","currency":"USD","open":null,"high":null,"low":null,"close":1.1045,"adjusted_close":null,"volume":null,"dividend":null,"source":"alphavantage","fetched_at":"2025-04-05T08:30:00Z"}
+{"date":"2025-04-04 21:00:00","symbol":"EUR","currency":"USD","open":null,"high":null,"low":null,"close":1.0956,"adjusted_close":null,"volume":null,"dividend":null,"source":"alphavantage","fetched_at":null}
+`
+		output, err := Render(flags.OutputFormatNDJSON, records, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("template without template", func(t *testing.T) {
+		if _, err := Render(flags.OutputFormatTemplate, intraday, ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("template with unknown field", func(t *testing.T) {
+		Template = "{{.Symbol}}"
+		defer func() { Template = "" }()
+
+		if _, err := Render(flags.OutputFormatTemplate, intraday, ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("invalid output format", func(t *testing.T) {
+		if _, err := Render(flags.OutputFormatTable, intraday, ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("each batch is written at once", func(t *testing.T) {
+		out := strings.Builder{}
+		writer, err := NewWriter(&out, flags.OutputFormatLedger, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if err := writer.Write(intraday[:1]); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		expected := "P 2025/04/03 12:00:00 EUR 1.1012 USD\n"
+		if out.String() != expected {
+			t.Errorf("expected %q before the next batch, got %q", expected, out.String())
+		}
+
+		if err := writer.Write(intraday[1:2]); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		expected += "P 2025/04/03 21:00:00 EUR 1.1045 USD\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("documents are written on flush", func(t *testing.T) {
+		out := strings.Builder{}
+		writer, err := NewWriter(&out, flags.OutputFormatJSON, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		for _, record := range intraday {
+			if err := writer.Write([]Record{record}); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+		if out.Len() != 0 {
+			t.Errorf("expected nothing before the flush, got %q", out.String())
+		}
+
+		if err := writer.Flush(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if count := strings.Count(out.String(), `"symbol"`); count != len(intraday) {
+			t.Errorf("expected %d records in a single document, got %q", len(intraday), out.String())
+		}
+	})
+
+	t.Run("names of the journal", func(t *testing.T) {
+		if err := symbols.Load([]symbols.Mapping{{Commodity: "TSCO", Symbol: "TSCO.LON"}, {Commodity: "£", Symbol: "GBP"}}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		defer symbols.Load(nil)
+
+		stock := []Record{{Date: time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), Commodity: "TSCO.LON", Amount: decimal.MustParse("3.504"), Currency: "GBP", Stock: true}}
+		tests := []struct {
+			format   flags.OutputFormat
+			as       string
+			expected string
+		}{
+			{flags.OutputFormatHledger, "", "P 2025-04-04 \"TSCO\" 3.504 £\n"},
+			{flags.OutputFormatHledger, "TESCO", "P 2025-04-04 \"TESCO\" 3.504 £\n"},
+			{flags.OutputFormatLedger, "", "P 2025/04/04 TSCO 3.504 £\n"},
+			{flags.OutputFormatBeancount, "", "2025-04-04 price TSCO.LON 3.504 GBP\n"},
+			{flags.OutputFormatBeancount, "TESCO", "2025-04-04 price TESCO 3.504 GBP\n"},
+		}
+		for _, test := range tests {
+			output, err := Render(test.format, stock, test.as)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if output != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, output)
+			}
+		}
+	})
+
+	t.Run("symbols of the API for beancount", func(t *testing.T) {
+		if err := symbols.Load([]symbols.Mapping{{Commodity: "€", Symbol: "EUR"}}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		defer symbols.Load(nil)
+
+		rate := []Record{{Date: time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), Commodity: "USD", Amount: decimal.MustParse("0.9082"), Currency: "EUR"}}
+		expected := "2025-04-04 price USD 0.9082 EUR\n"
+		output, err := Render(flags.OutputFormatBeancount, rate, "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("invalid output format", func(t *testing.T) {
+		if _, err := NewWriter(&strings.Builder{}, flags.OutputFormatRawJSON, ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestTimestamp(t *testing.T) {
+	lastRefreshed := time.Date(2025, 4, 4, 16, 0, 1, 0, time.UTC)
+
+	t.Run("same day as the last refresh", func(t *testing.T) {
+		date := time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC)
+		if result := Timestamp(date, lastRefreshed); !result.Equal(lastRefreshed) {
+			t.Errorf("expected %v, got %v", lastRefreshed, result)
+		}
+	})
+
+	t.Run("other day", func(t *testing.T) {
+		date := time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC)
+		if result := Timestamp(date, lastRefreshed); !result.Equal(date) {
+			t.Errorf("expected %v, got %v", date, result)
+		}
+	})
+
+	t.Run("intraday price", func(t *testing.T) {
+		date := time.Date(2025, 4, 4, 15, 55, 0, 0, time.UTC)
+		if result := Timestamp(date, lastRefreshed); !result.Equal(date) {
+			t.Errorf("expected %v, got %v", date, result)
+		}
+	})
+}
+
+func TestJoinSources(t *testing.T) {
+	tests := map[[2]string]string{
+		{"alphavantage", "alphavantage"}: "alphavantage",
+		{"ecb", "alphavantage"}:          "ecb,alphavantage",
+		{"", "ecb"}:                      "ecb",
+		{"ecb", ""}:                      "ecb",
+	}
+	for sources, expected := range tests {
+		if result := JoinSources(sources[0], sources[1]); result != expected {
+			t.Errorf("expected %s for %v, got %s", expected, sources, result)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package render
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+)
+
+// Template is the text of the Go template used by the "template" output format. It is executed once for each
+// Record, and a newline is added to its output when it does not end with one.
+var Template string
+
+// parsed caches the template, so it is only parsed once for all the records. It is parsed again if Template changes.
+var parsed struct {
+	text     string
+	template *template.Template
+}
+
+// templateFuncs are the functions available in the templates, besides the fields and methods of Record.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"round": func(value decimal.Decimal, places int) string {
+		return value.Round(int32(places)).String()
+	},
+}
+
+// checkTemplate parses the template given by the user, unless it was already parsed.
+func checkTemplate() error {
+	if Template == "" {
+		return errors.New("[render.checkTemplate] no template given (see the --template and --template-file flags)")
+	}
+	if parsed.template != nil && parsed.text == Template {
+		return nil
+	}
+
+	tmpl, err := template.New("price").Funcs(templateFuncs).Parse(Template)
+	if err != nil {
+		return fmt.Errorf("[render.checkTemplate] error parsing the template: %w", err)
+	}
+	parsed.text = Template
+	parsed.template = tmpl
+	return nil
+}
+
+// formatTemplate writes a record with the template given by the user.
+func formatTemplate(record Record) (string, error) {
+	if err := checkTemplate(); err != nil {
+		return "", err
+	}
+
+	out := strings.Builder{}
+	if err := parsed.template.Execute(&out, record); err != nil {
+		return "", fmt.Errorf("[render.formatTemplate] error executing the template: %w", err)
+	}
+	if !strings.HasSuffix(out.String(), "\n") {
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
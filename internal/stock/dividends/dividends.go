@@ -0,0 +1,555 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package dividends
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+const apiFunctionDividends = "DIVIDENDS"
+
+// Sources of the dividend events.
+const (
+	// SourceDividends uses the DIVIDENDS endpoint, which gives the ex-dividend and payment dates of each dividend.
+	SourceDividends = "dividends"
+	// SourceAdjusted uses the dividend amounts of the weekly adjusted prices. The payment dates are unknown, so each
+	// dividend is dated with the last day of the week of its ex-dividend date.
+	SourceAdjusted = "adjusted"
+)
+
+type Response interface {
+	TypeBody() error
+	ParseBody(body []byte) (Typed, error)
+}
+
+// Provider is implemented by every price source able to return the dividends paid by a stock.
+// The raw body is returned alongside the typed dividends so the "raw-json" output format can be served as is.
+// A provider whose dividends do not come with their currency leaves it empty, in which case it is looked up with
+// StockCurrency and the amounts are taken as paid in it (see fetch).
+type Provider interface {
+	Dividends(symbol string, format flags.OutputFormat) (Typed, []byte, error)
+	search.CurrencyProvider
+}
+
+// Sources is implemented by the providers able to return both the dividends and the adjusted prices of a stock.
+type Sources interface {
+	Provider
+	price.Provider
+}
+
+// AlphaVantage implements Provider using the DIVIDENDS endpoint of the Alpha Vantage API.
+type AlphaVantage struct{}
+
+// Options are the accounts and the withholding tax used in the hledger transactions. They are set in the `dividends`
+// section of the configuration file, and can be overridden with flags.
+type Options struct {
+	// Account is the account (and its subaccounts) where the stocks are held, used to read the quantity held at each
+	// ex-dividend date from the journal.
+	Account string `mapstructure:"account"`
+	// IncomeAccount is the account where the gross dividend is booked.
+	IncomeAccount string `mapstructure:"income-account"`
+	// CashAccount is the account receiving the net dividend.
+	CashAccount string `mapstructure:"cash-account"`
+	// TaxAccount is the account where the withholding tax is booked.
+	TaxAccount string `mapstructure:"tax-account"`
+	// WithholdingTax is the percentage of the gross dividend withheld at source.
+	WithholdingTax decimal.Decimal `mapstructure:"withholding-tax"`
+}
+
+// DefaultOptions returns the options used when the configuration file does not set them.
+func DefaultOptions() Options {
+	return Options{
+		Account:       "assets",
+		IncomeAccount: "income:dividends",
+		CashAccount:   "assets:cash",
+		TaxAccount:    "expenses:taxes",
+	}
+}
+
+// Holding returns the quantity of the stock held at the end of a day.
+type Holding func(date time.Time) decimal.Decimal
+
+// Quantity returns a Holding for a constant quantity.
+func Quantity(quantity decimal.Decimal) Holding {
+	return func(time.Time) decimal.Decimal {
+		return quantity
+	}
+}
+
+// JournalHolding returns a Holding reading the quantity of a commodity from the postings of a journal.
+func JournalHolding(j *journal.Journal, commodity string, account string) Holding {
+	return func(date time.Time) decimal.Decimal {
+		return j.Holding(commodity, account, date)
+	}
+}
+
+type RawDividend struct {
+	ExDividendDate  string `json:"ex_dividend_date"`
+	DeclarationDate string `json:"declaration_date"`
+	RecordDate      string `json:"record_date"`
+	PaymentDate     string `json:"payment_date"`
+	Amount          string `json:"amount"`
+}
+
+type Raw struct {
+	Symbol string        `json:"symbol"`
+	Data   []RawDividend `json:"data"`
+}
+
+type TypedDividend struct {
+	ExDividendDate  time.Time
+	DeclarationDate time.Time
+	RecordDate      time.Time
+	PaymentDate     time.Time
+	Amount          decimal.Decimal
+}
+
+type Typed struct {
+	Symbol    string
+	Currency  string
+	Dividends []TypedDividend
+}
+
+// Normalised is a dividend as written by the "json" format. Unlike the body of the API, the unknown dates are null
+// instead of "None" and the amounts are numbers. The quantity held and the amounts received are null when the quantity
+// held is unknown.
+type Normalised struct {
+	Symbol          string       `json:"symbol"`
+	Currency        string       `json:"currency"`
+	ExDividendDate  string       `json:"ex_dividend_date"`
+	DeclarationDate *string      `json:"declaration_date"`
+	RecordDate      *string      `json:"record_date"`
+	PaymentDate     *string      `json:"payment_date"`
+	Amount          json.Number  `json:"amount"`
+	Quantity        *json.Number `json:"quantity"`
+	Gross           *json.Number `json:"gross"`
+	WithholdingTax  *json.Number `json:"withholding_tax"`
+	Net             *json.Number `json:"net"`
+}
+
+type Dividends struct {
+	Raw   Raw
+	Typed Typed
+}
+
+// parseOptionalDate parses a date that the API sets to "None" when it is unknown.
+func parseOptionalDate(s string) (time.Time, error) {
+	if s == "" || s == "None" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func (typed *TypedDividend) TypeBody(raw RawDividend) error {
+	var err error
+	typed.ExDividendDate, err = time.Parse("2006-01-02", raw.ExDividendDate)
+	if err != nil {
+		return fmt.Errorf("[stock.dividends.(*TypedDividend).TypeBody] error parsing ex-dividend date: %w", err)
+	}
+	typed.DeclarationDate, err = parseOptionalDate(raw.DeclarationDate)
+	if err != nil {
+		return fmt.Errorf("[stock.dividends.(*TypedDividend).TypeBody] error parsing declaration date: %w", err)
+	}
+	typed.RecordDate, err = parseOptionalDate(raw.RecordDate)
+	if err != nil {
+		return fmt.Errorf("[stock.dividends.(*TypedDividend).TypeBody] error parsing record date: %w", err)
+	}
+	typed.PaymentDate, err = parseOptionalDate(raw.PaymentDate)
+	if err != nil {
+		return fmt.Errorf("[stock.dividends.(*TypedDividend).TypeBody] error parsing payment date: %w", err)
+	}
+	typed.Amount, err = decimal.Parse(raw.Amount)
+	if err != nil {
+		return fmt.Errorf("[stock.dividends.(*TypedDividend).TypeBody] error parsing amount: %w", err)
+	}
+
+	return nil
+}
+
+func (obj *Dividends) TypeBody() error {
+	obj.Typed.Symbol = obj.Raw.Symbol
+	obj.Typed.Dividends = make([]TypedDividend, 0, len(obj.Raw.Data))
+	for _, raw := range obj.Raw.Data {
+		var dividend TypedDividend
+		if err := dividend.TypeBody(raw); err != nil {
+			return fmt.Errorf("[(*Dividends).TypeBody] error casting dividend: %w", err)
+		}
+		obj.Typed.Dividends = append(obj.Typed.Dividends, dividend)
+	}
+
+	return nil
+}
+
+// inCurrency sets the currency of dividends paid in it. The dividends are paid in the unit the stock is quoted in, so
+// the ones paid in a minor unit of a currency (e.g. pence) are converted to its major unit, the same way as its prices.
+func (typed *Typed) inCurrency(currency string) {
+	var divisor decimal.Decimal
+	typed.Currency, divisor = internal.MajorUnit(currency)
+	for i := range typed.Dividends {
+		typed.Dividends[i].Amount = typed.Dividends[i].Amount.Div(divisor)
+	}
+}
+
+// fetch gets the dividends of a stock from the given provider. When the provider does not give their currency, it is
+// looked up with the same provider.
+func fetch(provider Provider, symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	typed, body, err := provider.Dividends(symbol, format)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	// The raw format is not parsed.
+	if format == flags.OutputFormatRawJSON || typed.Currency != "" {
+		return typed, body, nil
+	}
+
+	currency, err := provider.StockCurrency(typed.Symbol)
+	if err != nil {
+		return Typed{}, nil, fmt.Errorf("[stock.dividends.fetch] error getting the currency of %s: %w", symbol, err)
+	}
+	typed.inCurrency(currency)
+
+	return typed, body, nil
+}
+
+func (obj *Dividends) ParseBody(body []byte) (Typed, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Dividends).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Dividends).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return obj.Typed, nil
+}
+
+// FromSeries extracts the dividends from a series of adjusted prices. Each dividend is dated with the date of the
+// point of the series, which is used both as ex-dividend and payment date.
+func FromSeries(series price.Series) Typed {
+	typed := Typed{
+		Symbol:   series.MetaData.Symbol,
+		Currency: series.MetaData.Currency,
+	}
+	for date, prices := range series.TimeSeriesAdjusted {
+		if prices.DividendAmount.Sign() > 0 {
+			typed.Dividends = append(typed.Dividends, TypedDividend{
+				ExDividendDate: date,
+				PaymentDate:    date,
+				Amount:         prices.DividendAmount,
+			})
+		}
+	}
+	return typed
+}
+
+// buildURL creates the URL to make the HTTP request to the Alpha Vantage API.
+func buildURL(symbol string) (string, error) {
+	if internal.ApiKey == "" {
+		return "", errors.New("[stock.dividends.buildURL] API key is required")
+	}
+	if symbol == "" {
+		return "", errors.New("[stock.dividends.buildURL] no stock symbol provided")
+	}
+
+	url := strings.Builder{}
+	url.WriteString(internal.ApiBaseUrl)
+	url.WriteString("function=")
+	url.WriteString(apiFunctionDividends)
+	url.WriteString("&symbol=")
+	url.WriteString(symbol)
+	url.WriteString("&apikey=")
+	url.WriteString(internal.ApiKey)
+
+	return url.String(), nil
+}
+
+// Dividends fetches the dividends of a stock from the Alpha Vantage API and casts them into their proper types.
+func (AlphaVantage) Dividends(symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	url, err := buildURL(symbol)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+
+	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		return Typed{}, nil, fmt.Errorf("[stock.dividends.(AlphaVantage).Dividends] %w %s: %w", internal.ErrUnknownSymbol, symbol, err)
+	}
+	if err != nil {
+		return Typed{}, nil, err
+	}
+
+	// The raw format does not need the body to be parsed.
+	if format == flags.OutputFormatRawJSON {
+		return Typed{}, body, nil
+	}
+
+	response := Dividends{}
+	typed, err := response.ParseBody(body)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	if typed.Symbol == "" {
+		return Typed{}, nil, fmt.Errorf("[stock.dividends.(AlphaVantage).Dividends] %w %s: no dividends found", internal.ErrUnknownSymbol, symbol)
+	}
+
+	return typed, body, nil
+}
+
+// payment returns the date of the transaction of a dividend, which is its payment date when known.
+func (dividend TypedDividend) payment() time.Time {
+	if dividend.PaymentDate.IsZero() {
+		return dividend.ExDividendDate
+	}
+	return dividend.PaymentDate
+}
+
+// amounts returns the gross dividend, the withholding tax and the net dividend rounded to the cent, so the transaction
+// is always balanced.
+func amounts(quantity decimal.Decimal, amount decimal.Decimal, withholdingTax decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal) {
+	gross := quantity.Mul(amount).Round(2)
+	tax := gross.Mul(withholdingTax).Div(decimal.New(100, 0)).Round(2)
+	return gross, tax, gross.Sub(tax)
+}
+
+// formatDate formats a date that might be unknown.
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return "-"
+	}
+	return date.Format("2006-01-02")
+}
+
+// generateOutputHledger generates a transaction for each dividend received, i.e. each dividend with a quantity held
+// on the day before its ex-dividend date.
+func generateOutputHledger(typed Typed, dividends []TypedDividend, holding Holding, name string, options Options) string {
+	currency := journal.Quote(symbols.ToJournal(typed.Currency))
+
+	// Align the amounts of the postings.
+	accounts := []string{options.CashAccount, options.IncomeAccount}
+	if options.WithholdingTax.Sign() > 0 {
+		accounts = append(accounts, options.TaxAccount)
+	}
+	width := 0
+	for _, account := range accounts {
+		width = max(width, len(account))
+	}
+	posting := fmt.Sprintf("    %%-%ds  %%12s %%s\n", width)
+
+	out := strings.Builder{}
+	for _, dividend := range dividends {
+		quantity := holding(dividend.ExDividendDate.AddDate(0, 0, -1))
+		if quantity.IsZero() {
+			continue
+		}
+		gross, tax, net := amounts(quantity, dividend.Amount, options.WithholdingTax)
+
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("%s %s dividend  ; ex-dividend:%s, quantity:%s, per-share:%s\n",
+			dividend.payment().Format("2006-01-02"),
+			name,
+			dividend.ExDividendDate.Format("2006-01-02"),
+			quantity.Trim(0),
+			dividend.Amount.Trim(0)))
+		out.WriteString(fmt.Sprintf(posting, options.CashAccount, net, currency))
+		if options.WithholdingTax.Sign() > 0 {
+			out.WriteString(fmt.Sprintf(posting, options.TaxAccount, tax, currency))
+		}
+		out.WriteString(fmt.Sprintf(posting, options.IncomeAccount, gross.Neg(), currency))
+	}
+	return out.String()
+}
+
+// generateOutputJSON writes the dividends as a JSON array of normalised dividends, with the names of the journal.
+func generateOutputJSON(typed Typed, dividends []TypedDividend, holding Holding, name string, options Options) (string, error) {
+	date := func(date time.Time) *string {
+		if date.IsZero() {
+			return nil
+		}
+		formatted := date.Format("2006-01-02")
+		return &formatted
+	}
+	number := func(value string) *json.Number {
+		n := json.Number(value)
+		return &n
+	}
+
+	normalised := make([]Normalised, 0, len(dividends))
+	for _, dividend := range dividends {
+		n := Normalised{
+			Symbol:          name,
+			Currency:        symbols.ToJournal(typed.Currency),
+			ExDividendDate:  dividend.ExDividendDate.Format("2006-01-02"),
+			DeclarationDate: date(dividend.DeclarationDate),
+			RecordDate:      date(dividend.RecordDate),
+			PaymentDate:     date(dividend.PaymentDate),
+			Amount:          json.Number(dividend.Amount.Trim(0).String()),
+		}
+		if holding != nil {
+			quantity := holding(dividend.ExDividendDate.AddDate(0, 0, -1))
+			gross, tax, net := amounts(quantity, dividend.Amount, options.WithholdingTax)
+			n.Quantity = number(quantity.Trim(0).String())
+			n.Gross = number(gross.String())
+			n.WithholdingTax = number(tax.String())
+			n.Net = number(net.String())
+		}
+		normalised = append(normalised, n)
+	}
+
+	out, err := json.MarshalIndent(normalised, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("[stock.dividends.generateOutputJSON] error encoding the dividends: %w", err)
+	}
+	return string(out) + "\n", nil
+}
+
+// generateOutputTable generates a table with the dividends. The long version includes the other dates of each
+// dividend and, when the quantity held is known, the amounts received.
+func generateOutputTable(typed Typed, dividends []TypedDividend, holding Holding, options Options, format flags.OutputFormat) string {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	if format == flags.OutputFormatTable {
+		t.AppendHeader(table.Row{"Ex-Dividend Date", "Payment Date", "Amount", "Currency"})
+		for _, dividend := range dividends {
+			t.AppendRow(table.Row{
+				formatDate(dividend.ExDividendDate),
+				formatDate(dividend.PaymentDate),
+				internal.FormatPrice(dividend.Amount, typed.Symbol, typed.Currency),
+				typed.Currency,
+			})
+		}
+		return t.Render() + "\n"
+	}
+
+	header := table.Row{"Ex-Dividend Date", "Declaration Date", "Record Date", "Payment Date", "Amount", "Currency"}
+	if holding != nil {
+		header = append(header, "Quantity", "Gross", "Withholding Tax", "Net")
+	}
+	t.AppendHeader(header)
+	for _, dividend := range dividends {
+		row := table.Row{
+			formatDate(dividend.ExDividendDate),
+			formatDate(dividend.DeclarationDate),
+			formatDate(dividend.RecordDate),
+			formatDate(dividend.PaymentDate),
+			internal.FormatPrice(dividend.Amount, typed.Symbol, typed.Currency),
+			typed.Currency,
+		}
+		if holding != nil {
+			quantity := holding(dividend.ExDividendDate.AddDate(0, 0, -1))
+			gross, tax, net := amounts(quantity, dividend.Amount, options.WithholdingTax)
+			row = append(row, quantity, gross, tax, net)
+		}
+		t.AppendRow(row)
+	}
+	return t.Render() + "\n"
+}
+
+// GenerateOutput renders the dividends paid between `begin` and `end` (by ex-dividend date) in the desired format.
+// The "hledger" format needs to know the quantity held, whereas it is optional for the "json" and "table-long" formats.
+// `name` is the name of the stock in the journal. The "raw-json" format returns the raw body given by the provider.
+func GenerateOutput(typed Typed, body []byte, holding Holding, name string, options Options, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
+	switch format {
+	case flags.OutputFormatRawJSON:
+		return string(body), nil
+	case flags.OutputFormatCSV, flags.OutputFormatRawCSV:
+		return "", errors.New("[stock.dividends.GenerateOutput] CSV output formats not supported")
+	case flags.OutputFormatHledger, flags.OutputFormatJSON, flags.OutputFormatTable, flags.OutputFormatTableLong:
+		// Do nothing.
+	default:
+		return "", errors.New("[stock.dividends.GenerateOutput] invalid output format")
+	}
+
+	var dividends []TypedDividend
+	for _, dividend := range typed.Dividends {
+		if !(dividend.ExDividendDate.Before(begin) || dividend.ExDividendDate.After(end)) {
+			dividends = append(dividends, dividend)
+		}
+	}
+	sort.Slice(dividends, func(i, j int) bool {
+		return dividends[i].ExDividendDate.Before(dividends[j].ExDividendDate)
+	})
+
+	switch format {
+	case flags.OutputFormatHledger:
+		if holding == nil {
+			return "", errors.New("[stock.dividends.GenerateOutput] the quantity held is needed to generate the transactions")
+		}
+		return generateOutputHledger(typed, dividends, holding, name, options), nil
+	case flags.OutputFormatJSON:
+		return generateOutputJSON(typed, dividends, holding, name, options)
+	}
+	return generateOutputTable(typed, dividends, holding, options, format), nil
+}
+
+// Execute is the core function of the dividends package. It fetches the dividends of a stock from the given source
+// of the provider and returns them in the desired format. `holding` may be nil if the quantity held is unknown.
+func Execute(provider Sources, symbol string, name string, source string, holding Holding, options Options, format flags.OutputFormat, begin string, end string) (string, error) {
+	beginTime, endTime, err := internal.ValidateDates(begin, end)
+	if err != nil {
+		return "", err
+	}
+	// Avoid spending a request on a format that cannot be generated.
+	if format == flags.OutputFormatCSV || format == flags.OutputFormatRawCSV {
+		return "", errors.New("[stock.dividends.Execute] CSV output formats not supported")
+	}
+
+	var typed Typed
+	var body []byte
+	switch source {
+	case SourceDividends:
+		typed, body, err = fetch(provider, symbol, format)
+		if err != nil {
+			return "", err
+		}
+	case SourceAdjusted:
+		if format == flags.OutputFormatRawJSON {
+			return "", fmt.Errorf("[stock.dividends.Execute] raw JSON output format not supported with the %q source", source)
+		}
+		series, _, err := price.FetchSeries(provider, symbol, format, flags.IntervalWeekly, true, false)
+		if err != nil {
+			return "", err
+		}
+		typed = FromSeries(series)
+	default:
+		return "", fmt.Errorf("[stock.dividends.Execute] invalid source %q (possible values are %q and %q)", source, SourceDividends, SourceAdjusted)
+	}
+
+	return GenerateOutput(typed, body, holding, name, options, beginTime, endTime, format)
+}
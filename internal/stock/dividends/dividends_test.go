@@ -0,0 +1,266 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package dividends
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+// sources combines the Alpha Vantage implementations of the dividends, of the prices and of the currency of the stocks.
+type sources struct {
+	price.AlphaVantage
+}
+
+func (sources) StockCurrency(symbol string) (string, error) {
+	return search.AlphaVantage{}.StockCurrency(symbol)
+}
+
+func (sources) Dividends(symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	return AlphaVantage{}.Dividends(symbol, format)
+}
+
+func TestDividends(t *testing.T) {
+	internal.ApiKey = "demo"
+	options := DefaultOptions()
+
+	t.Run("success", func(t *testing.T) {
+		expected := `2024-12-10 IBM dividend  ; ex-dividend:2024-11-12, quantity:10, per-share:1.67
+    assets:cash              16.70 NIL
+    income:dividends        -16.70 NIL
+
+2025-03-10 IBM dividend  ; ex-dividend:2025-02-10, quantity:10, per-share:1.67
+    assets:cash              16.70 NIL
+    income:dividends        -16.70 NIL
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, Quantity(decimal.New(10, 0)), options, flags.OutputFormatHledger, "2024-10-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("withholding tax", func(t *testing.T) {
+		withheld := options
+		withheld.WithholdingTax = decimal.New(15, 0)
+		expected := `2025-03-10 IBM dividend  ; ex-dividend:2025-02-10, quantity:7, per-share:1.67
+    assets:cash               9.94 NIL
+    expenses:taxes            1.75 NIL
+    income:dividends        -11.69 NIL
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, Quantity(decimal.New(7, 0)), withheld, flags.OutputFormatHledger, "2025-01-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("fractional quantity from journal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "main.journal")
+		content := `2024-09-01 buy
+    assets:broker   0.1 IBM @ 200 USD
+    assets:cash
+
+2024-10-01 buy
+    assets:broker   0.2 IBM @ 210 USD
+    assets:cash
+`
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		j, err := journal.Parse(path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		withheld := options
+		withheld.WithholdingTax = decimal.MustParse("12.5")
+		// The quantities are exact, so 0.1 + 0.2 gives 0.3 shares and not 0.30000000000000004.
+		expected := `2025-03-10 IBM dividend  ; ex-dividend:2025-02-10, quantity:0.3, per-share:1.67
+    assets:cash               0.44 NIL
+    expenses:taxes            0.06 NIL
+    income:dividends         -0.50 NIL
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, JournalHolding(j, "IBM", "assets:broker"), withheld, flags.OutputFormatHledger, "2025-01-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("quantity from journal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "main.journal")
+		content := `2024-09-01 buy
+    assets:broker   10 IBM @ 200 USD
+    assets:cash
+
+2025-02-10 buy on the ex-dividend date
+    assets:broker   5 IBM @ 250 USD
+    assets:cash
+`
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		j, err := journal.Parse(path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// The dividend of August is skipped since there were no stocks held then.
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, JournalHolding(j, "IBM", "assets:broker"), options, flags.OutputFormatHledger, "2024-01-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if strings.Count(output, "dividend  ;") != 2 || strings.Contains(output, "quantity:15") {
+			t.Errorf("expected 2 dividends of 10 stocks, got %s", output)
+		}
+	})
+
+	t.Run("adjusted source", func(t *testing.T) {
+		expected := `2025-02-14 IBM dividend  ; ex-dividend:2025-02-14, quantity:2, per-share:1.67
+    assets:cash               3.34 NIL
+    income:dividends         -3.34 NIL
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceAdjusted, Quantity(decimal.New(2, 0)), options, flags.OutputFormatHledger, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("table without quantity", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, nil, options, flags.OutputFormatTableLong, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.Contains(output, "1962-02-06") || strings.Contains(output, "QUANTITY") {
+			t.Errorf("expected every dividend without quantity, got %s", output)
+		}
+	})
+
+	t.Run("success JSON", func(t *testing.T) {
+		withheld := options
+		withheld.WithholdingTax = decimal.New(15, 0)
+		expected := `[
+  {
+    "symbol": "IBM",
+    "currency": "NIL",
+    "ex_dividend_date": "2025-02-10",
+    "declaration_date": "2025-01-28",
+    "record_date": "2025-02-10",
+    "payment_date": "2025-03-10",
+    "amount": 1.67,
+    "quantity": 7,
+    "gross": 11.69,
+    "withholding_tax": 1.75,
+    "net": 9.94
+  }
+]
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, Quantity(decimal.New(7, 0)), withheld, flags.OutputFormatJSON, "2025-01-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("JSON without quantity", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, nil, options, flags.OutputFormatJSON, "1962-01-01", "1962-12-31")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.Contains(output, `"payment_date": null`) || !strings.Contains(output, `"net": null`) {
+			t.Errorf("expected null payment date and amounts, got %s", output)
+		}
+	})
+
+	t.Run("raw JSON", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceDividends, nil, options, flags.OutputFormatRawJSON, "2025-01-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.Contains(output, `"payment_date": "None"`) {
+			t.Errorf("expected the raw body, got %s", output)
+		}
+	})
+
+	t.Run("raw JSON with adjusted source", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceAdjusted, nil, options, flags.OutputFormatRawJSON, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("hledger without quantity", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceDividends, nil, options, flags.OutputFormatHledger, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		_, err := Execute(sources{}, "UNKNOWN", "UNKNOWN", SourceDividends, Quantity(decimal.New(1, 0)), options, flags.OutputFormatHledger, "", "")
+		if !errors.Is(err, internal.ErrUnknownSymbol) {
+			t.Errorf("expected %v, got %v", internal.ErrUnknownSymbol, err)
+		}
+	})
+
+	t.Run("invalid source", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", "invalid", Quantity(decimal.New(1, 0)), options, flags.OutputFormatHledger, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("CSV output format", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceDividends, Quantity(decimal.New(1, 0)), options, flags.OutputFormatCSV, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	internal.ApiKey = ""
+
+	t.Run("no API key", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceDividends, Quantity(decimal.New(1, 0)), options, flags.OutputFormatHledger, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
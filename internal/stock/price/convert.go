@@ -0,0 +1,168 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package price
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	// The time zones of the intraday series must be known even on systems without a time zone database.
+	_ "time/tzdata"
+
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+)
+
+// Sources is implemented by the providers able to return both the stock prices and the exchange rates needed to
+// convert them into another currency.
+type Sources interface {
+	Provider
+	currencyRate.Provider
+}
+
+// rateFinder returns the exchange rate to apply to the prices of a given date. The timestamps of the intraday prices
+// must be given in UTC (see instant).
+type rateFinder func(date time.Time) (decimal.Decimal, bool)
+
+// location returns the time zone of the timestamps of an intraday series (e.g. "US/Eastern" for the stocks and "UTC"
+// for the exchange rates of Alpha Vantage). A series without a time zone is considered to be in UTC.
+func location(zone string) (*time.Location, error) {
+	if zone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("[stock.price.location] unknown time zone %q: %w", zone, err)
+	}
+	return loc, nil
+}
+
+// instant returns the timestamp of an intraday series, given in the time zone `zone`, in UTC. The timestamps are
+// parsed without their time zone, so only their wall clock is meaningful.
+func instant(date time.Time, zone *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), zone).UTC()
+}
+
+// newRateFinder returns a rateFinder over the closing exchange rates of a series. The rate of the same period is used
+// when there is one, otherwise the last known rate before the date (fill-forward), since the FX market is not open on
+// the same days as every stock exchange. The intraday rates are matched in UTC, since the stock exchanges and the FX
+// market do not give their timestamps in the same time zone.
+func newRateFinder(rates currencyRate.Series, interval flags.Interval) (rateFinder, error) {
+	zone := time.UTC
+	if interval.Intraday() {
+		var err error
+		zone, err = location(rates.MetaData.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type point struct {
+		date  time.Time
+		close decimal.Decimal
+	}
+	periods := make(map[string]decimal.Decimal, len(rates.TimeSeries))
+	points := make([]point, 0, len(rates.TimeSeries))
+	for date, prices := range rates.TimeSeries {
+		if interval.Intraday() {
+			date = instant(date, zone)
+		}
+		periods[currencyRate.Period(date, interval)] = prices.Close
+		points = append(points, point{date: date, close: prices.Close})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].date.Before(points[j].date)
+	})
+
+	return func(date time.Time) (decimal.Decimal, bool) {
+		if rate, ok := periods[currencyRate.Period(date, interval)]; ok {
+			return rate, true
+		}
+		// Index of the first exchange rate after the date.
+		i := sort.Search(len(points), func(i int) bool {
+			return points[i].date.After(date)
+		})
+		if i == 0 {
+			return decimal.Decimal{}, false
+		}
+		return points[i-1].close, true
+	}, nil
+}
+
+// Convert returns the series with its prices converted with the given exchange rates, which must be from the currency
+// of the series. The volumes and split coefficients are kept as is. The prices older than the first exchange rate are
+// dropped, since they cannot be converted. The intraday prices are matched with the rates of the same instant, whatever
+// the time zones of both series.
+func Convert(series Series, rates currencyRate.Series, interval flags.Interval) (Series, error) {
+	if rates.MetaData.FromSymbol != series.MetaData.Currency {
+		return Series{}, fmt.Errorf("[stock.price.Convert] exchange rates from %s cannot convert prices in %s",
+			rates.MetaData.FromSymbol, series.MetaData.Currency)
+	}
+
+	find, err := newRateFinder(rates, interval)
+	if err != nil {
+		return Series{}, err
+	}
+	rate := find
+	if interval.Intraday() {
+		zone, err := location(series.MetaData.TimeZone)
+		if err != nil {
+			return Series{}, err
+		}
+		rate = func(date time.Time) (decimal.Decimal, bool) {
+			return find(instant(date, zone))
+		}
+	}
+	converted := series
+	converted.MetaData.Currency = rates.MetaData.ToSymbol
+	converted.ConvertedFrom = series.MetaData.Currency
+
+	if series.Adjusted {
+		converted.TimeSeriesAdjusted = make(map[time.Time]TypedPricesAdjusted, len(series.TimeSeriesAdjusted))
+		for date, prices := range series.TimeSeriesAdjusted {
+			r, ok := rate(date)
+			if !ok {
+				continue
+			}
+			prices.Open = prices.Open.Mul(r)
+			prices.High = prices.High.Mul(r)
+			prices.Low = prices.Low.Mul(r)
+			prices.Close = prices.Close.Mul(r)
+			prices.AdjustedClose = prices.AdjustedClose.Mul(r)
+			prices.DividendAmount = prices.DividendAmount.Mul(r)
+			converted.TimeSeriesAdjusted[date] = prices
+		}
+		return converted, nil
+	}
+
+	converted.TimeSeries = make(map[time.Time]TypedPrices, len(series.TimeSeries))
+	for date, prices := range series.TimeSeries {
+		r, ok := rate(date)
+		if !ok {
+			continue
+		}
+		prices.Open = prices.Open.Mul(r)
+		prices.High = prices.High.Mul(r)
+		prices.Low = prices.Low.Mul(r)
+		prices.Close = prices.Close.Mul(r)
+		converted.TimeSeries[date] = prices
+	}
+	return converted, nil
+}
@@ -21,6 +21,7 @@ package price
 import (
 	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,15 +30,46 @@ import (
 	"github.com/jedib0t/go-pretty/v6/table"
 
 	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/pool"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
 	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
 )
 
 type Response interface {
 	TypeBody() error
-	GenerateOutput(body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error)
+	ParseBody(body []byte) (Series, error)
 }
 
+// Series is the typed time series of prices of a stock, independent of the provider it came from and of the interval
+// between each point. Only one of the maps is filled, depending on whether the prices are adjusted or not.
+// Intraday series have a timestamp instead of a date as key, and are never adjusted.
+// ConvertedFrom is the original currency of the prices, if they were converted into another one (see Convert).
+type Series struct {
+	MetaData           TypedMetadata
+	Adjusted           bool
+	Intraday           bool
+	ConvertedFrom      string
+	Source             string
+	Fetched            time.Time
+	TimeSeries         map[time.Time]TypedPrices
+	TimeSeriesAdjusted map[time.Time]TypedPricesAdjusted
+}
+
+// Provider is implemented by every price source able to return a time series of stock prices.
+// The raw body is returned alongside the typed series so the "raw-json" and "raw-csv" output formats can be served
+// as is. When one of those formats is requested, providers are free to return an empty Series.
+// A provider whose prices do not come with their currency leaves it empty, in which case it is looked up with
+// StockCurrency and the prices are taken as quoted in it (see FetchSeries).
+type Provider interface {
+	StockSeries(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (Series, []byte, error)
+	search.CurrencyProvider
+}
+
+// AlphaVantage implements Provider using the TIME_SERIES_* endpoints of the Alpha Vantage API.
+type AlphaVantage struct{}
+
 type RawMetadata struct {
 	Information   string `json:"1. Information"`
 	Symbol        string `json:"2. Symbol"`
@@ -45,10 +77,13 @@ type RawMetadata struct {
 	TimeZone      string `json:"4. Time Zone"`
 }
 
+// TypedMetadata is the metadata of a series of stock prices. The prices quoted in a minor unit of a currency are
+// converted to its major unit (see internal.MajorUnit), in which case Divisor is the number they were divided by.
 type TypedMetadata struct {
 	Information   string
 	Symbol        string
 	Currency      string
+	Divisor       decimal.Decimal
 	LastRefreshed time.Time
 	TimeZone      string
 }
@@ -64,11 +99,6 @@ func (typed *TypedMetadata) TypeBody(raw RawMetadata) error {
 	typed.LastRefreshed = lastRefreshed
 	typed.TimeZone = raw.TimeZone
 
-	typed.Currency, err = search.GetCurrency(typed.Symbol)
-	if err != nil {
-		return fmt.Errorf("[stock.price.(*TypedMetadata).TypeBody] error getting currency: %w", err)
-	}
-
 	return nil
 }
 
@@ -84,6 +114,7 @@ type TypedMetadataDaily struct {
 	Information   string
 	Symbol        string
 	Currency      string
+	Divisor       decimal.Decimal
 	LastRefreshed time.Time
 	OutputSize    string
 	TimeZone      string
@@ -100,14 +131,21 @@ func (typed *TypedMetadataDaily) TypeBody(raw RawMetadataDaily) error {
 	typed.LastRefreshed = lastRefreshed
 	typed.TimeZone = raw.TimeZone
 
-	typed.Currency, err = search.GetCurrency(typed.Symbol)
-	if err != nil {
-		return fmt.Errorf("[stock.price.(*TypedMetadataDaily).TypeBody] error getting currency: %w", err)
-	}
-
 	return nil
 }
 
+// toTypedMetadata drops the fields specific to the daily metadata, so it can be used in a Series.
+func (typed *TypedMetadataDaily) toTypedMetadata() TypedMetadata {
+	return TypedMetadata{
+		Information:   typed.Information,
+		Symbol:        typed.Symbol,
+		Currency:      typed.Currency,
+		Divisor:       typed.Divisor,
+		LastRefreshed: typed.LastRefreshed,
+		TimeZone:      typed.TimeZone,
+	}
+}
+
 type RawPrices struct {
 	Open   string `json:"1. open"`
 	High   string `json:"2. high"`
@@ -117,27 +155,27 @@ type RawPrices struct {
 }
 
 type TypedPrices struct {
-	Open   float64
-	High   float64
-	Low    float64
-	Close  float64
+	Open   decimal.Decimal
+	High   decimal.Decimal
+	Low    decimal.Decimal
+	Close  decimal.Decimal
 	Volume uint32
 }
 
 func (typed *TypedPrices) TypeBody(raw RawPrices) error {
-	openPrice, err := strconv.ParseFloat(raw.Open, 64)
+	openPrice, err := decimal.Parse(raw.Open)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPrices).TypeBody] error parsing open price: %w", err)
 	}
-	highPrice, err := strconv.ParseFloat(raw.High, 64)
+	highPrice, err := decimal.Parse(raw.High)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPrices).TypeBody] error parsing high price: %w", err)
 	}
-	lowPrice, err := strconv.ParseFloat(raw.Low, 64)
+	lowPrice, err := decimal.Parse(raw.Low)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPrices).TypeBody] error parsing low price: %w", err)
 	}
-	closePrice, err := strconv.ParseFloat(raw.Close, 64)
+	closePrice, err := decimal.Parse(raw.Close)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPrices).TypeBody] error parsing close price: %w", err)
 	}
@@ -155,6 +193,14 @@ func (typed *TypedPrices) TypeBody(raw RawPrices) error {
 	return nil
 }
 
+// divide converts the prices into the major unit of their currency (see internal.MajorUnit).
+func (typed *TypedPrices) divide(divisor decimal.Decimal) {
+	typed.Open = typed.Open.Div(divisor)
+	typed.High = typed.High.Div(divisor)
+	typed.Low = typed.Low.Div(divisor)
+	typed.Close = typed.Close.Div(divisor)
+}
+
 type RawPricesAdjusted struct {
 	Open             string `json:"1. open"`
 	High             string `json:"2. high"`
@@ -163,37 +209,38 @@ type RawPricesAdjusted struct {
 	AdjustedClose    string `json:"5. adjusted close"`
 	Volume           string `json:"6. volume"`
 	DividendAmount   string `json:"7. dividend amount"`
-	SplitCoefficient string `json:"-"`
+	SplitCoefficient string `json:"8. split coefficient"` // Only in the daily adjusted series.
 }
 
 type TypedPricesAdjusted struct {
-	Open           float64
-	High           float64
-	Low            float64
-	Close          float64
-	AdjustedClose  float64
-	Volume         uint32
-	DividendAmount float64
+	Open             decimal.Decimal
+	High             decimal.Decimal
+	Low              decimal.Decimal
+	Close            decimal.Decimal
+	AdjustedClose    decimal.Decimal
+	Volume           uint32
+	DividendAmount   decimal.Decimal
+	SplitCoefficient decimal.Decimal // Zero when the series does not give it.
 }
 
 func (typed *TypedPricesAdjusted) TypeBody(raw RawPricesAdjusted) error {
-	openPrice, err := strconv.ParseFloat(raw.Open, 64)
+	openPrice, err := decimal.Parse(raw.Open)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing open price: %w", err)
 	}
-	highPrice, err := strconv.ParseFloat(raw.High, 64)
+	highPrice, err := decimal.Parse(raw.High)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing high price: %w", err)
 	}
-	lowPrice, err := strconv.ParseFloat(raw.Low, 64)
+	lowPrice, err := decimal.Parse(raw.Low)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing low price: %w", err)
 	}
-	closePrice, err := strconv.ParseFloat(raw.Close, 64)
+	closePrice, err := decimal.Parse(raw.Close)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing close price: %w", err)
 	}
-	adjustedClose, err := strconv.ParseFloat(raw.AdjustedClose, 64)
+	adjustedClose, err := decimal.Parse(raw.AdjustedClose)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing adjusted close price: %w", err)
 	}
@@ -201,10 +248,17 @@ func (typed *TypedPricesAdjusted) TypeBody(raw RawPricesAdjusted) error {
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing volume: %w", err)
 	}
-	dividendAmount, err := strconv.ParseFloat(raw.DividendAmount, 64)
+	dividendAmount, err := decimal.Parse(raw.DividendAmount)
 	if err != nil {
 		return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing dividend amount: %w", err)
 	}
+	var splitCoefficient decimal.Decimal
+	if raw.SplitCoefficient != "" {
+		splitCoefficient, err = decimal.Parse(raw.SplitCoefficient)
+		if err != nil {
+			return fmt.Errorf("[stock.price.(*TypedPricesAdjusted).TypeBody] error parsing split coefficient: %w", err)
+		}
+	}
 
 	typed.Open = openPrice
 	typed.High = highPrice
@@ -213,10 +267,21 @@ func (typed *TypedPricesAdjusted) TypeBody(raw RawPricesAdjusted) error {
 	typed.AdjustedClose = adjustedClose
 	typed.Volume = uint32(volume)
 	typed.DividendAmount = dividendAmount
+	typed.SplitCoefficient = splitCoefficient
 
 	return nil
 }
 
+// divide converts the prices and the dividend amount into the major unit of their currency (see internal.MajorUnit).
+func (typed *TypedPricesAdjusted) divide(divisor decimal.Decimal) {
+	typed.Open = typed.Open.Div(divisor)
+	typed.High = typed.High.Div(divisor)
+	typed.Low = typed.Low.Div(divisor)
+	typed.Close = typed.Close.Div(divisor)
+	typed.AdjustedClose = typed.AdjustedClose.Div(divisor)
+	typed.DividendAmount = typed.DividendAmount.Div(divisor)
+}
+
 // buildURL creates the URL to make the HTTP request to the Alpha Vantage API.
 func buildURL(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (string, error) {
 	if internal.ApiKey == "" {
@@ -225,18 +290,25 @@ func buildURL(symbol string, format flags.OutputFormat, interval flags.Interval,
 	if symbol == "" {
 		return "", errors.New("[price.buildURL] no search query provided")
 	}
-	switch format {
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong, flags.OutputFormatJSON, flags.OutputFormatCSV:
-		// Do nothing.
-	default:
-		return "", errors.New("[price.buildURL] invalid output format")
+	if _, ok := render.Lookup(format); !ok {
+		switch format {
+		case flags.OutputFormatTable, flags.OutputFormatTableLong, flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+			// Do nothing.
+		default:
+			return "", errors.New("[price.buildURL] invalid output format")
+		}
 	}
 
 	url := strings.Builder{}
 	url.WriteString(internal.ApiBaseUrl)
 	url.WriteString("function=")
 
-	if adjusted {
+	if interval.Intraday() {
+		if adjusted {
+			return "", errors.New("[internal.price.buildURL] adjusted prices are not available for intraday intervals")
+		}
+		url.WriteString(apiFunctionTimeSeriesIntraday)
+	} else if adjusted {
 		switch interval {
 		case flags.IntervalDaily:
 			url.WriteString(apiFunctionTimeSeriesDailyAdjusted)
@@ -262,6 +334,10 @@ func buildURL(symbol string, format flags.OutputFormat, interval flags.Interval,
 
 	url.WriteString("&symbol=")
 	url.WriteString(symbol)
+	if interval.Intraday() {
+		url.WriteString("&interval=")
+		url.WriteString(string(interval))
+	}
 
 	// Print entire time series if daily, because user can then limit the interval with `begin` and `end`.
 	// FIXME: Recently, the API started to lockdown the full time series for free users, so this needs to be fixed.
@@ -269,13 +345,13 @@ func buildURL(symbol string, format flags.OutputFormat, interval flags.Interval,
 	//				{
 	//						"Information": "Thank you for using Alpha Vantage! The outputsize=full parameter value is a premium feature for the TIME_SERIES_DAILY endpoint. You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly unlock all premium features"
 	//				}%
-	if interval == flags.IntervalDaily && full {
+	if (interval == flags.IntervalDaily || interval.Intraday()) && full {
 		url.WriteString("&outputsize=full")
 	}
 	url.WriteString("&apikey=")
 	url.WriteString(internal.ApiKey)
 
-	if format == flags.OutputFormatCSV {
+	if format == flags.OutputFormatRawCSV {
 		url.WriteString("&datatype=csv")
 	}
 
@@ -287,6 +363,13 @@ func buildURL(symbol string, format flags.OutputFormat, interval flags.Interval,
 func createResponseObject(interval flags.Interval, adjusted bool) (Response, error) {
 	var obj Response
 
+	if interval.Intraday() {
+		if adjusted {
+			return obj, errors.New("[stock.price.createResponseObject] adjusted prices are not available for intraday intervals")
+		}
+		return &Intraday{Interval: interval}, nil
+	}
+
 	if adjusted {
 		switch interval {
 		case flags.IntervalDaily:
@@ -315,10 +398,12 @@ func createResponseObject(interval flags.Interval, adjusted bool) (Response, err
 }
 
 // getDatesNormal returns the dates in the time series that are within the specified interval.
+// Intraday timestamps are compared by their day, so the prices of the whole `end` day are kept.
 func getDatesNormal(timeSeries map[time.Time]TypedPrices, begin time.Time, end time.Time) []time.Time {
 	var dates []time.Time
 	for date := range timeSeries {
-		if !(date.Before(begin) || date.After(end)) {
+		day := date.Truncate(24 * time.Hour)
+		if !(day.Before(begin) || day.After(end)) {
 			dates = append(dates, date)
 		}
 	}
@@ -348,57 +433,81 @@ func getDatesAdjusted(timeSeries map[time.Time]TypedPricesAdjusted, begin time.T
 	return dates
 }
 
-// generateOutputHledgerNormal generates the output in hledger format for non-adjusted prices.
-func generateOutputHledgerNormal(timeSeries map[time.Time]TypedPrices, dates []time.Time, symbol string, currency string) string {
-	out := strings.Builder{}
-	for _, date := range dates {
-		out.WriteString(fmt.Sprintf("P %s \"%s\" %.2f %s\n",
-			date.Format("2006-01-02"),
-			symbol,
-			timeSeries[date].Close,
-			currency))
+// getDates returns the dates of the series that are within the interval defined by `begin` and `end`.
+func getDates(series Series, begin time.Time, end time.Time) []time.Time {
+	if series.Adjusted {
+		return getDatesAdjusted(series.TimeSeriesAdjusted, begin, end)
 	}
-	return out.String()
+	return getDatesNormal(series.TimeSeries, begin, end)
 }
 
-// generateOutputHledgerAdjusted generates the output in hledger format for adjusted prices.
-func generateOutputHledgerAdjusted(timeSeries map[time.Time]TypedPricesAdjusted, dates []time.Time, symbol string, currency string) string {
-	out := strings.Builder{}
+// records converts the prices of the given dates into records, using the adjusted close price as the amount of the
+// adjusted series. The price of the day of the last refresh gets its time, while intraday prices have their own.
+func records(series Series, dates []time.Time) []render.Record {
+	lastRefreshed := series.MetaData.LastRefreshed
+	if series.Intraday {
+		lastRefreshed = time.Time{}
+	}
+
+	records := make([]render.Record, 0, len(dates))
 	for _, date := range dates {
-		out.WriteString(fmt.Sprintf("P %s \"%s\" %.2f %s\n",
-			date.Format("2006-01-02"),
-			symbol,
-			timeSeries[date].AdjustedClose, // Adjusted close price instead of close price.
-			currency))
+		record := render.Record{
+			Date:      render.Timestamp(date, lastRefreshed),
+			Commodity: series.MetaData.Symbol,
+			Currency:  series.MetaData.Currency,
+			Source:    series.Source,
+			Fetched:   series.Fetched,
+			Stock:     true,
+		}
+		if series.Adjusted {
+			prices := series.TimeSeriesAdjusted[date]
+			record.Amount = prices.AdjustedClose
+			record.Open, record.High, record.Low, record.Close = prices.Open, prices.High, prices.Low, prices.Close
+			record.Volume = decimal.New(int64(prices.Volume), 0)
+			record.AdjustedClose, record.Dividend = prices.AdjustedClose, prices.DividendAmount
+			record.Given = render.FieldsRange | render.FieldsVolume | render.FieldsAdjusted
+		} else {
+			prices := series.TimeSeries[date]
+			record.Amount = prices.Close
+			record.Open, record.High, record.Low, record.Close = prices.Open, prices.High, prices.Low, prices.Close
+			record.Volume = decimal.New(int64(prices.Volume), 0)
+			record.Given = render.FieldsRange | render.FieldsVolume
+		}
+		records = append(records, record)
 	}
-	return out.String()
+	return records
 }
 
 // generateMetadataTable generates a table with the metadata for a given stock symbol. It is used to display
 // the information about the stock before the table with the stock prices.
-func generateMetadataTable(symbol string, currency string, lastRefreshed time.Time, timeZone string) string {
+// The original currency is shown alongside the currency of the converted prices.
+func generateMetadataTable(symbol string, currency string, convertedFrom string, lastRefreshed time.Time, timeZone string, layout string) string {
+	if convertedFrom != "" {
+		currency = fmt.Sprintf("%s (from %s)", currency, convertedFrom)
+	}
+
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	t.AppendHeader(table.Row{"Symbol", "Currency", "Last Refreshed", "Timezone"})
-	t.AppendRow(table.Row{symbol, currency, lastRefreshed.Format("2006-01-02"), timeZone})
+	t.AppendRow(table.Row{symbol, currency, lastRefreshed.Format(layout), timeZone})
 	return t.Render() + "\n"
 }
 
 // generateTimeSeriesTableShort generates a short table with the prices for a given stock symbol.
 // It is used to display the stock prices in a compact way.
 // Note that for non-adjusted prices this output format is used both in `table` and `table-long`.
-func generateTimeSeriesTableShort(timeSeries map[time.Time]TypedPrices, dates []time.Time) string {
+func generateTimeSeriesTableShort(timeSeries map[time.Time]TypedPrices, dates []time.Time, symbol string, currency string, layout string) string {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	t.AppendHeader(table.Row{"Date", "Open", "High", "Low", "Close", "Volume"})
 	for _, date := range dates {
 		prices := timeSeries[date]
 		t.AppendRow([]interface{}{
-			date.Format("2006-01-02"),
-			fmt.Sprintf("%.2f", prices.Open),
-			fmt.Sprintf("%.2f", prices.High),
-			fmt.Sprintf("%.2f", prices.Low),
-			fmt.Sprintf("%.2f", prices.Close),
+			date.Format(layout),
+			internal.FormatPrice(prices.Open, symbol, currency),
+			internal.FormatPrice(prices.High, symbol, currency),
+			internal.FormatPrice(prices.Low, symbol, currency),
+			internal.FormatPrice(prices.Close, symbol, currency),
 			prices.Volume,
 		})
 	}
@@ -407,7 +516,7 @@ func generateTimeSeriesTableShort(timeSeries map[time.Time]TypedPrices, dates []
 
 // generateTimeSeriesTableShortAdjusted generates a table with the adjusted prices for a given stock symbol.
 // It is used to display the stock prices in a compact way, but only for adjusted prices output.
-func generateTimeSeriesTableShortAdjusted(timeSeries map[time.Time]TypedPricesAdjusted, dates []time.Time) string {
+func generateTimeSeriesTableShortAdjusted(timeSeries map[time.Time]TypedPricesAdjusted, dates []time.Time, symbol string, currency string) string {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	t.AppendHeader(table.Row{"Date", "Open", "High", "Low", "Close", "Adj. Close", "Volume"})
@@ -415,11 +524,11 @@ func generateTimeSeriesTableShortAdjusted(timeSeries map[time.Time]TypedPricesAd
 		prices := timeSeries[date]
 		t.AppendRow([]interface{}{
 			date.Format("2006-01-02"),
-			fmt.Sprintf("%.2f", prices.Open),
-			fmt.Sprintf("%.2f", prices.High),
-			fmt.Sprintf("%.2f", prices.Low),
-			fmt.Sprintf("%.2f", prices.Close),
-			fmt.Sprintf("%.2f", prices.AdjustedClose),
+			internal.FormatPrice(prices.Open, symbol, currency),
+			internal.FormatPrice(prices.High, symbol, currency),
+			internal.FormatPrice(prices.Low, symbol, currency),
+			internal.FormatPrice(prices.Close, symbol, currency),
+			internal.FormatPrice(prices.AdjustedClose, symbol, currency),
 			prices.Volume,
 		})
 	}
@@ -428,50 +537,293 @@ func generateTimeSeriesTableShortAdjusted(timeSeries map[time.Time]TypedPricesAd
 
 // generateTimeSeriesTableLongAdjusted generates a long table with the adjusted prices for a given stock symbol.
 // It is used to display the stock prices in a detailed way, but only for adjusted prices output.
-func generateTimeSeriesTableLongAdjusted(timeSeries map[time.Time]TypedPricesAdjusted, dates []time.Time) string {
+// The split coefficients are only shown for the series that give them (i.e. the daily series).
+func generateTimeSeriesTableLongAdjusted(timeSeries map[time.Time]TypedPricesAdjusted, dates []time.Time, symbol string, currency string) string {
+	splits := false
+	for _, prices := range timeSeries {
+		if !prices.SplitCoefficient.IsZero() {
+			splits = true
+			break
+		}
+	}
+
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
-	t.AppendHeader(table.Row{"Date", "Open", "High", "Low", "Close", "Adj. Close", "Volume", "Dividend Amount"})
+	header := table.Row{"Date", "Open", "High", "Low", "Close", "Adj. Close", "Volume", "Dividend Amount"}
+	if splits {
+		header = append(header, "Split Coefficient")
+	}
+	t.AppendHeader(header)
 	for _, date := range dates {
 		prices := timeSeries[date]
-		t.AppendRow([]interface{}{
+		row := table.Row{
 			date.Format("2006-01-02"),
-			fmt.Sprintf("%.2f", prices.Open),
-			fmt.Sprintf("%.2f", prices.High),
-			fmt.Sprintf("%.2f", prices.Low),
-			fmt.Sprintf("%.2f", prices.Close),
-			fmt.Sprintf("%.2f", prices.AdjustedClose),
+			internal.FormatPrice(prices.Open, symbol, currency),
+			internal.FormatPrice(prices.High, symbol, currency),
+			internal.FormatPrice(prices.Low, symbol, currency),
+			internal.FormatPrice(prices.Close, symbol, currency),
+			internal.FormatPrice(prices.AdjustedClose, symbol, currency),
 			prices.Volume,
-			fmt.Sprintf("%.2f", prices.DividendAmount),
-		})
+			internal.FormatPrice(prices.DividendAmount, symbol, currency),
+		}
+		if splits {
+			row = append(row, prices.SplitCoefficient.Trim(0).String())
+		}
+		t.AppendRow(row)
 	}
 	return t.Render() + "\n"
 }
 
-// TODO Continue implementing unitary tests for this
+// GenerateOutput renders a series in the desired format, keeping only the dates between `begin` and `end`.
+// The "raw-json" and "raw-csv" formats return the raw body given by the provider.
+func GenerateOutput(series Series, body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
+	// The formats made of one directive per price are written by the formatters of the render package.
+	if _, ok := render.Lookup(format); ok {
+		return render.Render(format, records(series, getDates(series, begin, end)), "")
+	}
 
-// Execute is the core function of the price package. It fetches the stock prices from the Alpha Vantage API for a given
-// stock symbol and returns it in the desired format.
-func Execute(symbol string, format flags.OutputFormat, interval flags.Interval, begin string, end string, adjusted bool, full bool) (string, error) {
-	beginTime, endTime, err := internal.ValidateDates(begin, end)
-	if err != nil {
-		return "", err
+	switch format {
+	case flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+		return string(body), nil
+	case flags.OutputFormatTable, flags.OutputFormatTableLong:
+		// Do nothing.
+	default:
+		return "", errors.New("[stock.price.GenerateOutput] invalid output format")
+	}
+
+	if series.Adjusted {
+		dates := getDatesAdjusted(series.TimeSeriesAdjusted, begin, end)
+
+		out := strings.Builder{}
+		out.WriteString(generateMetadataTable(
+			series.MetaData.Symbol,
+			series.MetaData.Currency,
+			series.ConvertedFrom,
+			series.MetaData.LastRefreshed,
+			series.MetaData.TimeZone,
+			"2006-01-02"))
+		if format == flags.OutputFormatTable {
+			out.WriteString(generateTimeSeriesTableShortAdjusted(
+				series.TimeSeriesAdjusted,
+				dates,
+				series.MetaData.Symbol,
+				series.MetaData.Currency))
+		} else {
+			out.WriteString(generateTimeSeriesTableLongAdjusted(
+				series.TimeSeriesAdjusted,
+				dates,
+				series.MetaData.Symbol,
+				series.MetaData.Currency))
+		}
+		return out.String(), nil
+	}
+
+	dates := getDatesNormal(series.TimeSeries, begin, end)
+
+	// Intraday prices are shown with their time in the tables.
+	layout := "2006-01-02"
+	if series.Intraday {
+		layout = "2006-01-02 15:04"
 	}
 
+	out := strings.Builder{}
+	out.WriteString(generateMetadataTable(
+		series.MetaData.Symbol,
+		series.MetaData.Currency,
+		series.ConvertedFrom,
+		series.MetaData.LastRefreshed,
+		series.MetaData.TimeZone,
+		layout))
+	out.WriteString(generateTimeSeriesTableShort(
+		series.TimeSeries,
+		dates,
+		series.MetaData.Symbol,
+		series.MetaData.Currency,
+		layout))
+	return out.String(), nil
+}
+
+// StockSeries fetches the stock prices from the Alpha Vantage API and casts them into a Series.
+func (AlphaVantage) StockSeries(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (Series, []byte, error) {
 	url, err := buildURL(symbol, format, interval, adjusted, full)
 	if err != nil {
-		return "", err
+		return Series{}, nil, err
 	}
 
 	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		// The API does not tell which parameter is wrong, but the symbol is the only one that comes from the user.
+		return Series{}, nil, fmt.Errorf("[stock.price.(AlphaVantage).StockSeries] %w %s: %w", internal.ErrUnknownSymbol, symbol, err)
+	}
 	if err != nil {
-		return "", err
+		return Series{}, nil, err
+	}
+
+	// The raw formats do not need the body to be parsed.
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return Series{}, body, nil
 	}
 
 	response, err := createResponseObject(interval, adjusted)
 	if err != nil {
+		return Series{}, nil, err
+	}
+
+	series, err := response.ParseBody(body)
+	if err != nil {
+		return Series{}, nil, err
+	}
+	if len(series.TimeSeries) == 0 && len(series.TimeSeriesAdjusted) == 0 {
+		return Series{}, nil, fmt.Errorf("[stock.price.(AlphaVantage).StockSeries] %w for %s", internal.ErrEmptyTimeSeries, symbol)
+	}
+	series.Source = internal.SourceAlphaVantage
+	series.Fetched = time.Now()
+
+	return series, body, nil
+}
+
+// TODO Continue implementing unitary tests for this
+
+// inCurrency sets the currency of a series whose prices are quoted in it. The prices quoted in a minor unit of a
+// currency (e.g. pence) are converted to its major unit.
+func (series *Series) inCurrency(currency string) {
+	series.MetaData.Currency, series.MetaData.Divisor = internal.MajorUnit(currency)
+	for date, prices := range series.TimeSeries {
+		prices.divide(series.MetaData.Divisor)
+		series.TimeSeries[date] = prices
+	}
+	for date, prices := range series.TimeSeriesAdjusted {
+		prices.divide(series.MetaData.Divisor)
+		series.TimeSeriesAdjusted[date] = prices
+	}
+}
+
+// FetchSeries gets the stock prices of a symbol from the given provider. When the provider does not give the currency
+// of the stock along with its prices, it is looked up with the same provider.
+func FetchSeries(provider Provider, symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (Series, []byte, error) {
+	series, body, err := provider.StockSeries(symbol, format, interval, adjusted, full)
+	if err != nil {
+		return Series{}, nil, err
+	}
+	// The raw formats are not parsed.
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV || series.MetaData.Currency != "" {
+		return series, body, nil
+	}
+
+	currency, err := provider.StockCurrency(series.MetaData.Symbol)
+	if err != nil {
+		return Series{}, nil, fmt.Errorf("[stock.price.FetchSeries] error getting the currency of %s: %w", symbol, err)
+	}
+	series.inCurrency(currency)
+
+	return series, body, nil
+}
+
+// fetch gets the stock prices of a symbol from the given provider, converted into `to` if it is not empty and differs
+// from the currency of the stock.
+func fetch(provider Sources, symbol string, to string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (Series, []byte, error) {
+	series, body, err := FetchSeries(provider, symbol, format, interval, adjusted, full)
+	if err != nil {
+		return Series{}, nil, err
+	}
+
+	if to != "" && to != series.MetaData.Currency {
+		rates, _, err := provider.FXSeries(series.MetaData.Currency, to, format, interval, full)
+		if err != nil {
+			return Series{}, nil, err
+		}
+		series, err = Convert(series, rates, interval)
+		if err != nil {
+			return Series{}, nil, err
+		}
+	}
+
+	return series, body, nil
+}
+
+// Execute is the core function of the price package. It fetches the stock prices from the given provider for a given
+// stock symbol and returns it in the desired format. If `to` is not empty and differs from the currency of the stock,
+// the prices are converted into it with the exchange rates of the same interval. The formats of the render package use
+// the names of the journal, and name the stock `as` when it is not empty.
+func Execute(provider Sources, symbol string, to string, as string, format flags.OutputFormat, interval flags.Interval, begin string, end string, adjusted bool, full bool) (string, error) {
+	out := strings.Builder{}
+	if err := Write(&out, provider, []string{symbol}, to, as, format, interval, begin, end, adjusted, full); err != nil {
 		return "", err
 	}
+	return out.String(), nil
+}
+
+// fetched is the result of the fetch of the prices of a symbol.
+type fetched struct {
+	series Series
+	body   []byte
+}
+
+// Write is like Execute for one or more stock symbols, but writes the prices of each symbol to `out` as soon as they
+// and the ones of the previous symbols are fetched, so they can be read while the next ones are still being fetched.
+// The symbols are fetched concurrently by at most internal.Workers workers, and written in the order they are given.
+// The formats that write a whole document (e.g. "json") are only written once every symbol is fetched. A symbol that
+// fails does not prevent the others from being written, and the errors of all of them are returned at the end.
+func Write(out io.Writer, provider Sources, symbols []string, to string, as string, format flags.OutputFormat, interval flags.Interval, begin string, end string, adjusted bool, full bool) error {
+	if len(symbols) == 0 {
+		return errors.New("[stock.price.Write] no stock symbol provided")
+	}
+	beginTime, endTime, err := internal.ValidateDates(begin, end)
+	if err != nil {
+		return err
+	}
+
+	// The formats made of one directive per price are written by a writer of the render package, which keeps the
+	// records of the formats that need all of them at once.
+	var writer *render.Writer
+	if _, ok := render.Lookup(format); ok {
+		writer, err = render.NewWriter(out, format, as)
+		if err != nil {
+			return err
+		}
+	} else {
+		switch format {
+		case flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+			if to != "" {
+				return errors.New("[stock.price.Write] raw JSON and CSV output formats not supported when converting the prices")
+			}
+			if len(symbols) > 1 {
+				return errors.New("[stock.price.Write] raw JSON and CSV output formats only support a single stock symbol")
+			}
+		case flags.OutputFormatTable, flags.OutputFormatTableLong:
+			// Do nothing.
+		default:
+			return errors.New("[stock.price.Write] invalid output format")
+		}
+	}
+
+	var errs []error
+	err = pool.Run(len(symbols), internal.Workers, func(i int) (fetched, error) {
+		series, body, err := fetch(provider, symbols[i], to, format, interval, adjusted, full)
+		return fetched{series: series, body: body}, err
+	}, func(i int, result fetched, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[stock.price.Write] failed to fetch prices of %s: %w", symbols[i], err))
+			return nil
+		}
+		if writer != nil {
+			return writer.Write(records(result.series, getDates(result.series, beginTime, endTime)))
+		}
+		output, err := GenerateOutput(result.series, result.body, beginTime, endTime, format)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(out, output)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-	return response.GenerateOutput(body, beginTime, endTime, format)
+	if writer != nil {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
 }
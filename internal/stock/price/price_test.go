@@ -19,35 +19,307 @@
 package price
 
 import (
+	"errors"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lentidas/hledger-price-tracker/internal"
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+// sources combines the Alpha Vantage implementations of the stock prices, of the currency of the stocks and of the
+// exchange rates.
+type sources struct {
+	AlphaVantage
+}
+
+func (sources) StockCurrency(symbol string) (string, error) {
+	return search.AlphaVantage{}.StockCurrency(symbol)
+}
+
+func (sources) FXSeries(from string, to string, format flags.OutputFormat, interval flags.Interval, full bool) (currencyRate.Series, []byte, error) {
+	return currencyRate.AlphaVantage{}.FXSeries(from, to, format, interval, full)
+}
+
+// inUSD overrides the currency of the stocks, which is unknown with the demo API key.
+type inUSD struct {
+	sources
+}
+
+func (p inUSD) StockSeries(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (Series, []byte, error) {
+	series, body, err := p.sources.StockSeries(symbol, format, interval, adjusted, full)
+	series.MetaData.Currency = "USD"
+	return series, body, err
+}
+
+// inPence overrides the lookup of the currency of the stocks, to check that it goes through the given provider.
+type inPence struct {
+	sources
+}
+
+func (inPence) StockCurrency(string) (string, error) {
+	return "GBX", nil
+}
+
 // TODO Add unitary tests for the parsing of the price response, per interval, and adjusted or not.
 
 func TestPrice(t *testing.T) {
 	internal.ApiKey = "demo"
 
-	// t.Run("success", func(t *testing.T) {
-	// 	// TODO Implement expected response.
-	// })
+	t.Run("success", func(t *testing.T) {
+		expected := "P 2025-03-28 \"IBM\" 244.00 NIL\nP 2025-04-04 \"IBM\" 227.48 NIL\n"
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "2025-03-22", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("several symbols", func(t *testing.T) {
+		// The symbols are fetched concurrently, but written in order, and a failure does not stop the others.
+		internal.Workers = 3
+		defer func() { internal.Workers = 0 }()
+
+		expected := strings.Repeat("P 2025-03-28 \"IBM\" 244.00 NIL\nP 2025-04-04 \"IBM\" 227.48 NIL\n", 2)
+		out := strings.Builder{}
+		err := Write(&out, sources{}, []string{"IBM", "UNKNOWN", "IBM"}, "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "2025-03-22", "", false, false)
+		if !errors.Is(err, internal.ErrUnknownSymbol) {
+			t.Errorf("expected %v, got %v", internal.ErrUnknownSymbol, err)
+		}
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("success with period expressions", func(t *testing.T) {
+		// The end is the last day of its period, so all of March is kept.
+		expected := "P 2025-03-21 \"IBM\" 243.05 NIL\nP 2025-03-28 \"IBM\" 244.00 NIL\n"
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "2025Q1", "2025/03", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success intraday", func(t *testing.T) {
+		// Only the last bar of each day is kept.
+		expected := "P 2025-04-03 \"IBM\" 243.95 NIL\nP 2025-04-04 \"IBM\" 227.48 NIL\n"
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatHledger, flags.Interval60Min, "", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success intraday table", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatTable, flags.Interval60Min, "2025-04-04", "2025-04-04", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		for _, timestamp := range []string{"2025-04-04 10:00", "2025-04-04 15:00", "2025-04-04 19:00"} {
+			if !strings.Contains(output, timestamp) {
+				t.Errorf("expected %s in the table, got %s", timestamp, output)
+			}
+		}
+		if strings.Contains(output, "2025-04-03 15:00") {
+			t.Errorf("expected no price before the beginning of the time period, got %s", output)
+		}
+	})
+
+	t.Run("split coefficient table", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatTableLong, flags.IntervalDaily, "1999-05-26", "1999-05-28", true, true)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.Contains(output, "SPLIT COEFFICIENT") || !strings.Contains(output, "│ 2                 │") {
+			t.Errorf("expected the split coefficients in the table, got %s", output)
+		}
+	})
+
+	t.Run("success converted", func(t *testing.T) {
+		expected := "P 2025-03-28 \"IBM\" 222.7964 EUR\nP 2025-04-04 \"IBM\" 207.279776 EUR\n"
+		output, err := Execute(inUSD{}, "IBM", "EUR", "", flags.OutputFormatHledger, flags.IntervalWeekly, "2025-03-22", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success converted into the same currency", func(t *testing.T) {
+		expected := "P 2025-03-28 \"IBM\" 244.00 USD\nP 2025-04-04 \"IBM\" 227.48 USD\n"
+		output, err := Execute(inUSD{}, "IBM", "USD", "", flags.OutputFormatHledger, flags.IntervalWeekly, "2025-03-22", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("converted with raw JSON output format", func(t *testing.T) {
+		if _, err := Execute(inUSD{}, "IBM", "EUR", "", flags.OutputFormatRawJSON, flags.IntervalWeekly, "", "", false, false); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("several symbols with raw JSON output format", func(t *testing.T) {
+		if err := Write(&strings.Builder{}, sources{}, []string{"IBM", "IBM"}, "", "", flags.OutputFormatRawJSON, flags.IntervalWeekly, "", "", false, false); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("currency from the provider", func(t *testing.T) {
+		expected := "P 2025-03-28 \"IBM\" 2.44 GBP\nP 2025-04-04 \"IBM\" 2.2748 GBP\n"
+		output, err := Execute(inPence{}, "IBM", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "2025-03-22", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success beancount", func(t *testing.T) {
+		internal.ApiKey = "test"
+		defer func() { internal.ApiKey = "demo" }()
+
+		expected := "2025-03-28 price TSCO.LON 3.625 GBP\n2025-04-04 price TSCO.LON 3.504 GBP\n"
+		output, err := Execute(sources{}, "TSCO.LON", "", "", flags.OutputFormatBeancount, flags.IntervalWeekly, "", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success ledger", func(t *testing.T) {
+		internal.ApiKey = "test"
+		defer func() { internal.ApiKey = "demo" }()
+
+		expected := "P 2025/03/28 \"TSCO.LON\" 3.625 GBP\nP 2025/04/04 \"TSCO.LON\" 3.504 GBP\n"
+		output, err := Execute(sources{}, "TSCO.LON", "", "", flags.OutputFormatLedger, flags.IntervalWeekly, "", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success csv between dates", func(t *testing.T) {
+		expected := "date,symbol,currency,open,high,low,close,adjusted_close,volume,dividend\n" +
+			"2025-03-28,IBM,NIL,250.06,255.67,242.69,244.00,,17213093,\n"
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatCSV, flags.IntervalWeekly, "2025-03-22", "2025-03-31", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success ndjson between dates", func(t *testing.T) {
+		expected := `{"date":"2025-03-28","symbol":"IBM","currency":"NIL","open":250.06,"high":255.67,"low":242.69,"close":244.00,"adjusted_close":null,"volume":17213093,"dividend":null,"source":"alphavantage","fetched_at":"`
+		output, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatNDJSON, flags.IntervalWeekly, "2025-03-22", "2025-03-31", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.HasPrefix(output, expected) || strings.Count(output, "\n") != 1 {
+			t.Errorf("expected a single line starting with %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success template", func(t *testing.T) {
+		internal.ApiKey = "test"
+		render.Template = "{{.Commodity}} {{.Date.Format \"2006-01-02\"}} {{.Open}} {{.Close}} {{.Volume}} {{.Source}}"
+		defer func() {
+			internal.ApiKey = "demo"
+			render.Template = ""
+		}()
+
+		expected := "TSCO.LON 2025-04-04 3.6210 3.5040 98765432 alphavantage\n"
+		output, err := Execute(sources{}, "TSCO.LON", "", "", flags.OutputFormatTemplate, flags.IntervalWeekly, "2025-04-01", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success in minor units", func(t *testing.T) {
+		// The currency is only known with a real API key, and London quotes in pence.
+		internal.ApiKey = "test"
+		defer func() { internal.ApiKey = "demo" }()
+
+		expected := "P 2025-03-28 \"TSCO.LON\" 3.625 GBP\nP 2025-04-04 \"TSCO.LON\" 3.504 GBP\n"
+		output, err := Execute(sources{}, "TSCO.LON", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+
+		internal.KeepMinorUnits = true
+		defer func() { internal.KeepMinorUnits = false }()
+		expected = "P 2025-03-28 \"TSCO.LON\" 362.50 GBX\nP 2025-04-04 \"TSCO.LON\" 350.40 GBX\n"
+		output, err = Execute(sources{}, "TSCO.LON", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("intraday adjusted", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "", "", flags.OutputFormatHledger, flags.Interval60Min, "", "", true, false); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		_, err := Execute(sources{}, "UNKNOWN", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false)
+		if !errors.Is(err, internal.ErrUnknownSymbol) {
+			t.Errorf("expected %v, got %v", internal.ErrUnknownSymbol, err)
+		}
+	})
 
 	t.Run("no symbol", func(t *testing.T) {
-		if _, err := Execute("", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false); err == nil {
+		if _, err := Execute(sources{}, "", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid output format", func(t *testing.T) {
-		if _, err := Execute("tesco", "invalid", flags.IntervalWeekly, "", "", false, false); err == nil {
+		if _, err := Execute(sources{}, "tesco", "", "", "invalid", flags.IntervalWeekly, "", "", false, false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid interval", func(t *testing.T) {
-		if _, err := Execute("tesco", flags.OutputFormatHledger, "invalid", "", "", false, false); err == nil {
+		if _, err := Execute(sources{}, "tesco", "", "", flags.OutputFormatHledger, "invalid", "", "", false, false); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -55,7 +327,102 @@ func TestPrice(t *testing.T) {
 	internal.ApiKey = ""
 
 	t.Run("no API key", func(t *testing.T) {
-		if _, err := Execute("tesco", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false); err == nil {
+		if _, err := Execute(sources{}, "tesco", "", "", flags.OutputFormatHledger, flags.IntervalWeekly, "", "", false, false); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestConvert(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, time.April, d, 0, 0, 0, 0, time.UTC)
+	}
+	series := Series{
+		MetaData: TypedMetadata{Symbol: "TSCO.LON", Currency: "GBP"},
+		TimeSeries: map[time.Time]TypedPrices{
+			day(1): {Close: decimal.New(1, 0)},
+			day(2): {Close: decimal.New(2, 0)},
+			day(3): {Close: decimal.New(3, 0)},
+			day(4): {Close: decimal.New(4, 0)},
+		},
+	}
+	rates := currencyRate.Series{
+		MetaData: currencyRate.TypedMetadata{FromSymbol: "GBP", ToSymbol: "EUR"},
+		TimeSeries: map[time.Time]currencyRate.TypedPrices{
+			day(2): {Close: decimal.MustParse("1.2")},
+			day(4): {Close: decimal.MustParse("1.1")},
+		},
+	}
+
+	t.Run("fill forward", func(t *testing.T) {
+		converted, err := Convert(series, rates, flags.IntervalDaily)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if converted.MetaData.Currency != "EUR" || converted.ConvertedFrom != "GBP" {
+			t.Errorf("expected prices in EUR converted from GBP, got %s from %s", converted.MetaData.Currency, converted.ConvertedFrom)
+		}
+		// The price of the 1st has no exchange rate before it, and the 3rd uses the rate of the 2nd.
+		expected := map[time.Time]string{day(2): "2.4", day(3): "3.6", day(4): "4.4"}
+		if len(converted.TimeSeries) != len(expected) {
+			t.Fatalf("expected %d prices, got %d", len(expected), len(converted.TimeSeries))
+		}
+		for date, price := range expected {
+			if got := converted.TimeSeries[date].Close.String(); got != price {
+				t.Errorf("expected %s on %s, got %s", price, date.Format("2006-01-02"), got)
+			}
+		}
+	})
+
+	t.Run("other currency", func(t *testing.T) {
+		other := rates
+		other.MetaData.FromSymbol = "USD"
+		if _, err := Convert(series, other, flags.IntervalDaily); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("intraday in other time zones", func(t *testing.T) {
+		hour := func(h int) time.Time {
+			return time.Date(2025, time.April, 4, h, 0, 0, 0, time.UTC)
+		}
+		// 10:00 and 11:00 in New York are 14:00 and 15:00 in UTC.
+		series := Series{
+			MetaData: TypedMetadata{Symbol: "IBM", Currency: "USD", TimeZone: "US/Eastern"},
+			TimeSeries: map[time.Time]TypedPrices{
+				hour(10): {Close: decimal.New(10, 0)},
+				hour(11): {Close: decimal.New(20, 0)},
+			},
+		}
+		rates := currencyRate.Series{
+			MetaData: currencyRate.TypedMetadata{FromSymbol: "USD", ToSymbol: "EUR", TimeZone: "UTC"},
+			TimeSeries: map[time.Time]currencyRate.TypedPrices{
+				hour(10): {Close: decimal.MustParse("0.5")},
+				hour(11): {Close: decimal.MustParse("0.6")},
+				hour(14): {Close: decimal.MustParse("0.9")},
+			},
+		}
+
+		converted, err := Convert(series, rates, flags.Interval60Min)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		// The price of 11:00 in New York uses the rate of 14:00 in UTC, the last one before 15:00.
+		expected := map[time.Time]string{hour(10): "9.0", hour(11): "18.0"}
+		if len(converted.TimeSeries) != len(expected) {
+			t.Fatalf("expected %d prices, got %d", len(expected), len(converted.TimeSeries))
+		}
+		for date, price := range expected {
+			if got := converted.TimeSeries[date].Close.String(); got != price {
+				t.Errorf("expected %s at %s, got %s", price, date.Format("15:04"), got)
+			}
+		}
+	})
+
+	t.Run("unknown time zone", func(t *testing.T) {
+		other := rates
+		other.MetaData.TimeZone = "Nowhere/Unknown"
+		if _, err := Convert(series, other, flags.Interval60Min); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -65,7 +432,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	internal.ApiKey = "demo"
 
 	t.Run("daily", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_DAILY&symbol=IBM&outputsize=full&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_DAILY&symbol=IBM&outputsize=full&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalDaily, false, true)
 		if err != nil {
@@ -76,7 +443,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("daily adjusted", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_DAILY_ADJUSTED&symbol=IBM&outputsize=full&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_DAILY_ADJUSTED&symbol=IBM&outputsize=full&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalDaily, true, true)
 		if err != nil {
@@ -87,7 +454,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("weekly", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_WEEKLY&symbol=IBM&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_WEEKLY&symbol=IBM&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalWeekly, false, false)
 		if err != nil {
@@ -98,7 +465,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("weekly adjusted", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_WEEKLY_ADJUSTED&symbol=IBM&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_WEEKLY_ADJUSTED&symbol=IBM&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalWeekly, true, false)
 		if err != nil {
@@ -109,7 +476,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("weekly ignore full", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_WEEKLY&symbol=IBM&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_WEEKLY&symbol=IBM&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalWeekly, false, false)
 		if err != nil {
@@ -120,7 +487,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("monthly", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_MONTHLY&symbol=IBM&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_MONTHLY&symbol=IBM&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalMonthly, false, false)
 		if err != nil {
@@ -131,7 +498,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("monthly adjusted", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_MONTHLY_ADJUSTED&symbol=IBM&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_MONTHLY_ADJUSTED&symbol=IBM&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalMonthly, true, false)
 		if err != nil {
@@ -142,7 +509,7 @@ func TestPriceURLBuilder(t *testing.T) {
 	})
 
 	t.Run("monthly ignore full", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_MONTHLY&symbol=IBM&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_MONTHLY&symbol=IBM&apikey=demo"
 
 		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.IntervalMonthly, false, false)
 		if err != nil {
@@ -152,10 +519,32 @@ func TestPriceURLBuilder(t *testing.T) {
 		}
 	})
 
-	t.Run("CSV", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=TIME_SERIES_DAILY&symbol=IBM&outputsize=full&apikey=demo&datatype=csv"
+	t.Run("intraday", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_INTRADAY&symbol=IBM&interval=5min&apikey=demo"
+
+		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.Interval5Min, false, false)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("intraday full", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_INTRADAY&symbol=IBM&interval=1min&outputsize=full&apikey=demo"
+
+		url, err := buildURL("IBM", flags.OutputFormatHledger, flags.Interval1Min, false, true)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("raw CSV", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=TIME_SERIES_DAILY&symbol=IBM&outputsize=full&apikey=demo&datatype=csv"
 
-		url, err := buildURL("IBM", flags.OutputFormatCSV, flags.IntervalDaily, false, true)
+		url, err := buildURL("IBM", flags.OutputFormatRawCSV, flags.IntervalDaily, false, true)
 		if err != nil {
 			t.Errorf("expected nil, got %v", err)
 		} else if url != expected {
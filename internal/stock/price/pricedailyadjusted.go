@@ -20,12 +20,8 @@ package price
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"strings"
 	"time"
-
-	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
 const apiFunctionTimeSeriesDailyAdjusted = "TIME_SERIES_DAILY_ADJUSTED" // Requires premium API key.
@@ -73,54 +69,22 @@ func (obj *DailyAdjusted) TypeBody() error {
 	return nil
 }
 
-func (obj *DailyAdjusted) GenerateOutput(body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
-	switch format {
-	case flags.OutputFormatJSON, flags.OutputFormatCSV:
-		return string(body), nil
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong:
-		// Parse the JSON body into the Raw struct.
-		err := json.Unmarshal(body, &obj.Raw)
-		if err != nil {
-			return "", fmt.Errorf("[(*Daily).GenerateOutput] failure to unmarshal JSON body: %w", err)
-		}
-
-		// Cast the attributes into proper types.
-		err = obj.TypeBody()
-		if err != nil {
-			return "", fmt.Errorf("[(*Daily).GenerateOutput] error casting response attributes: %w", err)
-		}
+func (obj *DailyAdjusted) ParseBody(body []byte) (Series, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*DailyAdjusted).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
 
-		dates := getDatesAdjusted(obj.Typed.TimeSeries, begin, end)
-
-		if format == flags.OutputFormatHledger {
-			return generateOutputHledgerAdjusted(
-					obj.Typed.TimeSeries,
-					dates,
-					obj.Typed.MetaData.Symbol,
-					obj.Typed.MetaData.Currency),
-				nil
-		} else {
-			out := strings.Builder{}
-			out.WriteString(generateMetadataTable(
-				obj.Typed.MetaData.Symbol,
-				obj.Typed.MetaData.Currency,
-				obj.Typed.MetaData.LastRefreshed,
-				obj.Typed.MetaData.TimeZone,
-			))
-
-			if format == flags.OutputFormatTable {
-				out.WriteString(generateTimeSeriesTableShortAdjusted(
-					obj.Typed.TimeSeries,
-					dates))
-			} else {
-				out.WriteString(generateTimeSeriesTableLongAdjusted(
-					obj.Typed.TimeSeries,
-					dates))
-			}
-
-			return out.String(), nil
-		}
-	default:
-		return "", errors.New("[(*Daily).GenerateOutput] invalid output format")
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*DailyAdjusted).ParseBody] error casting response attributes: %w", err)
 	}
+
+	return Series{
+		MetaData:           obj.Typed.MetaData.toTypedMetadata(),
+		Adjusted:           true,
+		TimeSeriesAdjusted: obj.Typed.TimeSeries,
+	}, nil
 }
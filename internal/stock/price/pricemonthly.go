@@ -20,12 +20,8 @@ package price
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"strings"
 	"time"
-
-	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
 const apiFunctionTimeSeriesMonthly = "TIME_SERIES_MONTHLY"
@@ -73,45 +69,21 @@ func (obj *Monthly) TypeBody() error {
 	return nil
 }
 
-func (obj *Monthly) GenerateOutput(body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
-	switch format {
-	case flags.OutputFormatJSON, flags.OutputFormatCSV:
-		return string(body), nil
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong:
-		// Parse the JSON body into the Raw struct.
-		err := json.Unmarshal(body, &obj.Raw)
-		if err != nil {
-			return "", fmt.Errorf("[(*Monthly).GenerateOutput] failure to unmarshal JSON body: %w", err)
-		}
-
-		// Cast the attributes into proper types.
-		err = obj.TypeBody()
-		if err != nil {
-			return "", fmt.Errorf("[(*Monthly).GenerateOutput] error casting response attributes: %w", err)
-		}
+func (obj *Monthly) ParseBody(body []byte) (Series, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Monthly).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
 
-		dates := getDatesNormal(obj.Typed.TimeSeries, begin, end)
-
-		if format == flags.OutputFormatHledger {
-			return generateOutputHledgerNormal(
-					obj.Typed.TimeSeries,
-					dates,
-					obj.Typed.MetaData.Symbol,
-					obj.Typed.MetaData.Currency),
-				nil
-		} else {
-			out := strings.Builder{}
-			out.WriteString(generateMetadataTable(
-				obj.Typed.MetaData.Symbol,
-				obj.Typed.MetaData.Currency,
-				obj.Typed.MetaData.LastRefreshed,
-				obj.Typed.MetaData.TimeZone))
-			out.WriteString(generateTimeSeriesTableShort(
-				obj.Typed.TimeSeries,
-				dates))
-			return out.String(), nil
-		}
-	default:
-		return "", errors.New("[(*Monthly).GenerateOutput] invalid output format")
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*Monthly).ParseBody] error casting response attributes: %w", err)
 	}
+
+	return Series{
+		MetaData:   obj.Typed.MetaData,
+		TimeSeries: obj.Typed.TimeSeries,
+	}, nil
 }
@@ -20,12 +20,8 @@ package price
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"strings"
 	"time"
-
-	"github.com/lentidas/hledger-price-tracker/internal/flags"
 )
 
 const apiFunctionTimeSeriesMonthlyAdjusted = "TIME_SERIES_MONTHLY_ADJUSTED"
@@ -73,54 +69,22 @@ func (obj *MonthlyAdjusted) TypeBody() error {
 	return nil
 }
 
-func (obj *MonthlyAdjusted) GenerateOutput(body []byte, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
-	switch format {
-	case flags.OutputFormatJSON, flags.OutputFormatCSV:
-		return string(body), nil
-	case flags.OutputFormatHledger, flags.OutputFormatTable, flags.OutputFormatTableLong:
-		// Parse the JSON body into the Raw struct.
-		err := json.Unmarshal(body, &obj.Raw)
-		if err != nil {
-			return "", fmt.Errorf("[(*MonthlyAdjusted).GenerateOutput] failure to unmarshal JSON body: %w", err)
-		}
-
-		// Cast the attributes into proper types.
-		err = obj.TypeBody()
-		if err != nil {
-			return "", fmt.Errorf("[(*MonthlyAdjusted).GenerateOutput] error casting response attributes: %w", err)
-		}
+func (obj *MonthlyAdjusted) ParseBody(body []byte) (Series, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*MonthlyAdjusted).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
 
-		dates := getDatesAdjusted(obj.Typed.TimeSeries, begin, end)
-
-		if format == flags.OutputFormatHledger {
-			return generateOutputHledgerAdjusted(
-					obj.Typed.TimeSeries,
-					dates,
-					obj.Typed.MetaData.Symbol,
-					obj.Typed.MetaData.Currency),
-				nil
-		} else {
-			out := strings.Builder{}
-			out.WriteString(generateMetadataTable(
-				obj.Typed.MetaData.Symbol,
-				obj.Typed.MetaData.Currency,
-				obj.Typed.MetaData.LastRefreshed,
-				obj.Typed.MetaData.TimeZone,
-			))
-
-			if format == flags.OutputFormatTable {
-				out.WriteString(generateTimeSeriesTableShortAdjusted(
-					obj.Typed.TimeSeries,
-					dates))
-			} else {
-				out.WriteString(generateTimeSeriesTableLongAdjusted(
-					obj.Typed.TimeSeries,
-					dates))
-			}
-
-			return out.String(), nil
-		}
-	default:
-		return "", errors.New("[(*MonthlyAdjusted).GenerateOutput] invalid output format")
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Series{}, fmt.Errorf("[(*MonthlyAdjusted).ParseBody] error casting response attributes: %w", err)
 	}
+
+	return Series{
+		MetaData:           obj.Typed.MetaData,
+		Adjusted:           true,
+		TimeSeriesAdjusted: obj.Typed.TimeSeries,
+	}, nil
 }
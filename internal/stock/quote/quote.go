@@ -0,0 +1,463 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package quote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/pool"
+	"github.com/lentidas/hledger-price-tracker/internal/render"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
+)
+
+const apiFunctionGlobalQuote = "GLOBAL_QUOTE"
+
+type Response interface {
+	TypeBody() error
+	ParseBody(body []byte) (Typed, error)
+}
+
+// Provider is implemented by every price source able to return the latest quote of a stock.
+// The raw body is returned alongside the typed quote so the "raw-json" and "raw-csv" output formats can be served
+// as is. When one of those formats is requested, providers are free to return an empty Typed.
+// A provider whose quotes do not come with their currency leaves it empty, in which case it is looked up with
+// StockCurrency and the prices are taken as quoted in it (see fetch).
+type Provider interface {
+	StockQuote(symbol string, format flags.OutputFormat) (Typed, []byte, error)
+	search.CurrencyProvider
+}
+
+// AlphaVantage implements Provider using the GLOBAL_QUOTE endpoint of the Alpha Vantage API.
+type AlphaVantage struct{}
+
+type Raw struct {
+	GlobalQuote struct {
+		Symbol           string `json:"01. symbol"`
+		Open             string `json:"02. open"`
+		High             string `json:"03. high"`
+		Low              string `json:"04. low"`
+		Price            string `json:"05. price"`
+		Volume           string `json:"06. volume"`
+		LatestTradingDay string `json:"07. latest trading day"`
+		PreviousClose    string `json:"08. previous close"`
+		Change           string `json:"09. change"`
+		ChangePercent    string `json:"10. change percent"`
+	} `json:"Global Quote"`
+}
+
+type Typed struct {
+	Symbol           string
+	Currency         string
+	Open             decimal.Decimal
+	High             decimal.Decimal
+	Low              decimal.Decimal
+	Price            decimal.Decimal
+	Volume           uint64
+	LatestTradingDay time.Time
+	PreviousClose    decimal.Decimal
+	Change           decimal.Decimal
+	ChangePercent    decimal.Decimal
+	Source           string
+	Fetched          time.Time
+}
+
+type Quote struct {
+	Raw   Raw
+	Typed Typed
+}
+
+func (obj *Quote) TypeBody() error {
+	raw := obj.Raw.GlobalQuote
+
+	latestTradingDay, err := time.Parse("2006-01-02", raw.LatestTradingDay)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing latest trading day: %w", err)
+	}
+
+	openPrice, err := decimal.Parse(raw.Open)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing open price: %w", err)
+	}
+	highPrice, err := decimal.Parse(raw.High)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing high price: %w", err)
+	}
+	lowPrice, err := decimal.Parse(raw.Low)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing low price: %w", err)
+	}
+	price, err := decimal.Parse(raw.Price)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing price: %w", err)
+	}
+	volume, err := strconv.ParseUint(raw.Volume, 10, 64)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing volume: %w", err)
+	}
+	previousClose, err := decimal.Parse(raw.PreviousClose)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing previous close: %w", err)
+	}
+	change, err := decimal.Parse(raw.Change)
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing change: %w", err)
+	}
+	// The percentage comes with its sign attached (e.g. "-1.2345%").
+	changePercent, err := decimal.Parse(strings.TrimSuffix(raw.ChangePercent, "%"))
+	if err != nil {
+		return fmt.Errorf("[(*Quote).TypeBody] error parsing change percent: %w", err)
+	}
+
+	obj.Typed.Symbol = raw.Symbol
+	obj.Typed.Open = openPrice
+	obj.Typed.High = highPrice
+	obj.Typed.Low = lowPrice
+	obj.Typed.Price = price
+	obj.Typed.Volume = volume
+	obj.Typed.LatestTradingDay = latestTradingDay
+	obj.Typed.PreviousClose = previousClose
+	obj.Typed.Change = change
+	obj.Typed.ChangePercent = changePercent
+
+	return nil
+}
+
+func (obj *Quote) ParseBody(body []byte) (Typed, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Quote).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Quote).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return obj.Typed, nil
+}
+
+// empty reports whether the body is the empty quote the API answers with when it does not know the symbol.
+func empty(body []byte, format flags.OutputFormat) bool {
+	if format == flags.OutputFormatRawCSV {
+		// The CSV body only contains the header line in that case.
+		return len(strings.Split(strings.TrimSpace(string(body)), "\n")) < 2
+	}
+
+	var raw Raw
+	if err := json.Unmarshal(body, &raw); err != nil {
+		// Leave the error to the parser, which gives a more meaningful message.
+		return false
+	}
+	return raw.GlobalQuote.Symbol == ""
+}
+
+// buildURL creates the URL to make the HTTP request to the Alpha Vantage API.
+func buildURL(symbol string, format flags.OutputFormat) (string, error) {
+	if internal.ApiKey == "" {
+		return "", errors.New("[stock.quote.buildURL] API key is required")
+	}
+	if symbol == "" {
+		return "", errors.New("[stock.quote.buildURL] no stock symbol provided")
+	}
+	if _, ok := render.Lookup(format); !ok {
+		switch format {
+		case flags.OutputFormatTable, flags.OutputFormatTableLong, flags.OutputFormatRawJSON, flags.OutputFormatRawCSV:
+			// Do nothing.
+		default:
+			return "", errors.New("[stock.quote.buildURL] invalid output format")
+		}
+	}
+
+	url := strings.Builder{}
+	url.WriteString(internal.ApiBaseUrl)
+	url.WriteString("function=")
+	url.WriteString(apiFunctionGlobalQuote)
+	url.WriteString("&symbol=")
+	url.WriteString(symbol)
+	url.WriteString("&apikey=")
+	url.WriteString(internal.ApiKey)
+
+	if format == flags.OutputFormatRawCSV {
+		url.WriteString("&datatype=csv")
+	}
+
+	return url.String(), nil
+}
+
+// StockQuote fetches the latest quote of a stock from the Alpha Vantage API and casts it into its proper types.
+func (AlphaVantage) StockQuote(symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	url, err := buildURL(symbol, format)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+
+	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		return Typed{}, nil, fmt.Errorf("[stock.quote.(AlphaVantage).StockQuote] %w %s: %w", internal.ErrUnknownSymbol, symbol, err)
+	}
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	if empty(body, format) {
+		return Typed{}, nil, fmt.Errorf("[stock.quote.(AlphaVantage).StockQuote] %w %s: empty quote", internal.ErrUnknownSymbol, symbol)
+	}
+
+	// The raw formats do not need the body to be parsed.
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV {
+		return Typed{}, body, nil
+	}
+
+	response := Quote{}
+	typed, err := response.ParseBody(body)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	typed.Source = internal.SourceAlphaVantage
+	typed.Fetched = time.Now()
+
+	return typed, body, nil
+}
+
+// joinJSON puts the raw bodies of several quotes in a JSON array. A single body is returned as is.
+func joinJSON(bodies [][]byte) string {
+	if len(bodies) == 1 {
+		return string(bodies[0])
+	}
+
+	out := strings.Builder{}
+	out.WriteString("[")
+	for i, body := range bodies {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(strings.TrimSpace(string(body)))
+	}
+	out.WriteString("]\n")
+	return out.String()
+}
+
+// joinCSV concatenates the raw bodies of several quotes, keeping only the header line of the first one.
+func joinCSV(bodies [][]byte) string {
+	out := strings.Builder{}
+	for i, body := range bodies {
+		lines := strings.TrimSpace(strings.ReplaceAll(string(body), "\r\n", "\n"))
+		if i > 0 {
+			_, lines, _ = strings.Cut(lines, "\n")
+		}
+		out.WriteString(lines)
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// records converts the quotes into records, using the latest price as their amount.
+func records(quotes []Typed) []render.Record {
+	records := make([]render.Record, 0, len(quotes))
+	for _, quote := range quotes {
+		records = append(records, render.Record{
+			Date:      quote.LatestTradingDay,
+			Commodity: quote.Symbol,
+			Amount:    quote.Price,
+			Currency:  quote.Currency,
+			Open:      quote.Open,
+			High:      quote.High,
+			Low:       quote.Low,
+			Close:     quote.Price,
+			Volume:    decimal.New(int64(quote.Volume), 0),
+			Source:    quote.Source,
+			Fetched:   quote.Fetched,
+			Given:     render.FieldsRange | render.FieldsVolume,
+			Stock:     true,
+		})
+	}
+	return records
+}
+
+// GenerateOutput renders the quotes of one or more stocks in the desired format.
+// The "raw-json" and "raw-csv" formats return the raw bodies given by the provider.
+func GenerateOutput(quotes []Typed, bodies [][]byte, format flags.OutputFormat) (string, error) {
+	// The formats made of one directive per quote are written by the formatters of the render package.
+	if _, ok := render.Lookup(format); ok {
+		return render.Render(format, records(quotes), "")
+	}
+
+	switch format {
+	case flags.OutputFormatRawJSON:
+		return joinJSON(bodies), nil
+	case flags.OutputFormatRawCSV:
+		return joinCSV(bodies), nil
+	case flags.OutputFormatTable, flags.OutputFormatTableLong:
+		t := table.NewWriter()
+		t.SetStyle(table.StyleLight)
+		if format == flags.OutputFormatTable {
+			t.AppendHeader(table.Row{"Symbol", "Price", "Currency", "Latest Trading Day"})
+			for _, quote := range quotes {
+				t.AppendRow(table.Row{
+					quote.Symbol,
+					internal.FormatPrice(quote.Price, quote.Symbol, quote.Currency),
+					quote.Currency,
+					quote.LatestTradingDay.Format("2006-01-02"),
+				})
+			}
+		} else {
+			t.AppendHeader(table.Row{"Symbol", "Open", "High", "Low", "Price", "Volume", "Previous Close", "Change", "Change %", "Currency", "Latest Trading Day"})
+			for _, quote := range quotes {
+				t.AppendRow(table.Row{
+					quote.Symbol,
+					internal.FormatPrice(quote.Open, quote.Symbol, quote.Currency),
+					internal.FormatPrice(quote.High, quote.Symbol, quote.Currency),
+					internal.FormatPrice(quote.Low, quote.Symbol, quote.Currency),
+					internal.FormatPrice(quote.Price, quote.Symbol, quote.Currency),
+					quote.Volume,
+					internal.FormatPrice(quote.PreviousClose, quote.Symbol, quote.Currency),
+					internal.FormatPrice(quote.Change, quote.Symbol, quote.Currency),
+					quote.ChangePercent.Round(4).String() + "%",
+					quote.Currency,
+					quote.LatestTradingDay.Format("2006-01-02"),
+				})
+			}
+		}
+		return t.Render() + "\n", nil
+	default:
+		return "", errors.New("[stock.quote.GenerateOutput] invalid output format")
+	}
+}
+
+// Execute is the core function of the quote package. It fetches the latest quote of each of the given stock symbols
+// from the given provider and returns them in the desired format. The formats of the render package use the names of
+// the journal, and name the stock `as` when it is not empty.
+func Execute(provider Provider, symbols []string, as string, format flags.OutputFormat) (string, error) {
+	out := strings.Builder{}
+	if err := Write(&out, provider, symbols, as, format); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// fetched is the result of the fetch of the quote of a symbol.
+type fetched struct {
+	typed Typed
+	body  []byte
+}
+
+// inCurrency sets the currency of a quote whose prices are quoted in it. The prices quoted in a minor unit of a
+// currency (e.g. pence) are converted to its major unit.
+func (typed *Typed) inCurrency(currency string) {
+	var divisor decimal.Decimal
+	typed.Currency, divisor = internal.MajorUnit(currency)
+	typed.Open = typed.Open.Div(divisor)
+	typed.High = typed.High.Div(divisor)
+	typed.Low = typed.Low.Div(divisor)
+	typed.Price = typed.Price.Div(divisor)
+	typed.PreviousClose = typed.PreviousClose.Div(divisor)
+	typed.Change = typed.Change.Div(divisor)
+}
+
+// fetch gets the quote of a symbol from the given provider. When the provider does not give the currency of the stock
+// along with its quote, it is looked up with the same provider.
+func fetch(provider Provider, symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	typed, body, err := provider.StockQuote(symbol, format)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	// The raw formats are not parsed.
+	if format == flags.OutputFormatRawJSON || format == flags.OutputFormatRawCSV || typed.Currency != "" {
+		return typed, body, nil
+	}
+
+	currency, err := provider.StockCurrency(typed.Symbol)
+	if err != nil {
+		return Typed{}, nil, fmt.Errorf("[stock.quote.fetch] error getting the currency of %s: %w", symbol, err)
+	}
+	typed.inCurrency(currency)
+
+	return typed, body, nil
+}
+
+// Write is like Execute, but writes the quote of each symbol to `out` as soon as it and the ones of the previous
+// symbols are fetched, so it can be read while the next ones are still being fetched. The symbols are fetched
+// concurrently by at most internal.Workers workers, and written in the order they are given. The tables, the raw
+// formats and the formats that write a whole document (e.g. "json") are only written once every symbol is fetched.
+// A symbol that fails does not prevent the others from being written, and the errors of all of them are returned at
+// the end.
+func Write(out io.Writer, provider Provider, symbols []string, as string, format flags.OutputFormat) error {
+	if len(symbols) == 0 {
+		return errors.New("[stock.quote.Write] no stock symbol provided")
+	}
+
+	// The formats made of one directive per quote are written by a writer of the render package.
+	var writer *render.Writer
+	if _, ok := render.Lookup(format); ok {
+		var err error
+		writer, err = render.NewWriter(out, format, as)
+		if err != nil {
+			return err
+		}
+	}
+
+	quotes := make([]Typed, 0, len(symbols))
+	bodies := make([][]byte, 0, len(symbols))
+	var errs []error
+	err := pool.Run(len(symbols), internal.Workers, func(i int) (fetched, error) {
+		typed, body, err := fetch(provider, symbols[i], format)
+		return fetched{typed: typed, body: body}, err
+	}, func(i int, result fetched, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[stock.quote.Write] failed to fetch the quote of %s: %w", symbols[i], err))
+			return nil
+		}
+		if writer != nil {
+			return writer.Write(records([]Typed{result.typed}))
+		}
+		quotes = append(quotes, result.typed)
+		bodies = append(bodies, result.body)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if writer != nil {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	} else if len(quotes) > 0 {
+		output, err := GenerateOutput(quotes, bodies, format)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(out, output); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,210 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package quote
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+// sources combines the Alpha Vantage implementations of the quotes and of the currency of the stocks.
+type sources struct {
+	AlphaVantage
+}
+
+func (sources) StockCurrency(symbol string) (string, error) {
+	return search.AlphaVantage{}.StockCurrency(symbol)
+}
+
+// writes records every write made to it.
+type writes struct {
+	writes []string
+}
+
+func (w *writes) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestQuote(t *testing.T) {
+	internal.ApiKey = "demo"
+
+	t.Run("success", func(t *testing.T) {
+		expected := "P 2025-04-04 \"IBM\" 227.48 NIL\n"
+
+		output, err := Execute(sources{}, []string{"IBM"}, "", flags.OutputFormatHledger)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("success in minor units", func(t *testing.T) {
+		// The currency is only known with a real API key, and London quotes in pence.
+		internal.ApiKey = "test"
+		defer func() { internal.ApiKey = "demo" }()
+		expected := "P 2025-04-04 \"TSCO.LON\" 3.504 GBP\n"
+
+		output, err := Execute(sources{}, []string{"TSCO.LON"}, "", flags.OutputFormatHledger)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("several symbols", func(t *testing.T) {
+		expected := "P 2025-04-04 \"IBM\" 227.48 NIL\nP 2025-04-04 \"MSFT\" 359.84 NIL\n"
+
+		output, err := Execute(sources{}, []string{"IBM", "MSFT"}, "", flags.OutputFormatHledger)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("table-long", func(t *testing.T) {
+		output, err := Execute(sources{}, []string{"IBM"}, "", flags.OutputFormatTableLong)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.Contains(output, "-6.7514%") {
+			t.Errorf("expected the change percent in the table, got %s", output)
+		}
+	})
+
+	t.Run("several symbols in raw JSON", func(t *testing.T) {
+		output, err := Execute(sources{}, []string{"IBM", "MSFT"}, "", flags.OutputFormatRawJSON)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if !strings.HasPrefix(output, "[{") || !strings.HasSuffix(output, "}]\n") {
+			t.Errorf("expected a JSON array, got %s", output)
+		}
+	})
+
+	t.Run("several symbols in raw CSV", func(t *testing.T) {
+		output, err := Execute(sources{}, []string{"IBM", "MSFT"}, "", flags.OutputFormatRawCSV)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 3 {
+			t.Errorf("expected a header and 2 lines, got %q", output)
+		}
+	})
+
+	t.Run("several symbols in NDJSON", func(t *testing.T) {
+		// Each quote is written on its own as soon as it is fetched.
+		out := &writes{}
+		if err := Write(out, sources{}, []string{"IBM", "MSFT"}, "", flags.OutputFormatNDJSON); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(out.writes) != 2 {
+			t.Fatalf("expected 2 writes, got %q", out.writes)
+		}
+		for i, symbol := range []string{"IBM", "MSFT"} {
+			var line map[string]any
+			if err := json.Unmarshal([]byte(out.writes[i]), &line); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if line["symbol"] != symbol || line["source"] != internal.SourceAlphaVantage || line["fetched_at"] == nil {
+				t.Errorf("expected the quote of %s with its source and fetch time, got %q", symbol, out.writes[i])
+			}
+		}
+	})
+
+	t.Run("several symbols with an unknown one", func(t *testing.T) {
+		internal.Workers = 3
+		defer func() { internal.Workers = 0 }()
+
+		expected := "P 2025-04-04 \"IBM\" 227.48 NIL\nP 2025-04-04 \"MSFT\" 359.84 NIL\n"
+		out := strings.Builder{}
+		err := Write(&out, sources{}, []string{"IBM", "UNKNOWN", "MSFT"}, "", flags.OutputFormatHledger)
+		if !errors.Is(err, internal.ErrUnknownSymbol) {
+			t.Errorf("expected %v, got %v", internal.ErrUnknownSymbol, err)
+		}
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		_, err := Execute(sources{}, []string{"UNKNOWN"}, "", flags.OutputFormatHledger)
+		if !errors.Is(err, internal.ErrUnknownSymbol) {
+			t.Errorf("expected %v, got %v", internal.ErrUnknownSymbol, err)
+		}
+	})
+
+	t.Run("no symbol", func(t *testing.T) {
+		if _, err := Execute(sources{}, nil, "", flags.OutputFormatHledger); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("invalid output format", func(t *testing.T) {
+		if _, err := Execute(sources{}, []string{"IBM"}, "", "invalid"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	internal.ApiKey = ""
+
+	t.Run("no API key", func(t *testing.T) {
+		if _, err := Execute(sources{}, []string{"IBM"}, "", flags.OutputFormatHledger); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestQuoteURLBuilder(t *testing.T) {
+	internal.ApiKey = "demo"
+
+	t.Run("raw json", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=GLOBAL_QUOTE&symbol=IBM&apikey=demo"
+
+		url, err := buildURL("IBM", flags.OutputFormatRawJSON)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+
+	t.Run("raw csv", func(t *testing.T) {
+		expected := internal.ApiBaseUrl + "function=GLOBAL_QUOTE&symbol=IBM&apikey=demo&datatype=csv"
+
+		url, err := buildURL("IBM", flags.OutputFormatRawCSV)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		} else if url != expected {
+			t.Errorf("expected %s, got %s", expected, url)
+		}
+	})
+}
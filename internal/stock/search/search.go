@@ -37,9 +37,26 @@ const apiFunctionSearch = "SYMBOL_SEARCH"
 
 type Response interface {
 	TypeBody() error
-	GenerateOutput(body []byte, format flags.OutputFormat) (string, error)
+	ParseBody(body []byte) (Typed, error)
 }
 
+// Provider is implemented by every price source able to search for stock symbols.
+// The raw body is returned alongside the typed results so the "json" and "csv" output formats can be served as is.
+// When one of those formats is requested, providers are free to return an empty Typed.
+type Provider interface {
+	SymbolSearch(query string, format flags.OutputFormat) (Typed, []byte, error)
+	CurrencyProvider
+}
+
+// CurrencyProvider is implemented by every price source able to tell in which currency a stock is traded, for the
+// providers whose prices do not come with their currency.
+type CurrencyProvider interface {
+	StockCurrency(symbol string) (string, error)
+}
+
+// AlphaVantage implements Provider using the SYMBOL_SEARCH endpoint of the Alpha Vantage API.
+type AlphaVantage struct{}
+
 type Raw struct {
 	BestMatches []struct {
 		Symbol      string `json:"1. symbol"`
@@ -134,33 +151,36 @@ func (obj *Search) TypeBody() error {
 	return nil
 }
 
-func (obj *Search) GenerateOutput(body []byte, format flags.OutputFormat) (string, error) {
+func (obj *Search) ParseBody(body []byte) (Typed, error) {
+	// Parse the JSON body.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Search).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Search).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return obj.Typed, nil
+}
+
+// GenerateOutput renders the search results in the desired format.
+// The "json" and "csv" formats return the raw body given by the provider.
+func GenerateOutput(typed Typed, body []byte, format flags.OutputFormat) (string, error) {
 	switch format {
 	case flags.OutputFormatHledger:
-		return "", errors.New("[(*Search).GenerateOutput] hledger output format not supported")
+		return "", errors.New("[stock.search.GenerateOutput] hledger output format not supported")
 	case flags.OutputFormatJSON, flags.OutputFormatCSV:
 		return string(body), nil
 	case flags.OutputFormatTable, flags.OutputFormatTableLong:
-		// Create a struct to parse the JSON body.
-		var obj Search
-
-		// Parse the JSON body.
-		err := json.Unmarshal(body, &obj.Raw)
-		if err != nil {
-			return "", fmt.Errorf("[(*Search).GenerateOutput] failure to unmarshal JSON body: %w", err)
-		}
-
-		// Cast the attributes into proper types.
-		err = obj.TypeBody()
-		if err != nil {
-			return "", fmt.Errorf("[(*Search).GenerateOutput] error casting response attributes: %w", err)
-		}
-
 		t := table.NewWriter()
 		t.SetStyle(table.StyleLight)
 		if format == flags.OutputFormatTableLong {
 			t.AppendHeader(table.Row{"#", "Symbol", "Name", "Type", "Region", "Market Open", "Market Close", "Timezone", "Currency", "Match Score"})
-			for i, result := range obj.Typed.BestMatches {
+			for i, result := range typed.BestMatches {
 				t.AppendRow([]interface{}{
 					i + 1,
 					result.Symbol,
@@ -181,7 +201,7 @@ func (obj *Search) GenerateOutput(body []byte, format flags.OutputFormat) (strin
 			})
 		} else {
 			t.AppendHeader(table.Row{"#", "Symbol", "Name", "Type", "Region", "Currency", "Match Score"})
-			for i, result := range obj.Typed.BestMatches {
+			for i, result := range typed.BestMatches {
 				t.AppendRow([]interface{}{
 					i + 1,
 					result.Symbol,
@@ -199,7 +219,7 @@ func (obj *Search) GenerateOutput(body []byte, format flags.OutputFormat) (strin
 
 		return t.Render(), nil
 	default:
-		return "", errors.New("[(*Search).GenerateOutput] invalid output format")
+		return "", errors.New("[stock.search.GenerateOutput] invalid output format")
 	}
 }
 
@@ -233,30 +253,28 @@ func buildURL(query string, format flags.OutputFormat) (string, error) {
 	return url.String(), nil
 }
 
-// GetCurrency performs a search for a certain stock symbol, then returns the currency of the first result.
-func GetCurrency(symbol string) (string, error) {
+// StockCurrency performs a search for a certain stock symbol, then returns the currency of the first result.
+func (AlphaVantage) StockCurrency(symbol string) (string, error) {
 	// Alpha Vantage does not use the same stocks for the search and price demos. As such, since we are essentially
 	// also performing a search when getting the price of a stock, this would fail with the `demo` API key.
 	if internal.DebugMode || internal.ApiKey == "demo" {
 		return "NIL", nil
 	}
 
-	body, err := Execute(symbol, flags.OutputFormatJSON)
+	_, body, err := AlphaVantage{}.SymbolSearch(symbol, flags.OutputFormatJSON)
 	if err != nil {
 		return "", err
 	}
 	var response Raw
-	err = json.Unmarshal([]byte(body), &response)
+	err = json.Unmarshal(body, &response)
 	if err != nil {
-		return "", fmt.Errorf("[stock.search.GetCurrency] error unmarshalling JSON to get currency: %w", err)
-	} else if len(response.BestMatches) == 0 {
-		return "", fmt.Errorf("[stock.search.GetCurrency] error getting currency of symbol %s", symbol)
+		return "", fmt.Errorf("[stock.search.(AlphaVantage).StockCurrency] error unmarshalling JSON to get currency: %w", err)
 	}
 
 	// If no results are found, return an error. An error is a correct in this case, because if the user uses a known
 	// stock symbol, the API should always return at least one result.
 	if len(response.BestMatches) < 1 {
-		return "", errors.New("[stock.search.GetCurrency] no results found")
+		return "", fmt.Errorf("[stock.search.(AlphaVantage).StockCurrency] %w %s: no results found", internal.ErrUnknownSymbol, symbol)
 	}
 
 	// TODO Maybe consider also returning an error if the match score is not 100%.
@@ -264,20 +282,39 @@ func GetCurrency(symbol string) (string, error) {
 	return response.BestMatches[0].Currency, nil
 }
 
-// Execute is the core function of the search package. It performs a search for a certain stock symbol and returns the
-// results in the specified format.
-func Execute(query string, format flags.OutputFormat) (string, error) {
+// SymbolSearch performs a search on the Alpha Vantage API and casts the results into their proper types.
+func (AlphaVantage) SymbolSearch(query string, format flags.OutputFormat) (Typed, []byte, error) {
 	url, err := buildURL(query, format)
 	if err != nil {
-		return "", err
+		return Typed{}, nil, err
 	}
 
 	body, err := internal.HTTPRequest(url)
 	if err != nil {
-		return "", err
+		return Typed{}, nil, err
+	}
+
+	// The raw formats do not need the body to be parsed.
+	if format != flags.OutputFormatTable && format != flags.OutputFormatTableLong {
+		return Typed{}, body, nil
 	}
 
 	response := Search{}
+	typed, err := response.ParseBody(body)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+
+	return typed, body, nil
+}
+
+// Execute is the core function of the search package. It performs a search for a certain stock symbol on the given
+// provider and returns the results in the specified format.
+func Execute(provider Provider, query string, format flags.OutputFormat) (string, error) {
+	typed, body, err := provider.SymbolSearch(query, format)
+	if err != nil {
+		return "", err
+	}
 
-	return response.GenerateOutput(body, format)
+	return GenerateOutput(typed, body, format)
 }
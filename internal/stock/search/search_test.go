@@ -19,32 +19,35 @@
 package search
 
 import (
+	"os"
 	"testing"
-	"time"
 
 	"github.com/lentidas/hledger-price-tracker/internal"
 	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
 func TestSearch(t *testing.T) {
 	internal.ApiKey = "demo"
 
 	t.Run("success", func(t *testing.T) {
-		if _, err := Execute("tesco", flags.OutputFormatJSON); err != nil {
+		if _, err := Execute(AlphaVantage{}, "tesco", flags.OutputFormatJSON); err != nil {
 			t.Errorf("expected nil, got %v", err)
 		}
-		// Sleep to avoid hitting the Alpha Vantage per-second rate limit across the test suite.
-		time.Sleep(time.Duration(500) * time.Millisecond)
 	})
 
 	t.Run("no search query", func(t *testing.T) {
-		if _, err := Execute("", flags.OutputFormatJSON); err == nil {
+		if _, err := Execute(AlphaVantage{}, "", flags.OutputFormatJSON); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 
 	t.Run("invalid output format", func(t *testing.T) {
-		if _, err := Execute("tesco", "invalid"); err == nil {
+		if _, err := Execute(AlphaVantage{}, "tesco", "invalid"); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -52,7 +55,7 @@ func TestSearch(t *testing.T) {
 	internal.ApiKey = ""
 
 	t.Run("no API key", func(t *testing.T) {
-		if _, err := Execute("tesco", flags.OutputFormatJSON); err == nil {
+		if _, err := Execute(AlphaVantage{}, "tesco", flags.OutputFormatJSON); err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
@@ -62,7 +65,7 @@ func TestSearchURLBuilder(t *testing.T) {
 	internal.ApiKey = "demo"
 
 	t.Run("normal", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco&apikey=demo"
+		expected := internal.ApiBaseUrl + "function=SYMBOL_SEARCH&keywords=tesco&apikey=demo"
 		url, err := buildURL("tesco", flags.OutputFormatJSON)
 		if err != nil {
 			t.Fatalf("expected nil, got %v", err)
@@ -74,7 +77,7 @@ func TestSearchURLBuilder(t *testing.T) {
 	})
 
 	t.Run("CSV", func(t *testing.T) {
-		expected := "https://www.alphavantage.co/query?function=SYMBOL_SEARCH&keywords=tesco&apikey=demo&datatype=csv"
+		expected := internal.ApiBaseUrl + "function=SYMBOL_SEARCH&keywords=tesco&apikey=demo&datatype=csv"
 		url, err := buildURL("tesco", flags.OutputFormatCSV)
 		if err != nil {
 			t.Fatalf("expected nil, got %v", err)
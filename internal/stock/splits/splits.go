@@ -0,0 +1,438 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package splits
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+)
+
+const apiFunctionSplits = "SPLITS"
+
+// Sources of the split events.
+const (
+	// SourceSplits uses the SPLITS endpoint.
+	SourceSplits = "splits"
+	// SourceAdjusted uses the split coefficients of the daily adjusted prices (premium endpoint).
+	SourceAdjusted = "adjusted"
+)
+
+type Response interface {
+	TypeBody() error
+	ParseBody(body []byte) (Typed, error)
+}
+
+// Provider is implemented by every price source able to return the splits of a stock.
+// The raw body is returned alongside the typed splits so the "raw-json" output format can be served as is.
+type Provider interface {
+	Splits(symbol string, format flags.OutputFormat) (Typed, []byte, error)
+}
+
+// Sources is implemented by the providers able to return both the splits and the adjusted prices of a stock.
+type Sources interface {
+	Provider
+	price.Provider
+}
+
+// AlphaVantage implements Provider using the SPLITS endpoint of the Alpha Vantage API.
+type AlphaVantage struct{}
+
+// Options are the accounts used in the hledger transactions. They are set in the `splits` section of the
+// configuration file, and can be overridden with flags.
+type Options struct {
+	// Account is the account (and its subaccounts) where the stocks are held.
+	Account string `mapstructure:"account"`
+	// ConversionAccount is the account balancing the old and the new lots of each split.
+	ConversionAccount string `mapstructure:"conversion-account"`
+}
+
+// DefaultOptions returns the options used when the configuration file does not set them.
+func DefaultOptions() Options {
+	return Options{
+		Account:           "assets",
+		ConversionAccount: "equity:conversion",
+	}
+}
+
+// Holdings returns the quantity of the stock held in each account at the end of a day.
+type Holdings func(date time.Time) map[string]decimal.Decimal
+
+// Quantity returns a Holdings for a constant quantity held in a single account.
+func Quantity(account string, quantity decimal.Decimal) Holdings {
+	return func(time.Time) map[string]decimal.Decimal {
+		return map[string]decimal.Decimal{account: quantity}
+	}
+}
+
+// JournalHoldings returns a Holdings reading the quantity of a commodity from the postings of a journal.
+func JournalHoldings(j *journal.Journal, commodity string, account string) Holdings {
+	return func(date time.Time) map[string]decimal.Decimal {
+		return j.Holdings(commodity, account, date)
+	}
+}
+
+type RawSplit struct {
+	EffectiveDate string `json:"effective_date"`
+	SplitFactor   string `json:"split_factor"`
+}
+
+type Raw struct {
+	Symbol string     `json:"symbol"`
+	Data   []RawSplit `json:"data"`
+}
+
+type TypedSplit struct {
+	EffectiveDate time.Time
+	// SplitFactor is the number of new stocks for each old one (e.g. 4 for a 4-for-1 split, 0.1 for a 1-for-10
+	// reverse split).
+	SplitFactor decimal.Decimal
+}
+
+type Typed struct {
+	Symbol string
+	Splits []TypedSplit
+}
+
+// Normalised is a split as written by the "json" format. Unlike the body of the API, the split factor is a number. The
+// quantities held before and after the split are null when the quantity held is unknown.
+type Normalised struct {
+	Symbol         string       `json:"symbol"`
+	EffectiveDate  string       `json:"effective_date"`
+	SplitFactor    json.Number  `json:"split_factor"`
+	QuantityBefore *json.Number `json:"quantity_before"`
+	QuantityAfter  *json.Number `json:"quantity_after"`
+}
+
+type Splits struct {
+	Raw   Raw
+	Typed Typed
+}
+
+func (typed *TypedSplit) TypeBody(raw RawSplit) error {
+	var err error
+	typed.EffectiveDate, err = time.Parse("2006-01-02", raw.EffectiveDate)
+	if err != nil {
+		return fmt.Errorf("[stock.splits.(*TypedSplit).TypeBody] error parsing effective date: %w", err)
+	}
+	typed.SplitFactor, err = decimal.Parse(raw.SplitFactor)
+	if err != nil {
+		return fmt.Errorf("[stock.splits.(*TypedSplit).TypeBody] error parsing split factor: %w", err)
+	}
+	if typed.SplitFactor.Sign() <= 0 {
+		return fmt.Errorf("[stock.splits.(*TypedSplit).TypeBody] invalid split factor %s", raw.SplitFactor)
+	}
+
+	return nil
+}
+
+func (obj *Splits) TypeBody() error {
+	obj.Typed.Symbol = obj.Raw.Symbol
+	obj.Typed.Splits = make([]TypedSplit, 0, len(obj.Raw.Data))
+	for _, raw := range obj.Raw.Data {
+		var split TypedSplit
+		if err := split.TypeBody(raw); err != nil {
+			return fmt.Errorf("[(*Splits).TypeBody] error casting split: %w", err)
+		}
+		obj.Typed.Splits = append(obj.Typed.Splits, split)
+	}
+
+	return nil
+}
+
+func (obj *Splits) ParseBody(body []byte) (Typed, error) {
+	// Parse the JSON body into the Raw struct.
+	err := json.Unmarshal(body, &obj.Raw)
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Splits).ParseBody] failure to unmarshal JSON body: %w", err)
+	}
+
+	// Cast the attributes into proper types.
+	err = obj.TypeBody()
+	if err != nil {
+		return Typed{}, fmt.Errorf("[(*Splits).ParseBody] error casting response attributes: %w", err)
+	}
+
+	return obj.Typed, nil
+}
+
+// FromSeries extracts the splits from a series of daily adjusted prices, i.e. the points whose split coefficient
+// is neither zero (not given) nor one (no split).
+func FromSeries(series price.Series) Typed {
+	one := decimal.New(1, 0)
+	typed := Typed{Symbol: series.MetaData.Symbol}
+	for date, prices := range series.TimeSeriesAdjusted {
+		if !prices.SplitCoefficient.IsZero() && !prices.SplitCoefficient.Equal(one) {
+			typed.Splits = append(typed.Splits, TypedSplit{
+				EffectiveDate: date,
+				SplitFactor:   prices.SplitCoefficient,
+			})
+		}
+	}
+	return typed
+}
+
+// buildURL creates the URL to make the HTTP request to the Alpha Vantage API.
+func buildURL(symbol string) (string, error) {
+	if internal.ApiKey == "" {
+		return "", errors.New("[stock.splits.buildURL] API key is required")
+	}
+	if symbol == "" {
+		return "", errors.New("[stock.splits.buildURL] no stock symbol provided")
+	}
+
+	url := strings.Builder{}
+	url.WriteString(internal.ApiBaseUrl)
+	url.WriteString("function=")
+	url.WriteString(apiFunctionSplits)
+	url.WriteString("&symbol=")
+	url.WriteString(symbol)
+	url.WriteString("&apikey=")
+	url.WriteString(internal.ApiKey)
+
+	return url.String(), nil
+}
+
+// Splits fetches the splits of a stock from the Alpha Vantage API and casts them into their proper types.
+func (AlphaVantage) Splits(symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	url, err := buildURL(symbol)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+
+	body, err := internal.HTTPRequest(url)
+	if errors.Is(err, internal.ErrInvalidAPICall) {
+		return Typed{}, nil, fmt.Errorf("[stock.splits.(AlphaVantage).Splits] %w %s: %w", internal.ErrUnknownSymbol, symbol, err)
+	}
+	if err != nil {
+		return Typed{}, nil, err
+	}
+
+	// The raw format does not need the body to be parsed.
+	if format == flags.OutputFormatRawJSON {
+		return Typed{}, body, nil
+	}
+
+	response := Splits{}
+	typed, err := response.ParseBody(body)
+	if err != nil {
+		return Typed{}, nil, err
+	}
+	if typed.Symbol == "" {
+		return Typed{}, nil, fmt.Errorf("[stock.splits.(AlphaVantage).Splits] %w %s: no splits found", internal.ErrUnknownSymbol, symbol)
+	}
+
+	return typed, body, nil
+}
+
+// formatQuantity formats a quantity of stocks without trailing zeros.
+func formatQuantity(quantity decimal.Decimal) string {
+	return quantity.Trim(0).String()
+}
+
+// generateOutputHledger generates a transaction for each split of a stock that was held on the day before its
+// effective date. In each account holding the stock, the old lot is converted into the new one through the
+// conversion account, as hledger does for conversions between commodities.
+func generateOutputHledger(splits []TypedSplit, holdings Holdings, name string, options Options) string {
+	commodity := journal.Quote(name)
+
+	out := strings.Builder{}
+	for _, split := range splits {
+		held := holdings(split.EffectiveDate.AddDate(0, 0, -1))
+		accounts := make([]string, 0, len(held))
+		for account := range held {
+			accounts = append(accounts, account)
+		}
+		if len(accounts) == 0 {
+			continue
+		}
+		sort.Strings(accounts)
+
+		// Align the amounts of the postings.
+		width := len(options.ConversionAccount)
+		for _, account := range accounts {
+			width = max(width, len(account))
+		}
+		posting := fmt.Sprintf("    %%-%ds  %%12s %%s\n", width)
+
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("%s %s split  ; split-factor:%s\n",
+			split.EffectiveDate.Format("2006-01-02"),
+			name,
+			split.SplitFactor.Trim(0).String()))
+		for _, account := range accounts {
+			before := held[account]
+			after := before.Mul(split.SplitFactor)
+			out.WriteString(fmt.Sprintf(posting, account, formatQuantity(before.Neg()), commodity))
+			out.WriteString(fmt.Sprintf(posting, options.ConversionAccount, formatQuantity(before), commodity))
+			out.WriteString(fmt.Sprintf(posting, options.ConversionAccount, formatQuantity(after.Neg()), commodity))
+			out.WriteString(fmt.Sprintf(posting, account, formatQuantity(after), commodity))
+		}
+	}
+	return out.String()
+}
+
+// quantity returns the quantity of the stock held in all the accounts on the day before a split.
+func quantity(split TypedSplit, holdings Holdings) decimal.Decimal {
+	var total decimal.Decimal
+	for _, held := range holdings(split.EffectiveDate.AddDate(0, 0, -1)) {
+		total = total.Add(held)
+	}
+	return total
+}
+
+// generateOutputJSON writes the splits as a JSON array of normalised splits, with the name of the stock in the journal.
+func generateOutputJSON(splits []TypedSplit, holdings Holdings, name string) (string, error) {
+	number := func(value string) *json.Number {
+		n := json.Number(value)
+		return &n
+	}
+
+	normalised := make([]Normalised, 0, len(splits))
+	for _, split := range splits {
+		n := Normalised{
+			Symbol:        name,
+			EffectiveDate: split.EffectiveDate.Format("2006-01-02"),
+			SplitFactor:   json.Number(split.SplitFactor.Trim(0).String()),
+		}
+		if holdings != nil {
+			before := quantity(split, holdings)
+			n.QuantityBefore = number(formatQuantity(before))
+			n.QuantityAfter = number(formatQuantity(before.Mul(split.SplitFactor)))
+		}
+		normalised = append(normalised, n)
+	}
+
+	out, err := json.MarshalIndent(normalised, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("[stock.splits.generateOutputJSON] error encoding the splits: %w", err)
+	}
+	return string(out) + "\n", nil
+}
+
+// generateOutputTable generates a table with the splits. The long version includes the quantity held before and
+// after each split when it is known.
+func generateOutputTable(splits []TypedSplit, holdings Holdings, format flags.OutputFormat) string {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	header := table.Row{"Effective Date", "Split Factor"}
+	if format == flags.OutputFormatTableLong && holdings != nil {
+		header = append(header, "Quantity Before", "Quantity After")
+	}
+	t.AppendHeader(header)
+	for _, split := range splits {
+		row := table.Row{
+			split.EffectiveDate.Format("2006-01-02"),
+			split.SplitFactor.Trim(0).String(),
+		}
+		if format == flags.OutputFormatTableLong && holdings != nil {
+			before := quantity(split, holdings)
+			row = append(row, formatQuantity(before), formatQuantity(before.Mul(split.SplitFactor)))
+		}
+		t.AppendRow(row)
+	}
+	return t.Render() + "\n"
+}
+
+// GenerateOutput renders the splits effective between `begin` and `end` in the desired format.
+// The "hledger" format needs to know the quantity held, whereas it is optional for the "json" and "table-long" formats.
+// `name` is the name of the stock in the journal. The "raw-json" format returns the raw body given by the provider.
+func GenerateOutput(typed Typed, body []byte, holdings Holdings, name string, options Options, begin time.Time, end time.Time, format flags.OutputFormat) (string, error) {
+	switch format {
+	case flags.OutputFormatRawJSON:
+		return string(body), nil
+	case flags.OutputFormatCSV, flags.OutputFormatRawCSV:
+		return "", errors.New("[stock.splits.GenerateOutput] CSV output formats not supported")
+	case flags.OutputFormatHledger, flags.OutputFormatJSON, flags.OutputFormatTable, flags.OutputFormatTableLong:
+		// Do nothing.
+	default:
+		return "", errors.New("[stock.splits.GenerateOutput] invalid output format")
+	}
+
+	var splits []TypedSplit
+	for _, split := range typed.Splits {
+		if !(split.EffectiveDate.Before(begin) || split.EffectiveDate.After(end)) {
+			splits = append(splits, split)
+		}
+	}
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].EffectiveDate.Before(splits[j].EffectiveDate)
+	})
+
+	switch format {
+	case flags.OutputFormatHledger:
+		if holdings == nil {
+			return "", errors.New("[stock.splits.GenerateOutput] the quantity held is needed to generate the transactions")
+		}
+		return generateOutputHledger(splits, holdings, name, options), nil
+	case flags.OutputFormatJSON:
+		return generateOutputJSON(splits, holdings, name)
+	}
+	return generateOutputTable(splits, holdings, format), nil
+}
+
+// Execute is the core function of the splits package. It fetches the splits of a stock from the given source of the
+// provider and returns them in the desired format. `holdings` may be nil if the quantity held is unknown.
+func Execute(provider Sources, symbol string, name string, source string, holdings Holdings, options Options, format flags.OutputFormat, begin string, end string) (string, error) {
+	beginTime, endTime, err := internal.ValidateDates(begin, end)
+	if err != nil {
+		return "", err
+	}
+	// Avoid spending a request on a format that cannot be generated.
+	if format == flags.OutputFormatCSV || format == flags.OutputFormatRawCSV {
+		return "", errors.New("[stock.splits.Execute] CSV output formats not supported")
+	}
+
+	var typed Typed
+	var body []byte
+	switch source {
+	case SourceSplits:
+		typed, body, err = provider.Splits(symbol, format)
+		if err != nil {
+			return "", err
+		}
+	case SourceAdjusted:
+		if format == flags.OutputFormatRawJSON {
+			return "", fmt.Errorf("[stock.splits.Execute] raw JSON output format not supported with the %q source", source)
+		}
+		// Only the split coefficients are read, so the currency of the prices does not need to be looked up.
+		series, _, err := provider.StockSeries(symbol, format, flags.IntervalDaily, true, true)
+		if err != nil {
+			return "", err
+		}
+		typed = FromSeries(series)
+	default:
+		return "", fmt.Errorf("[stock.splits.Execute] invalid source %q (possible values are %q and %q)", source, SourceSplits, SourceAdjusted)
+	}
+
+	return GenerateOutput(typed, body, holdings, name, options, beginTime, endTime, format)
+}
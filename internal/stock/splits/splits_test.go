@@ -0,0 +1,218 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package splits
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/search"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+// sources combines the Alpha Vantage implementations of the splits, of the prices and of the currency of the stocks.
+type sources struct {
+	price.AlphaVantage
+}
+
+func (sources) StockCurrency(symbol string) (string, error) {
+	return search.AlphaVantage{}.StockCurrency(symbol)
+}
+
+func (sources) Splits(symbol string, format flags.OutputFormat) (Typed, []byte, error) {
+	return AlphaVantage{}.Splits(symbol, format)
+}
+
+func TestSplits(t *testing.T) {
+	internal.ApiKey = "demo"
+	options := DefaultOptions()
+
+	t.Run("success", func(t *testing.T) {
+		expected := `1997-05-28 IBM split  ; split-factor:2
+    assets                      -10 IBM
+    equity:conversion            10 IBM
+    equity:conversion           -20 IBM
+    assets                       20 IBM
+
+1999-05-27 IBM split  ; split-factor:2
+    assets                      -10 IBM
+    equity:conversion            10 IBM
+    equity:conversion           -20 IBM
+    assets                       20 IBM
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceSplits, Quantity("assets", decimal.New(10, 0)), options, flags.OutputFormatHledger, "", "2000-01-01")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("quantity from journal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "main.journal")
+		content := `2021-01-04 buy
+    assets:broker   10 IBM @ 120 USD
+    assets:cash
+
+2021-06-01 buy
+    assets:pension   3 IBM @ 140 USD
+    assets:cash
+`
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		j, err := journal.Parse(path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// Each account holding the stock has its own lot conversion, and the older splits are skipped since
+		// there were no stocks held then.
+		expected := `2021-11-04 IBM split  ; split-factor:1.046
+    assets:broker               -10 IBM
+    equity:conversion            10 IBM
+    equity:conversion        -10.46 IBM
+    assets:broker             10.46 IBM
+    assets:pension               -3 IBM
+    equity:conversion             3 IBM
+    equity:conversion        -3.138 IBM
+    assets:pension            3.138 IBM
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceSplits, JournalHoldings(j, "IBM", "assets"), options, flags.OutputFormatHledger, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("adjusted source", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceAdjusted, Quantity("assets", decimal.New(5, 0)), options, flags.OutputFormatHledger, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.HasPrefix(output, "1999-05-27 IBM split") || strings.Count(output, "split  ;") != 1 {
+			t.Errorf("expected only the split of 1999-05-27, got %s", output)
+		}
+	})
+
+	t.Run("table without quantity", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceSplits, nil, options, flags.OutputFormatTableLong, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.Contains(output, "1997-05-28") || strings.Contains(output, "QUANTITY") {
+			t.Errorf("expected every split without quantity, got %s", output)
+		}
+	})
+
+	t.Run("success JSON", func(t *testing.T) {
+		expected := `[
+  {
+    "symbol": "IBM",
+    "effective_date": "1999-05-27",
+    "split_factor": 2,
+    "quantity_before": 10,
+    "quantity_after": 20
+  },
+  {
+    "symbol": "IBM",
+    "effective_date": "2021-11-04",
+    "split_factor": 1.046,
+    "quantity_before": 10,
+    "quantity_after": 10.46
+  }
+]
+`
+		output, err := Execute(sources{}, "IBM", "IBM", SourceSplits, Quantity("assets", decimal.New(10, 0)), options, flags.OutputFormatJSON, "1998-01-01", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("JSON without quantity", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceSplits, nil, options, flags.OutputFormatJSON, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if strings.Count(output, `"quantity_before": null`) != 3 {
+			t.Errorf("expected 3 splits without quantity, got %s", output)
+		}
+	})
+
+	t.Run("raw JSON", func(t *testing.T) {
+		output, err := Execute(sources{}, "IBM", "IBM", SourceSplits, nil, options, flags.OutputFormatRawJSON, "", "")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !strings.Contains(output, `"split_factor": "2.0000"`) {
+			t.Errorf("expected the raw body, got %s", output)
+		}
+	})
+
+	t.Run("hledger without quantity", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceSplits, nil, options, flags.OutputFormatHledger, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		_, err := Execute(sources{}, "UNKNOWN", "UNKNOWN", SourceSplits, Quantity("assets", decimal.New(1, 0)), options, flags.OutputFormatHledger, "", "")
+		if !errors.Is(err, internal.ErrUnknownSymbol) {
+			t.Errorf("expected %v, got %v", internal.ErrUnknownSymbol, err)
+		}
+	})
+
+	t.Run("invalid source", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", "invalid", Quantity("assets", decimal.New(1, 0)), options, flags.OutputFormatHledger, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("CSV output format", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceSplits, Quantity("assets", decimal.New(1, 0)), options, flags.OutputFormatCSV, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	internal.ApiKey = ""
+
+	t.Run("no API key", func(t *testing.T) {
+		if _, err := Execute(sources{}, "IBM", "IBM", SourceSplits, Quantity("assets", decimal.New(1, 0)), options, flags.OutputFormatHledger, "", ""); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
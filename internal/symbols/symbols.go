@@ -0,0 +1,71 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package symbols
+
+import "fmt"
+
+// Mapping is an entry of the `symbols` section of the configuration file, which maps the name of a commodity in the
+// hledger journal to its symbol in the API.
+type Mapping struct {
+	Commodity string `mapstructure:"commodity"`
+	Symbol    string `mapstructure:"symbol"`
+}
+
+// toAPI and toJournal map the commodities in both directions.
+var toAPI = map[string]string{}
+var toJournal = map[string]string{}
+
+// Load replaces the current mappings. Every commodity and every symbol can only be mapped once.
+func Load(mappings []Mapping) error {
+	api := make(map[string]string, len(mappings))
+	names := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		if mapping.Commodity == "" || mapping.Symbol == "" {
+			return fmt.Errorf("[symbols.Load] mapping %q to %q is missing the commodity or the symbol", mapping.Commodity, mapping.Symbol)
+		}
+		if _, ok := api[mapping.Commodity]; ok {
+			return fmt.Errorf("[symbols.Load] commodity %q is mapped more than once", mapping.Commodity)
+		}
+		if _, ok := names[mapping.Symbol]; ok {
+			return fmt.Errorf("[symbols.Load] symbol %q is mapped more than once", mapping.Symbol)
+		}
+		api[mapping.Commodity] = mapping.Symbol
+		names[mapping.Symbol] = mapping.Commodity
+	}
+
+	toAPI = api
+	toJournal = names
+	return nil
+}
+
+// ToAPI returns the symbol of a commodity of the journal in the API, which is the commodity itself if it is not mapped.
+func ToAPI(commodity string) string {
+	if symbol, ok := toAPI[commodity]; ok {
+		return symbol
+	}
+	return commodity
+}
+
+// ToJournal returns the name of an API symbol in the journal, which is the symbol itself if it is not mapped.
+func ToJournal(symbol string) string {
+	if commodity, ok := toJournal[symbol]; ok {
+		return commodity
+	}
+	return symbol
+}
@@ -0,0 +1,54 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package symbols
+
+import "testing"
+
+func TestLoad(t *testing.T) {
+	defer Load(nil)
+
+	t.Run("valid", func(t *testing.T) {
+		err := Load([]Mapping{{Commodity: "VWCE", Symbol: "VWCE.DEX"}, {Commodity: "€", Symbol: "EUR"}})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if result := ToAPI("VWCE"); result != "VWCE.DEX" {
+			t.Errorf("expected VWCE.DEX, got %s", result)
+		}
+		if result := ToJournal("EUR"); result != "€" {
+			t.Errorf("expected €, got %s", result)
+		}
+		if result := ToAPI("IBM"); result != "IBM" {
+			t.Errorf("expected IBM, got %s", result)
+		}
+	})
+
+	invalid := map[string][]Mapping{
+		"missing symbol":      {{Commodity: "VWCE"}},
+		"duplicate commodity": {{Commodity: "TSCO", Symbol: "TSCO.LON"}, {Commodity: "TSCO", Symbol: "TSCO"}},
+		"duplicate symbol":    {{Commodity: "€", Symbol: "EUR"}, {Commodity: "EURO", Symbol: "EUR"}},
+	}
+	for name, mappings := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if err := Load(mappings); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package testserver runs the tests of the packages calling the APIs against a local stand-in serving the fixtures of
+// the repository, so that they neither need the network nor an API key.
+package testserver
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/fixture"
+)
+
+// Dir returns the directory containing the fixtures of the repository.
+func Dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "testdata", "fixtures")
+}
+
+// Run starts the stand-in, points the API to it with the cache disabled, and runs the tests. It is meant to be called
+// from TestMain and returns the exit code of the tests.
+func Run(m *testing.M) int {
+	server := fixture.NewServer(Dir())
+	defer server.Close()
+
+	internal.SetBaseUrl(server.URL)
+	defer internal.SetBaseUrl(internal.DefaultBaseUrl)
+	internal.NoCache = true
+
+	return m.Run()
+}
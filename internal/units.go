@@ -0,0 +1,57 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package internal
+
+import (
+	"github.com/lentidas/hledger-price-tracker/internal/decimal"
+)
+
+// KeepMinorUnits keeps the prices of the stocks quoted in a minor unit of a currency (e.g. pence) as they are,
+// instead of converting them to the major unit (e.g. pounds).
+var KeepMinorUnits bool
+
+// minorUnit is a currency code used by stock exchanges to quote prices in a fraction of another currency.
+type minorUnit struct {
+	major   string
+	divisor int64
+}
+
+// minorUnits maps the quote currencies in minor units to their major currency. The codes are case-sensitive, since
+// the exchanges do not agree on them (e.g. London quotes in "GBX", "GBp" or "GBx").
+var minorUnits = map[string]minorUnit{
+	"GBX": {"GBP", 100}, // Pence sterling.
+	"GBp": {"GBP", 100},
+	"GBx": {"GBP", 100},
+	"ILA": {"ILS", 100}, // Israeli agorot.
+	"ILa": {"ILS", 100},
+	"ZAC": {"ZAR", 100}, // South African cents.
+	"ZAc": {"ZAR", 100},
+	"USX": {"USD", 100},  // US cents.
+	"KWF": {"KWD", 1000}, // Kuwaiti fils.
+}
+
+// MajorUnit returns the major currency of a quote currency, and the number by which the prices must be divided to be
+// expressed in it. Currencies that are not minor units are returned as they are, with a divisor of 1, as well as every
+// currency when KeepMinorUnits is set.
+func MajorUnit(currency string) (string, decimal.Decimal) {
+	if unit, ok := minorUnits[currency]; ok && !KeepMinorUnits {
+		return unit.major, decimal.New(unit.divisor, 0)
+	}
+	return currency, decimal.New(1, 0)
+}
@@ -0,0 +1,226 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	cryptoRate "github.com/lentidas/hledger-price-tracker/internal/crypto/rate"
+	currencyRate "github.com/lentidas/hledger-price-tracker/internal/currency/rate"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+// compactDays is the number of days that are safely covered by the compact output of the daily endpoints, which
+// returns the last 100 data points. Older prices need the full time series.
+const compactDays = 100
+
+// symbolRegex matches the commodity symbols that can be looked up in the API. Other symbols (e.g. `$` or `€`)
+// cannot be fetched and are skipped, unless they are mapped to an API symbol in the configuration file.
+var symbolRegex = regexp.MustCompile(`^[A-Za-z0-9.\-:^]+$`)
+
+// Kind is the kind of market a commodity is traded on, which defines the endpoint used to fetch its prices.
+type Kind string
+
+const (
+	KindStock  Kind = "stock"
+	KindFX     Kind = "fx"
+	KindCrypto Kind = "crypto"
+)
+
+// Classify returns the kind of a commodity: physical currencies and cryptocurrencies are recognized from the lists of
+// the provider, and every other symbol is considered to be a stock.
+func Classify(p provider.Provider, commodity string) (Kind, error) {
+	exists, err := p.CurrencyExists(commodity)
+	if err != nil {
+		return KindStock, err
+	}
+	if exists {
+		return KindFX, nil
+	}
+
+	exists, err = p.CryptoExists(commodity)
+	if err != nil {
+		return KindStock, err
+	}
+	if exists {
+		return KindCrypto, nil
+	}
+
+	return KindStock, nil
+}
+
+// Request describes the prices to fetch for a commodity, which is named Commodity in the journal and Symbol in the API.
+// A zero Begin means that the whole history must be fetched.
+// The Currency is the one in which physical currencies and cryptocurrencies are priced. Stocks are priced in the
+// currency of their market when it is empty, otherwise their prices are converted into it.
+type Request struct {
+	Commodity string
+	Symbol    string
+	Kind      Kind
+	Currency  string
+	Interval  flags.Interval
+	Adjusted  bool
+	Begin     time.Time
+}
+
+// Missing returns the commodities with postings in the journal whose prices are not up-to-date, sorted by symbol.
+// The prices of each commodity are missing from the day after its latest `P` directive or, if it has none, from the
+// date of its first posting. The default currency and the symbols that cannot be looked up are skipped.
+// The requests are for daily prices in the default currency, but their Kind is not set.
+func Missing(j *journal.Journal, today time.Time) []Request {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	var requests []Request
+	for commodity, first := range j.FirstPosting {
+		symbol := symbols.ToAPI(commodity)
+		if symbol == symbols.ToAPI(internal.DefaultCurrency) || !symbolRegex.MatchString(symbol) {
+			if internal.DebugMode {
+				fmt.Fprintf(os.Stderr, "[update.Missing] skipping commodity %q\n", commodity)
+			}
+			continue
+		}
+
+		begin := first
+		if latest, ok := j.LatestPrice[commodity]; ok {
+			begin = latest.AddDate(0, 0, 1)
+		}
+		if begin.After(today) {
+			continue
+		}
+
+		requests = append(requests, Request{
+			Commodity: commodity,
+			Symbol:    symbol,
+			Currency:  symbols.ToAPI(internal.DefaultCurrency),
+			Interval:  flags.IntervalDaily,
+			Begin:     begin,
+		})
+	}
+
+	sort.Slice(requests, func(i, k int) bool {
+		return requests[i].Commodity < requests[k].Commodity
+	})
+	return requests
+}
+
+// Fetch gets the prices of a commodity from the beginning of the request until today, in the hledger format and with
+// the names of the journal.
+func Fetch(p provider.Provider, request Request, today time.Time) (string, error) {
+	begin := ""
+	if !request.Begin.IsZero() {
+		begin = request.Begin.Format("2006-01-02")
+	}
+	full := request.Begin.IsZero() || today.Sub(request.Begin) > compactDays*24*time.Hour
+
+	var directives string
+	var err error
+	switch request.Kind {
+	case KindFX:
+		directives, err = currencyRate.Execute(p, request.Symbol, request.Currency, "", request.Commodity, flags.OutputFormatHledger, request.Interval, begin, "", full)
+	case KindCrypto:
+		directives, err = cryptoRate.Execute(request.Symbol, request.Currency, request.Commodity, flags.OutputFormatHledger, request.Interval, begin, "")
+	case KindStock:
+		directives, err = price.Execute(p, request.Symbol, request.Currency, request.Commodity, flags.OutputFormatHledger, request.Interval, begin, "", request.Adjusted, full)
+	default:
+		return "", fmt.Errorf("[update.Fetch] invalid kind %q", request.Kind)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return directives, nil
+}
+
+// Append adds the price directives at the end of the file at `path`, creating it if needed.
+func Append(path string, directives string) error {
+	if directives == "" {
+		return nil
+	}
+
+	// Make sure the new directives do not end up on the last line of the file.
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("[update.Append] failed to read prices file: %w", err)
+	}
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		directives = "\n" + directives
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("[update.Append] failed to open prices file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(directives); err != nil {
+		return fmt.Errorf("[update.Append] failed to write prices file: %w", err)
+	}
+	return nil
+}
+
+// Execute is the core function of the update package. It reads the journal at `journalPath` and returns the price
+// directives that are missing for each of its commodities. The prices file at `pricesPath` is also read if it
+// exists, even if it is not included by the journal, so prices are never fetched twice.
+// A commodity that fails does not prevent the others from being fetched: the errors are returned together with the
+// directives that were fetched successfully.
+func Execute(p provider.Provider, journalPath string, pricesPath string) (string, error) {
+	j, err := journal.Parse(journalPath)
+	if err != nil {
+		return "", err
+	}
+	if pricesPath != "" {
+		if err := j.Read(pricesPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	today := time.Now()
+	out := strings.Builder{}
+	var errs []error
+	for _, request := range Missing(j, today) {
+		request.Kind, err = Classify(p, request.Symbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[update.Execute] failed to classify %s: %w", request.Commodity, err))
+			continue
+		}
+		if request.Kind == KindStock {
+			// The journal does not tell in which currency a stock should be priced, so keep the one of its market.
+			request.Currency = ""
+		}
+		directives, err := Fetch(p, request, today)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[update.Execute] failed to fetch prices of %s: %w", request.Commodity, err))
+			continue
+		}
+		out.WriteString(directives)
+	}
+
+	return out.String(), errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestMissing(t *testing.T) {
+	internal.DefaultCurrency = "EUR"
+	if err := symbols.Load([]symbols.Mapping{{Commodity: "€", Symbol: "EUR"}, {Commodity: "VWCE", Symbol: "VWCE.DEX"}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	defer symbols.Load(nil)
+
+	j := &journal.Journal{
+		FirstPosting: map[string]time.Time{
+			"VWRL.L": date("2024-01-02"),
+			"BTC":    date("2023-06-01"),
+			"USD":    date("2023-06-01"),
+			"EUR":    date("2023-06-01"),
+			"$":      date("2023-06-01"),
+			"€":      date("2023-06-01"),
+			"VWCE":   date("2024-02-01"),
+			"IBM":    {},
+		},
+		LatestPrice: map[string]time.Time{
+			"VWRL.L": date("2024-02-05"),
+			"USD":    date("2024-03-01"),
+		},
+	}
+
+	requests := Missing(j, date("2024-03-01"))
+	expected := []Request{
+		{Commodity: "BTC", Begin: date("2023-06-01")},
+		{Commodity: "IBM"},
+		{Commodity: "VWCE", Symbol: "VWCE.DEX", Begin: date("2024-02-01")},
+		{Commodity: "VWRL.L", Begin: date("2024-02-06")},
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, requests)
+	}
+	for i := range expected {
+		if expected[i].Symbol == "" {
+			expected[i].Symbol = expected[i].Commodity
+		}
+		if requests[i].Commodity != expected[i].Commodity || requests[i].Symbol != expected[i].Symbol || !requests[i].Begin.Equal(expected[i].Begin) {
+			t.Errorf("expected %v, got %v", expected[i], requests[i])
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.journal")
+
+	t.Run("new file", func(t *testing.T) {
+		if err := Append(path, "P 2024-01-02 USD 0.91 EUR\n"); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("missing newline", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("P 2024-01-02 USD 0.91 EUR"), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if err := Append(path, "P 2024-01-03 USD 0.92 EUR\n"); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		body, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		expected := "P 2024-01-02 USD 0.91 EUR\nP 2024-01-03 USD 0.92 EUR\n"
+		if string(body) != expected {
+			t.Errorf("expected %q, got %q", expected, body)
+		}
+	})
+}
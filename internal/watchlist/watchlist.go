@@ -0,0 +1,207 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package watchlist
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/journal"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/symbols"
+	"github.com/lentidas/hledger-price-tracker/internal/update"
+)
+
+// Entry is a commodity of the `commodities` section of the configuration file.
+type Entry struct {
+	// Commodity is the name of the commodity in the hledger journal. Defaults to the name mapped to the symbol in the
+	// `symbols` section of the configuration file, or to the symbol itself.
+	Commodity string `mapstructure:"commodity"`
+	// Symbol is the symbol of the commodity in the API (e.g. `TSCO.LON`).
+	Symbol string `mapstructure:"symbol"`
+	// Kind is either "stock", "fx" or "crypto".
+	Kind string `mapstructure:"kind"`
+	// Currency is the currency in which physical currencies and cryptocurrencies are priced. Defaults to the default
+	// currency. Stocks are priced in the currency of their market, unless it is set, in which case their prices are
+	// converted into it.
+	Currency string `mapstructure:"currency"`
+	// Interval is either "daily", "weekly" or "monthly". Defaults to "daily". Stocks and physical currencies also
+	// accept the intraday intervals (e.g. "60min"), of which only the last price of each day is kept.
+	Interval string `mapstructure:"interval"`
+	// Adjusted selects the adjusted close prices of stocks.
+	Adjusted bool `mapstructure:"adjusted"`
+	// Begin is the first date to fetch (format YYYY-MM-DD) when the output file has no prices for the commodity yet.
+	// Defaults to the whole history.
+	Begin string `mapstructure:"begin"`
+	// Output is the file where the price directives are appended. Defaults to the standard output.
+	Output string `mapstructure:"output"`
+}
+
+// Request validates the entry and converts it into a request for the prices since `begin`, which may be zero.
+func (entry *Entry) Request(begin time.Time) (update.Request, error) {
+	if entry.Symbol == "" {
+		return update.Request{}, errors.New("[watchlist.(*Entry).Request] missing symbol")
+	}
+
+	request := update.Request{
+		Commodity: entry.name(),
+		Symbol:    entry.Symbol,
+		Kind:      update.Kind(entry.Kind),
+		Currency:  entry.Currency,
+		Interval:  flags.IntervalDaily,
+		Adjusted:  entry.Adjusted,
+		Begin:     begin,
+	}
+
+	switch request.Kind {
+	case update.KindStock:
+		// Without a currency, the prices are kept in the currency of the market.
+	case update.KindFX, update.KindCrypto:
+		if entry.Adjusted {
+			return update.Request{}, errors.New("[watchlist.(*Entry).Request] adjusted prices are only available for stocks")
+		}
+		if request.Currency == "" {
+			request.Currency = symbols.ToAPI(internal.DefaultCurrency)
+		}
+	default:
+		return update.Request{}, fmt.Errorf("[watchlist.(*Entry).Request] invalid kind %q (possible values are \"stock\", \"fx\", \"crypto\")", entry.Kind)
+	}
+
+	if entry.Interval != "" {
+		if err := request.Interval.Set(entry.Interval); err != nil {
+			return update.Request{}, fmt.Errorf("[watchlist.(*Entry).Request] invalid interval: %w", err)
+		}
+	}
+
+	if request.Begin.IsZero() && entry.Begin != "" {
+		var err error
+		request.Begin, err = time.Parse("2006-01-02", entry.Begin)
+		if err != nil {
+			return update.Request{}, fmt.Errorf("[watchlist.(*Entry).Request] failed to parse begin date: %w", err)
+		}
+	}
+
+	return request, nil
+}
+
+// name returns the name of the commodity in the hledger journal.
+func (entry *Entry) name() string {
+	if entry.Commodity != "" {
+		return entry.Commodity
+	}
+	return symbols.ToJournal(entry.Symbol)
+}
+
+// latestPrice returns the date of the latest price of the commodity in the output file of the entry, or a zero time
+// if the file does not exist or has no prices for the commodity.
+func (entry *Entry) latestPrice() (time.Time, error) {
+	if entry.Output == "" {
+		return time.Time{}, nil
+	}
+	j, err := journal.Parse(entry.Output)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return j.LatestPrice[entry.name()], nil
+}
+
+// fetch gets the prices of an entry that are not yet in its output file, with the commodity renamed as in the journal.
+func fetch(p provider.Provider, entry Entry, today time.Time) (string, error) {
+	var err error
+	entry.Output, err = journal.ExpandHome(entry.Output)
+	if err != nil {
+		return "", err
+	}
+
+	latest, err := entry.latestPrice()
+	if err != nil {
+		return "", err
+	}
+	begin := time.Time{}
+	if !latest.IsZero() {
+		begin = latest.AddDate(0, 0, 1)
+		if begin.After(today) {
+			return "", nil
+		}
+	}
+
+	request, err := entry.Request(begin)
+	if err != nil {
+		return "", err
+	}
+
+	directives, err := update.Fetch(p, request, today)
+	if err != nil {
+		return "", err
+	}
+
+	if entry.Output == "" {
+		return directives, nil
+	}
+	return "", update.Append(entry.Output, directives)
+}
+
+// Filter returns the entries whose commodity is in `names`, or all of them if `names` is empty.
+func Filter(entries []Entry, names []string) ([]Entry, error) {
+	if len(names) == 0 {
+		return entries, nil
+	}
+
+	var filtered []Entry
+	for _, name := range names {
+		found := false
+		for _, entry := range entries {
+			if entry.name() == name {
+				filtered = append(filtered, entry)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("[watchlist.Filter] commodity %q not found in the configuration file", name)
+		}
+	}
+	return filtered, nil
+}
+
+// Execute is the core function of the watchlist package. It fetches the prices of every entry that are not yet in
+// its output file and appends them to it. The directives of the entries without an output file are returned instead.
+// An entry that fails does not prevent the others from being fetched.
+func Execute(p provider.Provider, entries []Entry) (string, error) {
+	today := time.Now()
+	out := strings.Builder{}
+	var errs []error
+	for _, entry := range entries {
+		directives, err := fetch(p, entry, today)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[watchlist.Execute] failed to fetch prices of %s: %w", entry.name(), err))
+			continue
+		}
+		out.WriteString(directives)
+	}
+
+	return out.String(), errors.Join(errs...)
+}
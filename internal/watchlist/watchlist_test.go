@@ -0,0 +1,190 @@
+/*
+ * hledger-price-tracker - a CLI tool to get market prices for commodities
+ * Copyright (C) 2024 Gonçalo Carvalheiro Heleno
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package watchlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lentidas/hledger-price-tracker/internal"
+	"github.com/lentidas/hledger-price-tracker/internal/flags"
+	"github.com/lentidas/hledger-price-tracker/internal/provider"
+	"github.com/lentidas/hledger-price-tracker/internal/stock/price"
+	"github.com/lentidas/hledger-price-tracker/internal/testserver"
+	"github.com/lentidas/hledger-price-tracker/internal/update"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testserver.Run(m))
+}
+
+// inUSD overrides the currency of the stocks, which is unknown with the demo API key.
+type inUSD struct {
+	provider.AlphaVantage
+}
+
+func (p inUSD) StockSeries(symbol string, format flags.OutputFormat, interval flags.Interval, adjusted bool, full bool) (price.Series, []byte, error) {
+	series, body, err := p.AlphaVantage.StockSeries(symbol, format, interval, adjusted, full)
+	series.MetaData.Currency = "USD"
+	return series, body, err
+}
+
+func TestEntryRequest(t *testing.T) {
+	internal.DefaultCurrency = "EUR"
+
+	t.Run("defaults", func(t *testing.T) {
+		entry := Entry{Symbol: "BTC", Kind: "crypto"}
+		request, err := entry.Request(time.Time{})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if request.Currency != "EUR" || request.Interval != flags.IntervalDaily || request.Kind != update.KindCrypto {
+			t.Errorf("expected daily crypto prices in EUR, got %v", request)
+		}
+	})
+
+	t.Run("begin from configuration", func(t *testing.T) {
+		entry := Entry{Symbol: "TSCO.LON", Kind: "stock", Interval: "weekly", Begin: "2024-01-01"}
+		request, err := entry.Request(time.Time{})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if request.Interval != flags.IntervalWeekly || request.Begin.Format("2006-01-02") != "2024-01-01" {
+			t.Errorf("expected weekly prices since 2024-01-01, got %v", request)
+		}
+	})
+
+	t.Run("begin from output file", func(t *testing.T) {
+		entry := Entry{Symbol: "USD", Kind: "fx", Begin: "2024-01-01"}
+		begin := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		request, err := entry.Request(begin)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !request.Begin.Equal(begin) {
+			t.Errorf("expected %s, got %s", begin, request.Begin)
+		}
+	})
+
+	t.Run("stock in another currency", func(t *testing.T) {
+		entry := Entry{Symbol: "IBM", Kind: "stock", Currency: "USD"}
+		request, err := entry.Request(time.Time{})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if request.Currency != "USD" || request.Kind != update.KindStock {
+			t.Errorf("expected stock prices in USD, got %v", request)
+		}
+	})
+
+	invalid := map[string]Entry{
+		"missing symbol":    {Kind: "stock"},
+		"invalid kind":      {Symbol: "IBM", Kind: "bond"},
+		"adjusted currency": {Symbol: "USD", Kind: "fx", Adjusted: true},
+		"invalid interval":  {Symbol: "IBM", Kind: "stock", Interval: "hourly"},
+		"invalid begin":     {Symbol: "IBM", Kind: "stock", Begin: "01/01/2024"},
+	}
+	for name, entry := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := entry.Request(time.Time{}); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	internal.ApiKey = "demo"
+	today := time.Date(2025, 4, 5, 0, 0, 0, 0, time.UTC)
+
+	t.Run("stock converted into the currency of the entry", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "stocks.journal")
+		if err := os.WriteFile(path, []byte("P 2025-03-21 IBM 225.3512 EUR\n"), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		entry := Entry{Symbol: "IBM", Kind: "stock", Currency: "EUR", Interval: "weekly", Output: path}
+		if _, err := fetch(inUSD{}, entry, today); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		expected := "P 2025-03-21 IBM 225.3512 EUR\nP 2025-03-28 \"IBM\" 222.7964 EUR\nP 2025-04-04 \"IBM\" 207.279776 EUR\n"
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %q, got %q", expected, string(content))
+		}
+	})
+}
+
+func TestLatestPrice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tsco.journal")
+	entry := Entry{Commodity: "TSCO", Symbol: "TSCO.LON", Kind: "stock", Output: path}
+
+	t.Run("missing file", func(t *testing.T) {
+		latest, err := entry.latestPrice()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !latest.IsZero() {
+			t.Errorf("expected zero time, got %s", latest)
+		}
+	})
+
+	t.Run("existing prices", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("P 2024-01-02 TSCO 3.05 GBP\nP 2024-01-03 TSCO 3.10 GBP\n"), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		latest, err := entry.latestPrice()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if latest.Format("2006-01-02") != "2024-01-03" {
+			t.Errorf("expected 2024-01-03, got %s", latest)
+		}
+	})
+}
+
+func TestFilter(t *testing.T) {
+	entries := []Entry{{Symbol: "IBM"}, {Commodity: "TSCO", Symbol: "TSCO.LON"}}
+
+	t.Run("all", func(t *testing.T) {
+		filtered, err := Filter(entries, nil)
+		if err != nil || len(filtered) != 2 {
+			t.Errorf("expected 2 entries, got %v (%v)", filtered, err)
+		}
+	})
+
+	t.Run("by commodity", func(t *testing.T) {
+		filtered, err := Filter(entries, []string{"TSCO"})
+		if err != nil || len(filtered) != 1 || filtered[0].Symbol != "TSCO.LON" {
+			t.Errorf("expected TSCO.LON, got %v (%v)", filtered, err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := Filter(entries, []string{"AAPL"}); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}